@@ -0,0 +1,60 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLRU_Evict(t *testing.T) {
+	c := New(2, time.Minute)
+	c.Add("a", 1)
+	c.Add("b", 2)
+
+	// a is used more recently than b now
+	_, ok := c.Get("a")
+	assert.True(t, ok)
+
+	c.Add("c", 3)
+	assert.Equal(t, 2, c.Len())
+
+	_, ok = c.Get("b")
+	assert.False(t, ok)
+	value, ok := c.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, 1, value)
+	value, ok = c.Get("c")
+	assert.True(t, ok)
+	assert.Equal(t, 3, value)
+}
+
+func TestLRU_Expire(t *testing.T) {
+	now := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	c := New(2, time.Minute)
+	c.now = func() time.Time { return now }
+
+	c.Add("a", 1)
+	now = now.Add(30 * time.Second)
+	_, ok := c.Get("a")
+	assert.True(t, ok)
+
+	// replacing a value resets its ttl
+	c.Add("a", 2)
+	now = now.Add(59 * time.Second)
+	value, ok := c.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, 2, value)
+
+	now = now.Add(time.Second)
+	_, ok = c.Get("a")
+	assert.False(t, ok)
+	assert.Equal(t, 0, c.Len())
+}
+
+func TestLRU_Disabled(t *testing.T) {
+	c := New(0, time.Minute)
+	c.Add("a", 1)
+	_, ok := c.Get("a")
+	assert.False(t, ok)
+}
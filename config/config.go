@@ -18,6 +18,16 @@ type SpotifyConfig struct {
 	ClientID     string `mapstructure:"client_id"`
 	ClientSecret string `mapstructure:"client_secret"`
 	RedirectUrl  string `mapstructure:"redirect_url"`
+	// number of search queries whose results are cached, 0 disables the cache
+	SearchCacheSize int `mapstructure:"search_cache_size"`
+	// time in s until cached search results are fetched again
+	SearchCacheTTLInS int `mapstructure:"search_cache_ttl_s"`
+	// number of tracks whose metadata is cached in memory
+	TrackCacheSize int `mapstructure:"track_cache_size"`
+	// time in s until track metadata is fetched again
+	TrackCacheTTLInS int `mapstructure:"track_cache_ttl_s"`
+	// always fetch track metadata from spotify, fetched tracks are still cached
+	TrackCacheBypass bool `mapstructure:"track_cache_bypass"`
 }
 
 type ServerConfig struct {
@@ -26,14 +36,29 @@ type ServerConfig struct {
 	Debug           bool   `mapstructure:"debug"`
 }
 
+// EventsConfig limits the event streams of the sse and websocket endpoints
+type EventsConfig struct {
+	// time in s between two heartbeats on a stream, 0 disables heartbeats
+	HeartbeatIntervalInS int `mapstructure:"heartbeat_interval_s"`
+	// time in s after which a stream without events is closed, 0 disables the timeout
+	IdleTimeoutInS int `mapstructure:"idle_timeout_s"`
+	// maximum number of concurrent streams of a user, 0 means no limit
+	MaxStreamsPerUser int `mapstructure:"max_streams_per_user"`
+	// maximum number of concurrent streams in a session, 0 means no limit
+	MaxStreamsPerSession int `mapstructure:"max_streams_per_session"`
+}
+
 type DBConfig struct {
-	DBUser                string `mapstructure:"db_user"`
-	DBPassword            string `mapstructure:"db_password"`
-	DBHost                string `mapstructure:"db_host"`
-	DBPort                int    `mapstructure:"db_port"`
-	DBName                string `mapstructure:"db_name"`
-	UserCollectionName    string `mapstructure:"user_collection_name"`
-	SessionCollectionName string `mapstructure:"session_collection_name"`
+	DBUser                 string `mapstructure:"db_user"`
+	DBPassword             string `mapstructure:"db_password"`
+	DBHost                 string `mapstructure:"db_host"`
+	DBPort                 int    `mapstructure:"db_port"`
+	DBName                 string `mapstructure:"db_name"`
+	UserCollectionName     string `mapstructure:"user_collection_name"`
+	SessionCollectionName  string `mapstructure:"session_collection_name"`
+	JoinCodeCollectionName string `mapstructure:"join_code_collection_name"`
+	// tracks are only cached in memory if empty
+	TrackCollectionName string `mapstructure:"track_collection_name"`
 }
 
 type Config struct {
@@ -41,7 +66,11 @@ type Config struct {
 	Server           *ServerConfig      `mapstructure:"server"`
 	Database         *DBConfig          `mapstructure:"database"`
 	GarbageCollector *GarbageCollConfig `mapstructure:"garbagecoll"`
-	MaxUsers         int                `mapstructure:"max_users"`
+	Events           *EventsConfig      `mapstructure:"events"`
+	// global upper bound for the number of users in a session
+	MaxUsers int `mapstructure:"max_users"`
+	// maximum number of songs a single playlist or album import can add to a session, 0 means no limit
+	MaxImportTracks int `mapstructure:"max_import_tracks"`
 }
 
 var Conf *Config
@@ -6,17 +6,24 @@ var (
 	ErrIllegalState = errors.New("illegal state in database")
 
 	// User collection errors
-	ErrUsernameTaken   = errors.New("requested username already taken")
-	ErrNoUserWithID    = errors.New("no user with given id")
-	ErrNoUserWithState = errors.New("no user with given state")
+	ErrUsernameTaken      = errors.New("requested username already taken")
+	ErrNoUserWithID       = errors.New("no user with given id")
+	ErrNoUserWithState    = errors.New("no user with given state")
+	ErrSessionFull        = errors.New("session has reached its user limit")
+	ErrNoWaitingUser      = errors.New("no user waiting to be admitted")
+	ErrSuggestionCooldown = errors.New("user has to wait before suggesting another song")
 
 	// Song collection errors
 	ErrNoSongWithID         = errors.New("no song with given id")
 	ErrUserAlreadyVoted     = errors.New("user already voted for this song")
 	ErrSongAlreadySuggested = errors.New("song has already been suggested")
+	ErrTooManySuggestions   = errors.New("user has reached the maximum number of pending suggestions")
 
 	// Session collection errors
 	ErrSessionAlreadyExisting = errors.New("session with this id already exists")
 	ErrNoSessionWithID        = errors.New("no session with given id")
 	ErrSongAlreadyInSession   = errors.New("song with this ID already exists for this session")
+	ErrInvalidInvite          = errors.New("invite does not exist or has expired")
+	ErrNoSessionWithJoinCode  = errors.New("no session with this join code")
+	ErrJoinCodesExhausted     = errors.New("could not allocate an unused join code")
 )
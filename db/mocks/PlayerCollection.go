@@ -17,6 +17,66 @@ type PlayerCollection struct {
 	mock.Mock
 }
 
+// AddHistoryEntry provides a mock function with given fields: ctx, sessionID, entry
+func (_m *PlayerCollection) AddHistoryEntry(ctx context.Context, sessionID string, entry *player.HistoryEntry) error {
+	ret := _m.Called(ctx, sessionID, entry)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, *player.HistoryEntry) error); ok {
+		r0 = rf(ctx, sessionID, entry)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// AddSkipVote provides a mock function with given fields: ctx, sessionID, songID, username
+func (_m *PlayerCollection) AddSkipVote(ctx context.Context, sessionID string, songID string, username string) (*player.Player, error) {
+	ret := _m.Called(ctx, sessionID, songID, username)
+
+	var r0 *player.Player
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string) *player.Player); ok {
+		r0 = rf(ctx, sessionID, songID, username)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*player.Player)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, string) error); ok {
+		r1 = rf(ctx, sessionID, songID, username)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetHistory provides a mock function with given fields: ctx, sessionID
+func (_m *PlayerCollection) GetHistory(ctx context.Context, sessionID string) ([]*player.HistoryEntry, error) {
+	ret := _m.Called(ctx, sessionID)
+
+	var r0 []*player.HistoryEntry
+	if rf, ok := ret.Get(0).(func(context.Context, string) []*player.HistoryEntry); ok {
+		r0 = rf(ctx, sessionID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*player.HistoryEntry)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, sessionID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetPlayer provides a mock function with given fields: ctx, sessionID
 func (_m *PlayerCollection) GetPlayer(ctx context.Context, sessionID string) (*player.Player, error) {
 	ret := _m.Called(ctx, sessionID)
@@ -40,6 +100,36 @@ func (_m *PlayerCollection) GetPlayer(ctx context.Context, sessionID string) (*p
 	return r0, r1
 }
 
+// GetSetlist provides a mock function with given fields: ctx, sessionID
+func (_m *PlayerCollection) GetSetlist(ctx context.Context, sessionID string) ([]*player.SetlistEntry, int, error) {
+	ret := _m.Called(ctx, sessionID)
+
+	var r0 []*player.SetlistEntry
+	if rf, ok := ret.Get(0).(func(context.Context, string) []*player.SetlistEntry); ok {
+		r0 = rf(ctx, sessionID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*player.SetlistEntry)
+		}
+	}
+
+	var r1 int
+	if rf, ok := ret.Get(1).(func(context.Context, string) int); ok {
+		r1 = rf(ctx, sessionID)
+	} else {
+		r1 = ret.Get(1).(int)
+	}
+
+	var r2 error
+	if rf, ok := ret.Get(2).(func(context.Context, string) error); ok {
+		r2 = rf(ctx, sessionID)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
 // IncrementProgress provides a mock function with given fields: ctx, sessionID, progress
 func (_m *PlayerCollection) IncrementProgress(ctx context.Context, sessionID string, progress time.Duration) error {
 	ret := _m.Called(ctx, sessionID, progress)
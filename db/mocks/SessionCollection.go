@@ -17,6 +17,34 @@ type SessionCollection struct {
 	mock.Mock
 }
 
+// AddBan provides a mock function with given fields: ctx, sessionID, ban
+func (_m *SessionCollection) AddBan(ctx context.Context, sessionID string, ban *session.Ban) error {
+	ret := _m.Called(ctx, sessionID, ban)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, *session.Ban) error); ok {
+		r0 = rf(ctx, sessionID, ban)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// AddInvite provides a mock function with given fields: ctx, sessionID, invite
+func (_m *SessionCollection) AddInvite(ctx context.Context, sessionID string, invite *session.Invite) error {
+	ret := _m.Called(ctx, sessionID, invite)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, *session.Invite) error); ok {
+		r0 = rf(ctx, sessionID, invite)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // AddSession provides a mock function with given fields: ctx, sess
 func (_m *SessionCollection) AddSession(ctx context.Context, sess *session.Session) error {
 	ret := _m.Called(ctx, sess)
@@ -82,6 +110,29 @@ func (_m *SessionCollection) GetSessionByID(ctx context.Context, sessionID strin
 	return r0, r1
 }
 
+// GetSettings provides a mock function with given fields: ctx, sessionID
+func (_m *SessionCollection) GetSettings(ctx context.Context, sessionID string) (*session.Settings, error) {
+	ret := _m.Called(ctx, sessionID)
+
+	var r0 *session.Settings
+	if rf, ok := ret.Get(0).(func(context.Context, string) *session.Settings); ok {
+		r0 = rf(ctx, sessionID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*session.Settings)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, sessionID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // ListExpiredSessions provides a mock function with given fields: ctx, sessionExpiration
 func (_m *SessionCollection) ListExpiredSessions(ctx context.Context, sessionExpiration time.Duration) ([]string, error) {
 	ret := _m.Called(ctx, sessionExpiration)
@@ -128,7 +179,79 @@ func (_m *SessionCollection) ListSessionIDs(ctx context.Context) ([]string, erro
 	return r0, r1
 }
 
+// RedeemInvite provides a mock function with given fields: ctx, sessionID, token
+func (_m *SessionCollection) RedeemInvite(ctx context.Context, sessionID string, token string) (*session.Invite, error) {
+	ret := _m.Called(ctx, sessionID, token)
+
+	var r0 *session.Invite
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) *session.Invite); ok {
+		r0 = rf(ctx, sessionID, token)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*session.Invite)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, sessionID, token)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ResolveJoinCode provides a mock function with given fields: ctx, code
+func (_m *SessionCollection) ResolveJoinCode(ctx context.Context, code string) (string, error) {
+	ret := _m.Called(ctx, code)
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func(context.Context, string) string); ok {
+		r0 = rf(ctx, code)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, code)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // SetLastUpdated provides a mock function with given fields: ctx, sessionID
 func (_m *SessionCollection) SetLastUpdated(ctx context.Context, sessionID string) {
 	_m.Called(ctx, sessionID)
 }
+
+// SetPassword provides a mock function with given fields: ctx, sessionID, passwordHash
+func (_m *SessionCollection) SetPassword(ctx context.Context, sessionID string, passwordHash string) error {
+	ret := _m.Called(ctx, sessionID, passwordHash)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(ctx, sessionID, passwordHash)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UpdateSettings provides a mock function with given fields: ctx, sessionID, settings
+func (_m *SessionCollection) UpdateSettings(ctx context.Context, sessionID string, settings *session.Settings) error {
+	ret := _m.Called(ctx, sessionID, settings)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, *session.Settings) error); ok {
+		r0 = rf(ctx, sessionID, settings)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
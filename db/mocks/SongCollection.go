@@ -7,6 +7,8 @@ import (
 
 	mock "github.com/stretchr/testify/mock"
 
+	session "github.com/encore-fm/backend/session"
+
 	song "github.com/encore-fm/backend/song"
 )
 
@@ -15,13 +17,27 @@ type SongCollection struct {
 	mock.Mock
 }
 
-// AddSong provides a mock function with given fields: ctx, sessionID, newSong
-func (_m *SongCollection) AddSong(ctx context.Context, sessionID string, newSong *song.Model) error {
-	ret := _m.Called(ctx, sessionID, newSong)
+// AddSong provides a mock function with given fields: ctx, sessionID, newSong, maxPending
+func (_m *SongCollection) AddSong(ctx context.Context, sessionID string, newSong *song.Model, maxPending int) error {
+	ret := _m.Called(ctx, sessionID, newSong, maxPending)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, string, *song.Model) error); ok {
-		r0 = rf(ctx, sessionID, newSong)
+	if rf, ok := ret.Get(0).(func(context.Context, string, *song.Model, int) error); ok {
+		r0 = rf(ctx, sessionID, newSong, maxPending)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// AddSongs provides a mock function with given fields: ctx, sessionID, newSongs, maxPending
+func (_m *SongCollection) AddSongs(ctx context.Context, sessionID string, newSongs []*song.Model, maxPending int) error {
+	ret := _m.Called(ctx, sessionID, newSongs, maxPending)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, []*song.Model, int) error); ok {
+		r0 = rf(ctx, sessionID, newSongs, maxPending)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -89,25 +105,46 @@ func (_m *SongCollection) RemoveSong(ctx context.Context, sessionID string, song
 	return r0
 }
 
-// VoteDown provides a mock function with given fields: ctx, sessionID, songID, username
-func (_m *SongCollection) VoteDown(ctx context.Context, sessionID string, songID string, username string) (int, error) {
-	ret := _m.Called(ctx, sessionID, songID, username)
+// SetPriorities provides a mock function with given fields: ctx, sessionID, priorities
+func (_m *SongCollection) SetPriorities(ctx context.Context, sessionID string, priorities map[string]int) error {
+	ret := _m.Called(ctx, sessionID, priorities)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, map[string]int) error); ok {
+		r0 = rf(ctx, sessionID, priorities)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// VoteDown provides a mock function with given fields: ctx, sessionID, songID, username, threshold
+func (_m *SongCollection) VoteDown(ctx context.Context, sessionID string, songID string, username string, threshold session.RemovalThreshold) (int, session.RemovalReason, error) {
+	ret := _m.Called(ctx, sessionID, songID, username, threshold)
 
 	var r0 int
-	if rf, ok := ret.Get(0).(func(context.Context, string, string, string) int); ok {
-		r0 = rf(ctx, sessionID, songID, username)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string, session.RemovalThreshold) int); ok {
+		r0 = rf(ctx, sessionID, songID, username, threshold)
 	} else {
 		r0 = ret.Get(0).(int)
 	}
 
-	var r1 error
-	if rf, ok := ret.Get(1).(func(context.Context, string, string, string) error); ok {
-		r1 = rf(ctx, sessionID, songID, username)
+	var r1 session.RemovalReason
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, string, session.RemovalThreshold) session.RemovalReason); ok {
+		r1 = rf(ctx, sessionID, songID, username, threshold)
 	} else {
-		r1 = ret.Error(1)
+		r1 = ret.Get(1).(session.RemovalReason)
 	}
 
-	return r0, r1
+	var r2 error
+	if rf, ok := ret.Get(2).(func(context.Context, string, string, string, session.RemovalThreshold) error); ok {
+		r2 = rf(ctx, sessionID, songID, username, threshold)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
 }
 
 // VoteUp provides a mock function with given fields: ctx, sessionID, songID, username
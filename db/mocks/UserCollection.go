@@ -9,6 +9,8 @@ import (
 
 	oauth2 "golang.org/x/oauth2"
 
+	time "time"
+
 	user "github.com/encore-fm/backend/user"
 )
 
@@ -52,6 +54,73 @@ func (_m *UserCollection) AddUser(ctx context.Context, newUser *user.Model) erro
 	return r0
 }
 
+// AdmitWaitingUsers provides a mock function with given fields: ctx, sessionID
+func (_m *UserCollection) AdmitWaitingUsers(ctx context.Context, sessionID string) ([]*user.Model, error) {
+	ret := _m.Called(ctx, sessionID)
+
+	var r0 []*user.Model
+	if rf, ok := ret.Get(0).(func(context.Context, string) []*user.Model); ok {
+		r0 = rf(ctx, sessionID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*user.Model)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, sessionID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ClaimSuggestion provides a mock function with given fields: ctx, userID, now, minInterval
+func (_m *UserCollection) ClaimSuggestion(ctx context.Context, userID string, now time.Time, minInterval time.Duration) (*user.Model, error) {
+	ret := _m.Called(ctx, userID, now, minInterval)
+
+	var r0 *user.Model
+	if rf, ok := ret.Get(0).(func(context.Context, string, time.Time, time.Duration) *user.Model); ok {
+		r0 = rf(ctx, userID, now, minInterval)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*user.Model)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, time.Time, time.Duration) error); ok {
+		r1 = rf(ctx, userID, now, minInterval)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CountSSEConnections provides a mock function with given fields: ctx, sessionID
+func (_m *UserCollection) CountSSEConnections(ctx context.Context, sessionID string) (int, error) {
+	ret := _m.Called(ctx, sessionID)
+
+	var r0 int
+	if rf, ok := ret.Get(0).(func(context.Context, string) int); ok {
+		r0 = rf(ctx, sessionID)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, sessionID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // DeleteUser provides a mock function with given fields: ctx, userID
 func (_m *UserCollection) DeleteUser(ctx context.Context, userID string) error {
 	ret := _m.Called(ctx, userID)
@@ -140,6 +209,29 @@ func (_m *UserCollection) GetSpotifyClient(ctx context.Context, userID string) (
 	return r0, r1
 }
 
+// GetSuccessor provides a mock function with given fields: ctx, sessionID, ownerID
+func (_m *UserCollection) GetSuccessor(ctx context.Context, sessionID string, ownerID string) (*user.Model, error) {
+	ret := _m.Called(ctx, sessionID, ownerID)
+
+	var r0 *user.Model
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) *user.Model); ok {
+		r0 = rf(ctx, sessionID, ownerID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*user.Model)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, sessionID, ownerID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetSyncedSpotifyClients provides a mock function with given fields: ctx, sessionID
 func (_m *UserCollection) GetSyncedSpotifyClients(ctx context.Context, sessionID string) ([]*user.SpotifyClient, error) {
 	ret := _m.Called(ctx, sessionID)
@@ -246,6 +338,29 @@ func (_m *UserCollection) ListUsers(ctx context.Context, sessionID string) ([]*u
 	return r0, r1
 }
 
+// ListWaitingSessionIDs provides a mock function with given fields: ctx
+func (_m *UserCollection) ListWaitingSessionIDs(ctx context.Context) ([]string, error) {
+	ret := _m.Called(ctx)
+
+	var r0 []string
+	if rf, ok := ret.Get(0).(func(context.Context) []string); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // RemoveSSEConnection provides a mock function with given fields: ctx, userID
 func (_m *UserCollection) RemoveSSEConnection(ctx context.Context, userID string) (int, error) {
 	ret := _m.Called(ctx, userID)
@@ -295,6 +410,34 @@ func (_m *UserCollection) SetAutoSync(ctx context.Context, userID string, autoSy
 	return r0
 }
 
+// SetLastSuggestion provides a mock function with given fields: ctx, userID, lastSuggestion
+func (_m *UserCollection) SetLastSuggestion(ctx context.Context, userID string, lastSuggestion time.Time) error {
+	ret := _m.Called(ctx, userID, lastSuggestion)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, time.Time) error); ok {
+		r0 = rf(ctx, userID, lastSuggestion)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SetRole provides a mock function with given fields: ctx, userID, role
+func (_m *UserCollection) SetRole(ctx context.Context, userID string, role user.Role) error {
+	ret := _m.Called(ctx, userID, role)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, user.Role) error); ok {
+		r0 = rf(ctx, userID, role)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // SetSynchronized provides a mock function with given fields: ctx, userID, synchronized
 func (_m *UserCollection) SetSynchronized(ctx context.Context, userID string, synchronized bool) error {
 	ret := _m.Called(ctx, userID, synchronized)
@@ -322,3 +465,17 @@ func (_m *UserCollection) SetToken(ctx context.Context, userID string, token *oa
 
 	return r0
 }
+
+// TransferOwnership provides a mock function with given fields: ctx, ownerID, targetID
+func (_m *UserCollection) TransferOwnership(ctx context.Context, ownerID string, targetID string) error {
+	ret := _m.Called(ctx, ownerID, targetID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(ctx, ownerID, targetID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
@@ -2,6 +2,7 @@ package db
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
@@ -19,6 +20,10 @@ type PlayerCollection interface {
 	SetPaused(ctx context.Context, sessionID string) error
 	SetPlaying(ctx context.Context, sessionID string) error
 	IncrementProgress(ctx context.Context, sessionID string, progress time.Duration) error
+	AddSkipVote(ctx context.Context, sessionID string, songID string, username string) (*player.Player, error)
+	AddHistoryEntry(ctx context.Context, sessionID string, entry *player.HistoryEntry) error
+	GetHistory(ctx context.Context, sessionID string) ([]*player.HistoryEntry, error)
+	GetSetlist(ctx context.Context, sessionID string) ([]*player.SetlistEntry, int, error)
 }
 
 type playerCollection struct {
@@ -192,3 +197,134 @@ func (c *playerCollection) IncrementProgress(ctx context.Context, sessionID stri
 	}
 	return nil
 }
+
+// AddSkipVote records a user's vote to skip the song that is currently playing
+// voting twice has no effect
+// returns the updated player
+// Errors:
+// - ErrNoSongWithID if the song is not playing (anymore)
+func (c *playerCollection) AddSkipVote(
+	ctx context.Context,
+	sessionID string,
+	songID string,
+	username string,
+) (*player.Player, error) {
+	errMsg := "[db] add skip vote: %w"
+	filter := bson.M{
+		"_id":                    sessionID,
+		"player.current_song.id": songID,
+	}
+	update := bson.M{
+		"$addToSet": bson.M{"player.skip_votes": username},
+	}
+	after := options.After
+	opt := options.FindOneAndUpdate().
+		SetProjection(bson.M{"_id": 0, "player": 1}).
+		SetReturnDocument(after)
+
+	var sess session.Session
+	err := c.collection.FindOneAndUpdate(ctx, filter, update, opt).Decode(&sess)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, fmt.Errorf(errMsg, ErrNoSongWithID)
+		}
+		return nil, fmt.Errorf(errMsg, err)
+	}
+	return sess.Player, nil
+}
+
+// AddHistoryEntry appends a played song to the history and the setlist of a session,
+// only the last player.HistoryLen history entries and player.SetlistLen setlist entries are kept
+func (c *playerCollection) AddHistoryEntry(ctx context.Context, sessionID string, entry *player.HistoryEntry) error {
+	errMsg := "[db] add history entry: %w"
+	filter := bson.D{{"_id", sessionID}}
+	push := bson.D{
+		{
+			"history",
+			bson.D{
+				{"$each", []*player.HistoryEntry{entry}},
+				{"$slice", -player.HistoryLen},
+			},
+		},
+	}
+	update := bson.D{{"$push", push}}
+	if entry.Song != nil {
+		push = append(push, bson.E{
+			Key: "setlist",
+			Value: bson.D{
+				{"$each", []*player.SetlistEntry{{SongID: entry.Song.ID, Started: entry.Started}}},
+				{"$slice", -player.SetlistLen},
+			},
+		})
+		update = bson.D{
+			{"$push", push},
+			{"$inc", bson.D{{"played", 1}}},
+		}
+	}
+	result, err := c.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf(errMsg, err)
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf(errMsg, ErrNoSessionWithID)
+	}
+	return nil
+}
+
+// GetHistory returns the played songs of a session, oldest first
+func (c *playerCollection) GetHistory(ctx context.Context, sessionID string) ([]*player.HistoryEntry, error) {
+	errMsg := "[db] get history: %w"
+	filter := bson.D{{"_id", sessionID}}
+	projection := bson.D{
+		{"_id", 0},
+		{"history", 1},
+	}
+
+	var sess session.Session
+	err := c.collection.FindOne(
+		ctx,
+		filter,
+		options.FindOne().SetProjection(projection),
+	).Decode(&sess)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, fmt.Errorf(errMsg, ErrNoSessionWithID)
+		}
+		return nil, fmt.Errorf(errMsg, err)
+	}
+
+	if sess.History == nil {
+		return make([]*player.HistoryEntry, 0), nil
+	}
+	return sess.History, nil
+}
+
+// GetSetlist returns the setlist of a session, oldest first, and the number of songs played in the session.
+// Songs have been dropped from the setlist if more songs were played than it contains.
+func (c *playerCollection) GetSetlist(ctx context.Context, sessionID string) ([]*player.SetlistEntry, int, error) {
+	errMsg := "[db] get setlist: %w"
+	filter := bson.D{{"_id", sessionID}}
+	projection := bson.D{
+		{"_id", 0},
+		{"setlist", 1},
+		{"played", 1},
+	}
+
+	var sess session.Session
+	err := c.collection.FindOne(
+		ctx,
+		filter,
+		options.FindOne().SetProjection(projection),
+	).Decode(&sess)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, 0, fmt.Errorf(errMsg, ErrNoSessionWithID)
+		}
+		return nil, 0, fmt.Errorf(errMsg, err)
+	}
+
+	if sess.Setlist == nil {
+		return make([]*player.SetlistEntry, 0), sess.Played, nil
+	}
+	return sess.Setlist, sess.Played, nil
+}
@@ -23,11 +23,23 @@ type SessionCollection interface {
 	ListExpiredSessions(ctx context.Context, sessionExpiration time.Duration) ([]string, error)
 	DeleteSessions(ctx context.Context, sessionIDs []string) error
 	SetLastUpdated(ctx context.Context, sessionID string)
+	GetSettings(ctx context.Context, sessionID string) (*session.Settings, error)
+	UpdateSettings(ctx context.Context, sessionID string, settings *session.Settings) error
+	SetPassword(ctx context.Context, sessionID string, passwordHash string) error
+	AddInvite(ctx context.Context, sessionID string, invite *session.Invite) error
+	RedeemInvite(ctx context.Context, sessionID string, token string) (*session.Invite, error)
+	ResolveJoinCode(ctx context.Context, code string) (string, error)
+	AddBan(ctx context.Context, sessionID string, ban *session.Ban) error
 }
 
+// number of attempts to find an unused join code before giving up
+const joinCodeAttempts = 10
+
 type sessionCollection struct {
 	client     *mongo.Client
 	collection *mongo.Collection
+	// maps join codes (stored as _id, so they are unique) to session ids
+	joinCodes *mongo.Collection
 }
 
 var _ SessionCollection = (*sessionCollection)(nil)
@@ -37,17 +49,32 @@ func NewSessionCollection(client *mongo.Client) SessionCollection {
 	collection := client.
 		Database(config.Conf.Database.DBName).
 		Collection(config.Conf.Database.SessionCollectionName)
+	joinCodes := client.
+		Database(config.Conf.Database.DBName).
+		Collection(config.Conf.Database.JoinCodeCollectionName)
 	return &sessionCollection{
 		client:     client,
 		collection: collection,
+		joinCodes:  joinCodes,
 	}
 }
 
-// AddSession inserts a new session into session collection
+// AddSession allocates a join code and inserts a new session into session collection
 // if session with this id already exists it returns `ErrSessionAlreadyExisting`
 func (c *sessionCollection) AddSession(ctx context.Context, sess *session.Session) error {
 	errMsg := "[db] add session: %w"
+
+	code, err := c.allocateJoinCode(ctx, sess.ID)
+	if err != nil {
+		return fmt.Errorf(errMsg, err)
+	}
+	sess.JoinCode = code
+
 	if _, err := c.collection.InsertOne(ctx, sess); err != nil {
+		// the session was not created, free its code again
+		if _, releaseErr := c.joinCodes.DeleteOne(ctx, bson.M{"_id": code}); releaseErr != nil {
+			log.Errorf(errMsg, releaseErr)
+		}
 		if err, ok := err.(mongo.WriteException); ok {
 			log.Error(err)
 			return fmt.Errorf(errMsg, ErrSessionAlreadyExisting)
@@ -57,7 +84,7 @@ func (c *sessionCollection) AddSession(ctx context.Context, sess *session.Sessio
 	return nil
 }
 
-// DeleteSession deletes a session from the session collection
+// DeleteSession deletes a session from the session collection and releases its join code
 // if session with this id does not exists, returns `ErrNoSessionWithID` error
 func (c *sessionCollection) DeleteSession(ctx context.Context, sessionID string) error {
 	errMsg := "[db] delete session: %w"
@@ -71,10 +98,13 @@ func (c *sessionCollection) DeleteSession(ctx context.Context, sessionID string)
 		return fmt.Errorf(errMsg, ErrNoSessionWithID)
 	}
 
+	if err := c.releaseJoinCodes(ctx, []string{sessionID}); err != nil {
+		return fmt.Errorf(errMsg, err)
+	}
 	return nil
 }
 
-// deletes multiple sessions simultaneously
+// deletes multiple sessions simultaneously and releases their join codes
 func (c *sessionCollection) DeleteSessions(ctx context.Context, sessionIDs []string) error {
 	errMsg := "[db] delete sessions: %w"
 	filter := bson.M{
@@ -86,6 +116,9 @@ func (c *sessionCollection) DeleteSessions(ctx context.Context, sessionIDs []str
 	if err != nil {
 		return fmt.Errorf(errMsg, err)
 	}
+	if err := c.releaseJoinCodes(ctx, sessionIDs); err != nil {
+		return fmt.Errorf(errMsg, err)
+	}
 	if res.DeletedCount != int64(len(sessionIDs)) {
 		deleteErr := fmt.Errorf("one or more sessions with the given ids could not be deleted, "+
 			"expected count: %v, got: %v", len(sessionIDs), res.DeletedCount)
@@ -206,3 +239,224 @@ func (c *sessionCollection) SetLastUpdated(ctx context.Context, sessionID string
 		log.Errorf(errMsg, ErrNoSessionWithID)
 	}
 }
+
+// GetSettings returns the settings of a session
+// sessions created before settings existed get the default settings
+func (c *sessionCollection) GetSettings(ctx context.Context, sessionID string) (*session.Settings, error) {
+	errMsg := "[db] get settings: %w"
+	filter := bson.D{{"_id", sessionID}}
+	projection := bson.D{
+		{"_id", 0},
+		{"settings", 1},
+	}
+
+	var sess session.Session
+	err := c.collection.FindOne(
+		ctx,
+		filter,
+		options.FindOne().SetProjection(projection),
+	).Decode(&sess)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, fmt.Errorf(errMsg, ErrNoSessionWithID)
+		}
+		return nil, fmt.Errorf(errMsg, err)
+	}
+
+	if sess.Settings == nil {
+		return session.DefaultSettings(), nil
+	}
+	return sess.Settings, nil
+}
+
+// UpdateSettings replaces the settings of a session
+func (c *sessionCollection) UpdateSettings(ctx context.Context, sessionID string, settings *session.Settings) error {
+	errMsg := "[db] update settings: %w"
+
+	filter := bson.D{{"_id", sessionID}}
+	update := bson.D{
+		{
+			Key: "$set",
+			Value: bson.D{
+				{
+					Key:   "settings",
+					Value: settings,
+				},
+			},
+		},
+	}
+
+	result, err := c.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf(errMsg, err)
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf(errMsg, ErrNoSessionWithID)
+	}
+	return nil
+}
+
+// SetPassword sets the hashed session password, an empty hash removes the password
+func (c *sessionCollection) SetPassword(ctx context.Context, sessionID string, passwordHash string) error {
+	errMsg := "[db] set password: %w"
+
+	filter := bson.M{"_id": sessionID}
+	update := bson.M{
+		"$set": bson.M{"password_hash": passwordHash},
+	}
+
+	result, err := c.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf(errMsg, err)
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf(errMsg, ErrNoSessionWithID)
+	}
+	return nil
+}
+
+// AddInvite adds an invite to the session
+func (c *sessionCollection) AddInvite(ctx context.Context, sessionID string, invite *session.Invite) error {
+	errMsg := "[db] add invite: %w"
+
+	filter := bson.M{"_id": sessionID}
+	update := bson.M{
+		"$push": bson.M{"invites": invite},
+	}
+
+	result, err := c.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf(errMsg, err)
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf(errMsg, ErrNoSessionWithID)
+	}
+	return nil
+}
+
+// AddBan adds a ban to the session's ban list
+func (c *sessionCollection) AddBan(ctx context.Context, sessionID string, ban *session.Ban) error {
+	errMsg := "[db] add ban: %w"
+
+	filter := bson.M{"_id": sessionID}
+	update := bson.M{
+		"$push": bson.M{"bans": ban},
+	}
+
+	result, err := c.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf(errMsg, err)
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf(errMsg, ErrNoSessionWithID)
+	}
+	return nil
+}
+
+// RedeemInvite checks whether a session has a valid invite with the given token
+// single use invites are removed in the same operation, so they can only be redeemed once
+// Errors:
+// - ErrInvalidInvite if the invite does not exist, has expired or has already been used
+func (c *sessionCollection) RedeemInvite(ctx context.Context, sessionID string, token string) (*session.Invite, error) {
+	errMsg := "[db] redeem invite: %w"
+
+	validInvite := bson.M{
+		"token": token,
+		"$or": bson.A{
+			bson.M{"expires_at": time.Time{}},
+			bson.M{"expires_at": bson.M{"$gt": time.Now()}},
+		},
+	}
+	filter := bson.M{
+		"_id":     sessionID,
+		"invites": bson.M{"$elemMatch": validInvite},
+	}
+	projection := bson.M{
+		"invites": bson.M{"$elemMatch": bson.M{"token": token}},
+	}
+
+	// single use invites are pulled, multi use invites stay untouched
+	update := bson.M{
+		"$pull": bson.M{
+			"invites": bson.M{
+				"token":      token,
+				"single_use": true,
+			},
+		},
+	}
+	opt := options.FindOneAndUpdate().SetProjection(projection)
+
+	var sess *session.Session
+	err := c.collection.FindOneAndUpdate(ctx, filter, update, opt).Decode(&sess)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, fmt.Errorf(errMsg, ErrInvalidInvite)
+		}
+		return nil, fmt.Errorf(errMsg, err)
+	}
+	if len(sess.Invites) == 0 {
+		return nil, fmt.Errorf(errMsg, ErrInvalidInvite)
+	}
+	return sess.Invites[0], nil
+}
+
+// reserves an unused join code for the session
+// codes are generated randomly, on a collision a new code is drawn
+// Errors:
+// - ErrJoinCodesExhausted if no unused code was found
+func (c *sessionCollection) allocateJoinCode(ctx context.Context, sessionID string) (string, error) {
+	for i := 0; i < joinCodeAttempts; i++ {
+		code, err := session.NewJoinCode()
+		if err != nil {
+			return "", err
+		}
+
+		joinCode := bson.M{
+			"_id":        code,
+			"session_id": sessionID,
+		}
+		if _, err := c.joinCodes.InsertOne(ctx, joinCode); err != nil {
+			// code is already taken
+			if _, ok := err.(mongo.WriteException); ok {
+				continue
+			}
+			return "", fmt.Errorf("allocate join code: %w", err)
+		}
+		return code, nil
+	}
+
+	return "", ErrJoinCodesExhausted
+}
+
+// ResolveJoinCode returns the id of the session the join code belongs to
+// Errors:
+// - ErrNoSessionWithJoinCode if the code is not allocated
+func (c *sessionCollection) ResolveJoinCode(ctx context.Context, code string) (string, error) {
+	errMsg := "[db] resolve join code: %w"
+	filter := bson.M{"_id": session.NormalizeJoinCode(code)}
+
+	joinCode := &struct {
+		SessionID string `bson:"session_id"`
+	}{}
+	err := c.joinCodes.FindOne(ctx, filter).Decode(joinCode)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return "", fmt.Errorf(errMsg, ErrNoSessionWithJoinCode)
+		}
+		return "", fmt.Errorf(errMsg, err)
+	}
+	return joinCode.SessionID, nil
+}
+
+// releases the join codes of the given sessions, so they can be allocated again
+func (c *sessionCollection) releaseJoinCodes(ctx context.Context, sessionIDs []string) error {
+	filter := bson.M{
+		"session_id": bson.M{
+			"$in": sessionIDs,
+		},
+	}
+	if _, err := c.joinCodes.DeleteMany(ctx, filter); err != nil {
+		return fmt.Errorf("release join codes: %w", err)
+	}
+	return nil
+}
@@ -2,9 +2,12 @@ package db
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/encore-fm/backend/config"
+	"github.com/encore-fm/backend/queue"
 	"github.com/encore-fm/backend/session"
 	"github.com/encore-fm/backend/song"
 	"go.mongodb.org/mongo-driver/bson"
@@ -14,11 +17,13 @@ import (
 
 type SongCollection interface {
 	GetSongByID(ctx context.Context, sessionID, songID string) (*song.Model, error)
-	AddSong(ctx context.Context, sessionID string, newSong *song.Model) error
+	AddSong(ctx context.Context, sessionID string, newSong *song.Model, maxPending int) error
+	AddSongs(ctx context.Context, sessionID string, newSongs []*song.Model, maxPending int) error
 	RemoveSong(ctx context.Context, sessionID, songID string) error
 	ListSongs(ctx context.Context, sessionID string) ([]*song.Model, error)
+	SetPriorities(ctx context.Context, sessionID string, priorities map[string]int) error
 	VoteUp(ctx context.Context, sessionID, songID, username string) (int, error)
-	VoteDown(ctx context.Context, sessionID, songID, username string) (int, error)
+	VoteDown(ctx context.Context, sessionID, songID, username string, threshold session.RemovalThreshold) (int, session.RemovalReason, error)
 }
 
 type songCollection struct {
@@ -73,9 +78,12 @@ func (c *songCollection) GetSongByID(ctx context.Context, sessionID string, song
 }
 
 // AddSong adds a song to a session and sorts SongList
+// the suggester can have at most maxPending songs in the queue afterwards, 0 means no limit
 // Errors:
+// - ErrNoSessionWithID
 // - ErrSongAlreadyInSession
-func (c *songCollection) AddSong(ctx context.Context, sessionID string, newSong *song.Model) error {
+// - ErrTooManySuggestions
+func (c *songCollection) AddSong(ctx context.Context, sessionID string, newSong *song.Model, maxPending int) error {
 	errMsg := "[db] add song: %w"
 
 	filter := bson.D{
@@ -90,6 +98,9 @@ func (c *songCollection) AddSong(ctx context.Context, sessionID string, newSong
 			},
 		},
 	}
+	if maxPending > 0 {
+		filter = append(filter, pendingLimit(newSong.SuggestedBy, maxPending-1))
+	}
 
 	// todo: dont sort on insert
 	// or sort on insert and update but dont on find
@@ -123,11 +134,115 @@ func (c *songCollection) AddSong(ctx context.Context, sessionID string, newSong
 	}
 
 	if result.MatchedCount == 0 {
-		return fmt.Errorf(errMsg, ErrSongAlreadyInSession)
+		return fmt.Errorf(errMsg, c.addSongsError(ctx, sessionID, []string{newSong.ID}, maxPending))
 	}
 	return nil
 }
 
+// AddSongs adds several songs of the same user to the song list with a single update.
+// Either all songs are added or none:
+// - ErrNoSessionWithID if the session does not exist
+// - ErrSongAlreadyInSession if any of the songs is in the song list already
+// - ErrTooManySuggestions if the user would have more than maxPending songs in the queue, 0 means no limit
+func (c *songCollection) AddSongs(ctx context.Context, sessionID string, newSongs []*song.Model, maxPending int) error {
+	errMsg := "[db] add songs: %w"
+	if len(newSongs) == 0 {
+		return nil
+	}
+
+	songIDs := make([]string, 0, len(newSongs))
+	for _, s := range newSongs {
+		songIDs = append(songIDs, s.ID)
+	}
+
+	filter := bson.D{
+		{"_id", sessionID},
+		{"song_list.id", bson.D{{"$nin", songIDs}}},
+	}
+	if maxPending > 0 {
+		filter = append(filter, pendingLimit(newSongs[0].SuggestedBy, maxPending-len(newSongs)))
+	}
+	update := bson.D{
+		{
+			"$push",
+			bson.D{
+				{
+					"song_list",
+					bson.D{
+						{"$each", newSongs},
+						{
+							"$sort",
+							bson.D{
+								{"score", -1},
+								{"time_added", 1},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	result, err := c.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		if _, ok := err.(mongo.WriteException); ok {
+			return fmt.Errorf(errMsg, ErrSongAlreadyInSession)
+		}
+		return fmt.Errorf(errMsg, err)
+	}
+
+	if result.MatchedCount == 0 {
+		return fmt.Errorf(errMsg, c.addSongsError(ctx, sessionID, songIDs, maxPending))
+	}
+	return nil
+}
+
+// pendingLimit returns a filter matching sessions in which the user has at most maxPending songs
+func pendingLimit(username string, maxPending int) bson.E {
+	suggested := bson.D{{"$filter", bson.D{
+		{"input", bson.D{{"$ifNull", bson.A{"$song_list", bson.A{}}}}},
+		{"as", "song"},
+		{"cond", bson.D{{"$eq", bson.A{"$$song.suggested_by", username}}}},
+	}}}
+	return bson.E{Key: "$expr", Value: bson.D{{"$lte", bson.A{bson.D{{"$size", suggested}}, maxPending}}}}
+}
+
+// addSongsError returns why an update adding songs to a session did not match the session
+func (c *songCollection) addSongsError(ctx context.Context, sessionID string, songIDs []string, maxPending int) error {
+	filter := bson.D{{"_id", sessionID}}
+	projection := bson.D{
+		{"_id", 0},
+		{"song_list.id", 1},
+	}
+
+	var sess session.Session
+	err := c.collection.FindOne(
+		ctx,
+		filter,
+		options.FindOne().SetProjection(projection),
+	).Decode(&sess)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return ErrNoSessionWithID
+		}
+		return err
+	}
+
+	if maxPending == 0 {
+		return ErrSongAlreadyInSession
+	}
+	added := make(map[string]bool, len(songIDs))
+	for _, id := range songIDs {
+		added[id] = true
+	}
+	for _, s := range sess.SongList {
+		if added[s.ID] {
+			return ErrSongAlreadyInSession
+		}
+	}
+	return ErrTooManySuggestions
+}
+
 // RemoveSong removes a song from collection
 // todo: write test
 func (c *songCollection) RemoveSong(ctx context.Context, sessionID, songID string) error {
@@ -160,62 +275,71 @@ func (c *songCollection) RemoveSong(ctx context.Context, sessionID, songID strin
 	return nil
 }
 
-// ListSongs returns a sorted list of all songs in a session
-// todo: test
+// ListSongs returns all songs of a session in playback order: pinned songs first,
+// then the rest in the order of the session's queue ordering strategy
 func (c *songCollection) ListSongs(ctx context.Context, sessionID string) ([]*song.Model, error) {
 	errMsg := "[db] list songs: %w"
 
-	matchStage := bson.D{
-		{"$match", bson.D{{"_id", sessionID}}},
+	filter := bson.D{{"_id", sessionID}}
+	projection := bson.D{
+		{"_id", 0},
+		{"song_list", 1},
+		{"settings.queue_ordering", 1},
 	}
 
-	unwindStage := bson.D{
-		{"$unwind", "$song_list"},
+	var sess *session.Session
+	err := c.collection.FindOne(
+		ctx,
+		filter,
+		options.FindOne().SetProjection(projection),
+	).Decode(&sess)
+	if err != nil {
+		// a session that does not exist has no songs
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return make([]*song.Model, 0), nil
+		}
+		return nil, fmt.Errorf(errMsg, err)
 	}
 
-	sortStage := bson.D{
-		{
-			"$sort",
-			bson.D{
-				{"song_list.score", -1},
-				{"song_list.time_added", 1},
-			},
-		},
+	ordering := queue.OrderScore
+	if sess.Settings != nil {
+		ordering = sess.Settings.QueueOrdering
 	}
+	return queue.Arrange(ordering, sess.SongList, time.Now()), nil
+}
 
-	projectStage := bson.D{
-		{"$project", bson.D{
-			{"_id", 1},
-			{"song_list", 1},
-		}},
+// SetPriorities sets the priority of the given songs in a single update,
+// songs missing from priorities keep their priority
+func (c *songCollection) SetPriorities(ctx context.Context, sessionID string, priorities map[string]int) error {
+	errMsg := "[db] set priorities: %w"
+
+	if len(priorities) == 0 {
+		return nil
 	}
 
-	cursor, err := c.collection.Aggregate(
-		ctx,
-		mongo.Pipeline{matchStage, unwindStage, sortStage, projectStage},
-	)
-	if err != nil {
-		return nil, fmt.Errorf(errMsg, err)
+	set := bson.D{}
+	filters := make([]interface{}, 0, len(priorities))
+	i := 0
+	for songID, priority := range priorities {
+		// array filter identifiers must be alphanumeric and start with a lowercase letter
+		identifier := fmt.Sprintf("song%d", i)
+		set = append(set, bson.E{Key: fmt.Sprintf("song_list.$[%v].priority", identifier), Value: priority})
+		filters = append(filters, bson.M{identifier + ".id": songID})
+		i++
 	}
-	defer cursor.Close(ctx)
 
-	songList := make([]*song.Model, 0)
+	filter := bson.D{{"_id", sessionID}}
+	update := bson.D{{"$set", set}}
+	opts := options.Update().SetArrayFilters(options.ArrayFilters{Filters: filters})
 
-	// define custom result struct because I'm too stupid
-	// to make the mongo aggregation pipeline do what i want
-	type result struct {
-		Song *song.Model `bson:"song_list"`
+	result, err := c.collection.UpdateOne(ctx, filter, update, opts)
+	if err != nil {
+		return fmt.Errorf(errMsg, err)
 	}
-
-	for cursor.Next(ctx) {
-		var res result
-		if err := cursor.Decode(&res); err != nil {
-			return nil, fmt.Errorf(errMsg, err)
-		}
-		songList = append(songList, res.Song)
+	if result.MatchedCount == 0 {
+		return fmt.Errorf(errMsg, ErrNoSessionWithID)
 	}
-
-	return songList, nil
+	return nil
 }
 
 func (c *songCollection) VoteUp(
@@ -384,7 +508,8 @@ func (c *songCollection) VoteDown(
 	sessionID string,
 	songID string,
 	username string,
-) (int, error) {
+	threshold session.RemovalThreshold,
+) (int, session.RemovalReason, error) {
 	errMsg := "[db] vote down: %w"
 
 	// case 1: 	user not in Upvoters && user not in Downvoters
@@ -397,6 +522,7 @@ func (c *songCollection) VoteDown(
 	//		   	-> remove user from Upvoters
 	// 			-> add user to Downvoters
 	// 			-> decrement score by 2
+	// songs pushed over the removal threshold by case 1 or 3 are removed by the same update
 
 	// case 1: filters for
 	// - _id: sessionID
@@ -421,26 +547,12 @@ func (c *songCollection) VoteDown(
 			},
 		},
 	}
-	update := bson.D{
-		{
-			"$inc",
-			bson.D{{"song_list.$[song].score", scoreChange}},
-		},
-		{
-			"$push",
-			bson.D{{"song_list.$[song].downvoters", username}},
-		},
-	}
-	opts := options.Update().SetArrayFilters(options.ArrayFilters{
-		Filters: []interface{}{bson.M{"song.id": songID}},
-	})
-	result, err := c.collection.UpdateOne(ctx, filter, update, opts)
+	reason, ok, err := c.downvote(ctx, filter, songID, username, scoreChange, false, threshold)
 	if err != nil {
-		return 0, fmt.Errorf(errMsg, err)
+		return 0, "", fmt.Errorf(errMsg, err)
 	}
-	// check if modified
-	if result.ModifiedCount > 0 {
-		return scoreChange, nil
+	if ok {
+		return scoreChange, reason, nil
 	}
 
 	// case 2: filters for
@@ -466,7 +578,7 @@ func (c *songCollection) VoteDown(
 			},
 		},
 	}
-	update = bson.D{
+	update := bson.D{
 		{
 			"$inc",
 			bson.D{{"song_list.$[song].score", scoreChange}},
@@ -476,16 +588,16 @@ func (c *songCollection) VoteDown(
 			bson.D{{"song_list.$[song].downvoters", username}},
 		},
 	}
-	opts = options.Update().SetArrayFilters(options.ArrayFilters{
+	opts := options.Update().SetArrayFilters(options.ArrayFilters{
 		Filters: []interface{}{bson.M{"song.id": songID}},
 	})
-	result, err = c.collection.UpdateOne(ctx, filter, update, opts)
+	result, err := c.collection.UpdateOne(ctx, filter, update, opts)
 	if err != nil {
-		return 0, fmt.Errorf(errMsg, err)
+		return 0, "", fmt.Errorf(errMsg, err)
 	}
 	// check if modified
 	if result.ModifiedCount > 0 {
-		return scoreChange, nil
+		return scoreChange, "", nil
 	}
 
 	// case 3: filters for
@@ -511,31 +623,104 @@ func (c *songCollection) VoteDown(
 			},
 		},
 	}
-	update = bson.D{
-		{
-			"$inc",
-			bson.D{{"song_list.$[song].score", scoreChange}},
-		},
-		{
-			"$pull",
-			bson.D{{"song_list.$[song].upvoters", username}},
-		},
-		{
-			"$push",
-			bson.D{{"song_list.$[song].downvoters", username}},
-		},
+	reason, ok, err = c.downvote(ctx, filter, songID, username, scoreChange, true, threshold)
+	if err != nil {
+		return 0, "", fmt.Errorf(errMsg, err)
 	}
-	opts = options.Update().SetArrayFilters(options.ArrayFilters{
-		Filters: []interface{}{bson.M{"song.id": songID}},
-	})
-	result, err = c.collection.UpdateOne(ctx, filter, update, opts)
+	if ok {
+		return scoreChange, reason, nil
+	}
+
+	return 0, "", fmt.Errorf(errMsg, ErrIllegalState)
+}
+
+// downvote adds the user to the downvoters of the song matched by filter, changes its score and
+// withdraws the user's upvote if requested. A song that the vote pushes over the removal threshold
+// of the session is removed by the same update, so no other vote or song change can get in between.
+// ok is false if filter matches no song, reason is empty if the song has not been removed.
+func (c *songCollection) downvote(
+	ctx context.Context,
+	filter bson.D,
+	songID string,
+	username string,
+	scoreChange int,
+	withdrawUpvote bool,
+	threshold session.RemovalThreshold,
+) (reason session.RemovalReason, ok bool, err error) {
+	upvoters := bson.D{{"$ifNull", bson.A{"$$song.upvoters", bson.A{}}}}
+	if withdrawUpvote {
+		upvoters = bson.D{{"$filter", bson.D{
+			{"input", upvoters},
+			{"as", "upvoter"},
+			{"cond", bson.D{{"$ne", bson.A{"$$upvoter", username}}}},
+		}}}
+	}
+	downvoters := bson.D{{"$concatArrays", bson.A{
+		bson.D{{"$ifNull", bson.A{"$$song.downvoters", bson.A{}}}},
+		bson.A{username},
+	}}}
+
+	// the song list with the vote applied
+	songList := bson.D{{"$map", bson.D{
+		{"input", "$song_list"},
+		{"as", "song"},
+		{"in", bson.D{{"$cond", bson.A{
+			bson.D{{"$eq", bson.A{"$$song.id", songID}}},
+			bson.D{{"$mergeObjects", bson.A{
+				"$$song",
+				bson.D{
+					{"score", bson.D{{"$add", bson.A{"$$song.score", scoreChange}}}},
+					{"upvoters", upvoters},
+					{"downvoters", downvoters},
+				},
+			}}},
+			"$$song",
+		}}}},
+	}}}
+
+	// without the song if it is over the threshold now
+	if threshold.Enabled() {
+		conditions := bson.A{}
+		if threshold.Score < 0 {
+			conditions = append(conditions, bson.D{{"$lte", bson.A{"$$song.score", threshold.Score}}})
+		}
+		if threshold.Downvoters > 0 {
+			conditions = append(conditions, bson.D{{"$gte", bson.A{bson.D{{"$size", "$$song.downvoters"}}, threshold.Downvoters}}})
+		}
+		songList = bson.D{{"$filter", bson.D{
+			{"input", songList},
+			{"as", "song"},
+			{"cond", bson.D{{"$not", bson.A{
+				bson.D{{"$and", bson.A{
+					bson.D{{"$eq", bson.A{"$$song.id", songID}}},
+					bson.D{{"$or", conditions}},
+				}}},
+			}}}},
+		}}}
+	}
+
+	update := mongo.Pipeline{{{"$set", bson.D{{"song_list", songList}}}}}
+	projection := bson.D{
+		{"_id", 0},
+		{"song_list", bson.D{{"$elemMatch", bson.D{{"id", songID}}}}},
+	}
+	opts := options.FindOneAndUpdate().
+		SetProjection(projection).
+		SetReturnDocument(options.Before)
+
+	var sess *session.Session
+	err = c.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&sess)
 	if err != nil {
-		return 0, fmt.Errorf(errMsg, err)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return "", false, nil
+		}
+		return "", false, err
 	}
-	// check if modified
-	if result.ModifiedCount > 0 {
-		return scoreChange, nil
+	if len(sess.SongList) == 0 {
+		return "", false, ErrIllegalState
 	}
 
-	return 0, fmt.Errorf(errMsg, ErrIllegalState)
+	// the update applied the vote to this state of the song
+	before := sess.SongList[0]
+	return threshold.Reason(before.Score+scoreChange, len(before.Downvoters)+1), true, nil
 }
@@ -0,0 +1,64 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/encore-fm/backend/config"
+	"github.com/encore-fm/backend/spotifycl"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// TrackCollection stores the metadata of spotify tracks, independent of sessions
+type TrackCollection interface {
+	GetTrack(ctx context.Context, trackID string) (*spotifycl.TrackInfo, error)
+	SetTrack(ctx context.Context, track *spotifycl.TrackInfo) error
+}
+
+type trackCollection struct {
+	client     *mongo.Client
+	collection *mongo.Collection
+}
+
+var _ TrackCollection = (*trackCollection)(nil)
+var _ spotifycl.TrackStore = (*trackCollection)(nil)
+
+func NewTrackCollection(client *mongo.Client) TrackCollection {
+	collection := client.
+		Database(config.Conf.Database.DBName).
+		Collection(config.Conf.Database.TrackCollectionName)
+	return &trackCollection{
+		client:     client,
+		collection: collection,
+	}
+}
+
+// GetTrack returns the stored track, nil if there is none
+func (c *trackCollection) GetTrack(ctx context.Context, trackID string) (*spotifycl.TrackInfo, error) {
+	errMsg := "[db] get track: %w"
+	filter := bson.D{{"_id", trackID}}
+
+	var track spotifycl.TrackInfo
+	if err := c.collection.FindOne(ctx, filter).Decode(&track); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf(errMsg, err)
+	}
+	return &track, nil
+}
+
+// SetTrack inserts or replaces a track
+func (c *trackCollection) SetTrack(ctx context.Context, track *spotifycl.TrackInfo) error {
+	errMsg := "[db] set track: %w"
+	filter := bson.D{{"_id", track.ID}}
+
+	_, err := c.collection.ReplaceOne(ctx, filter, track, options.Replace().SetUpsert(true))
+	if err != nil {
+		return fmt.Errorf(errMsg, err)
+	}
+	return nil
+}
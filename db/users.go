@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/encore-fm/backend/config"
 	"github.com/encore-fm/backend/user"
@@ -11,13 +12,20 @@ import (
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"golang.org/x/oauth2"
+
+	log "github.com/sirupsen/logrus"
 )
 
 type UserCollection interface {
 	GetUserByID(ctx context.Context, userID string) (*user.Model, error)
 	GetUserByState(ctx context.Context, state string) (*user.Model, error)
 	GetAdminBySessionID(ctx context.Context, sessionID string) (*user.Model, error)
+	GetSuccessor(ctx context.Context, sessionID string, ownerID string) (*user.Model, error)
+	SetRole(ctx context.Context, userID string, role user.Role) error
+	TransferOwnership(ctx context.Context, ownerID string, targetID string) error
 	AddUser(ctx context.Context, newUser *user.Model) error
+	AdmitWaitingUsers(ctx context.Context, sessionID string) ([]*user.Model, error)
+	ListWaitingSessionIDs(ctx context.Context) ([]string, error)
 	DeleteUser(ctx context.Context, userID string) error
 	DeleteUsersBySessionID(ctx context.Context, sessionID string) error
 	DeleteUsersBySessionIDs(ctx context.Context, sessionIDs []string) error
@@ -26,16 +34,21 @@ type UserCollection interface {
 	SetToken(ctx context.Context, userID string, token *oauth2.Token) error
 	SetSynchronized(ctx context.Context, userID string, synchronized bool) error
 	SetAutoSync(ctx context.Context, userID string, autoSync bool) error
+	SetLastSuggestion(ctx context.Context, userID string, lastSuggestion time.Time) error
+	ClaimSuggestion(ctx context.Context, userID string, now time.Time, minInterval time.Duration) (*user.Model, error)
 	GetSpotifyClient(ctx context.Context, userID string) (*user.SpotifyClient, error)
 	GetSyncedSpotifyClients(ctx context.Context, sessionID string) ([]*user.SpotifyClient, error)
 	AddSSEConnection(ctx context.Context, userID string) (int, error)
 	RemoveSSEConnection(ctx context.Context, userID string) (int, error)
+	CountSSEConnections(ctx context.Context, sessionID string) (int, error)
 	ResetSSEConnections(ctx context.Context) error
 }
 
 type userCollection struct {
 	client     *mongo.Client
 	collection *mongo.Collection
+	// session collection, used to keep track of the number of users in a session
+	sessions *mongo.Collection
 }
 
 var _ UserCollection = (*userCollection)(nil)
@@ -44,9 +57,13 @@ func NewUserCollection(client *mongo.Client) UserCollection {
 	collection := client.
 		Database(config.Conf.Database.DBName).
 		Collection(config.Conf.Database.UserCollectionName)
+	sessions := client.
+		Database(config.Conf.Database.DBName).
+		Collection(config.Conf.Database.SessionCollectionName)
 	return &userCollection{
 		client:     client,
 		collection: collection,
+		sessions:   sessions,
 	}
 }
 
@@ -92,6 +109,7 @@ func (c *userCollection) GetUserByState(ctx context.Context, state string) (*use
 	return res, nil
 }
 
+// GetAdminBySessionID returns the owner of the session
 func (c *userCollection) GetAdminBySessionID(ctx context.Context, sessionID string) (*user.Model, error) {
 	errMsg := "[db] get admin by sessionID: %w"
 	filter := bson.D{
@@ -109,9 +127,239 @@ func (c *userCollection) GetAdminBySessionID(ctx context.Context, sessionID stri
 	return res, nil
 }
 
+// GetSuccessor returns the user that should take over the session if the owner leaves
+// moderators are preferred, ties are broken by score
+// Errors:
+// - ErrNoUserWithID if the owner is the only admitted user
+func (c *userCollection) GetSuccessor(ctx context.Context, sessionID string, ownerID string) (*user.Model, error) {
+	errMsg := "[db] get successor: %w"
+	filter := bson.M{
+		"session_id": sessionID,
+		"_id":        bson.M{"$ne": ownerID},
+		"waiting":    bson.M{"$ne": true},
+	}
+
+	// prefer moderators
+	moderatorFilter := bson.M{"role": user.RoleModerator}
+	for k, v := range filter {
+		moderatorFilter[k] = v
+	}
+	opt := options.FindOne().SetSort(bson.M{"score": -1})
+
+	for _, f := range []bson.M{moderatorFilter, filter} {
+		var successor *user.Model
+		err := c.collection.FindOne(ctx, f, opt).Decode(&successor)
+		if err == nil {
+			return successor, nil
+		}
+		if !errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, fmt.Errorf(errMsg, err)
+		}
+	}
+	return nil, fmt.Errorf(errMsg, ErrNoUserWithID)
+}
+
+// SetRole changes the role of a user that is not the owner
+// the owner role can only be handed on with TransferOwnership
+// Errors:
+// - ErrNoUserWithID if there is no admitted user with this id that is not the owner
+func (c *userCollection) SetRole(ctx context.Context, userID string, role user.Role) error {
+	errMsg := "[db] set role: %w"
+	if role == user.RoleOwner {
+		return fmt.Errorf(errMsg, ErrIllegalState)
+	}
+
+	filter := bson.M{
+		"_id":      userID,
+		"is_admin": false,
+		"waiting":  bson.M{"$ne": true},
+	}
+	update := bson.M{
+		"$set": bson.M{"role": role},
+	}
+
+	res, err := c.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf(errMsg, err)
+	}
+	if res.MatchedCount == 0 {
+		return fmt.Errorf(errMsg, ErrNoUserWithID)
+	}
+	return nil
+}
+
+// TransferOwnership makes the target the owner of the session, the previous owner becomes a moderator
+// Errors:
+// - ErrNoUserWithID if the target is not an admitted user of the owner's session
+// - ErrIllegalState if the owner is not the owner anymore, e.g. because of a concurrent transfer
+func (c *userCollection) TransferOwnership(ctx context.Context, ownerID string, targetID string) error {
+	errMsg := "[db] transfer ownership: %w"
+	if ownerID == targetID {
+		return fmt.Errorf(errMsg, ErrIllegalState)
+	}
+
+	owner, err := c.GetUserByID(ctx, ownerID)
+	if err != nil {
+		return fmt.Errorf(errMsg, err)
+	}
+	if !owner.IsAdmin {
+		return fmt.Errorf(errMsg, ErrIllegalState)
+	}
+
+	// promote the target first, so the session is never left without an owner
+	filter := bson.M{
+		"_id":        targetID,
+		"session_id": owner.SessionID,
+		"is_admin":   false,
+		"waiting":    bson.M{"$ne": true},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"is_admin": true,
+			"role":     user.RoleOwner,
+		},
+	}
+	before := options.Before
+	opt := options.FindOneAndUpdateOptions{
+		Projection:     bson.D{{"role", 1}},
+		ReturnDocument: &before,
+	}
+	var target *user.Model
+	err = c.collection.FindOneAndUpdate(ctx, filter, update, &opt).Decode(&target)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return fmt.Errorf(errMsg, ErrNoUserWithID)
+		}
+		return fmt.Errorf(errMsg, err)
+	}
+
+	// the demotion only succeeds for one of several concurrent transfers,
+	// the others hand the owner role back so the session keeps a single owner
+	filter = bson.M{"_id": ownerID, "is_admin": true}
+	update = bson.M{
+		"$set": bson.M{
+			"is_admin": false,
+			"role":     user.RoleModerator,
+		},
+	}
+	res, err := c.collection.UpdateOne(ctx, filter, update)
+	if err == nil && res.MatchedCount == 0 {
+		err = ErrIllegalState
+	}
+	if err != nil {
+		c.revokeOwnership(ctx, targetID, target.GetRole())
+		return fmt.Errorf(errMsg, err)
+	}
+	return nil
+}
+
+// revokeOwnership gives a user promoted by a failed transfer its previous role back
+func (c *userCollection) revokeOwnership(ctx context.Context, userID string, role user.Role) {
+	filter := bson.M{"_id": userID}
+	update := bson.M{
+		"$set": bson.M{
+			"is_admin": false,
+			"role":     role,
+		},
+	}
+	if _, err := c.collection.UpdateOne(ctx, filter, update); err != nil {
+		log.Errorf("[db] revoke ownership: %v", err)
+	}
+}
+
+// userLimitExpr returns an aggregation expression that evaluates to true
+// if a session document still has room for another user.
+// The session's own limit is capped by the global limit, a limit <= 0 means no limit.
+func userLimitExpr(globalMax int) bson.M {
+	hasSessionLimit := bson.M{"$gt": bson.A{"$max_users", 0}}
+	if globalMax <= 0 {
+		return bson.M{
+			"$or": bson.A{
+				bson.M{"$not": bson.A{hasSessionLimit}},
+				bson.M{"$lt": bson.A{"$user_count", "$max_users"}},
+			},
+		}
+	}
+	return bson.M{
+		"$lt": bson.A{
+			"$user_count",
+			bson.M{
+				"$cond": bson.A{
+					hasSessionLimit,
+					bson.M{"$min": bson.A{"$max_users", globalMax}},
+					globalMax,
+				},
+			},
+		},
+	}
+}
+
+// claimSlot atomically increments the session's user count if the session is not full yet
+// Errors:
+// - ErrNoSessionWithID
+// - ErrSessionFull
+func (c *userCollection) claimSlot(ctx context.Context, sessionID string) error {
+	filter := bson.M{
+		"_id":   sessionID,
+		"$expr": userLimitExpr(config.Conf.MaxUsers),
+	}
+	update := bson.M{
+		"$inc": bson.M{"user_count": 1},
+	}
+	res, err := c.sessions.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount > 0 {
+		return nil
+	}
+
+	// find out whether the session is full or does not exist
+	count, err := c.sessions.CountDocuments(ctx, bson.M{"_id": sessionID})
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrNoSessionWithID
+	}
+	return ErrSessionFull
+}
+
+// releaseSlot decrements the session's user count
+func (c *userCollection) releaseSlot(ctx context.Context, sessionID string) error {
+	filter := bson.M{
+		"_id":        sessionID,
+		"user_count": bson.M{"$gt": 0},
+	}
+	update := bson.M{
+		"$inc": bson.M{"user_count": -1},
+	}
+	_, err := c.sessions.UpdateOne(ctx, filter, update)
+	return err
+}
+
+// AddUser inserts a new user into the user collection
+// users that are not waiting occupy one of the session's user slots
+// Errors:
+// - ErrSessionFull if the session has reached its user limit
+// - ErrNoSessionWithID
+// - ErrUsernameTaken
 func (c *userCollection) AddUser(ctx context.Context, newUser *user.Model) error {
 	errMsg := "[db] add user: %w"
+
+	if !newUser.Waiting {
+		if err := c.claimSlot(ctx, newUser.SessionID); err != nil {
+			return fmt.Errorf(errMsg, err)
+		}
+	}
+
 	if _, err := c.collection.InsertOne(ctx, newUser); err != nil {
+		// give back the slot claimed for this user
+		if !newUser.Waiting {
+			if releaseErr := c.releaseSlot(ctx, newUser.SessionID); releaseErr != nil {
+				log.Errorf(errMsg, releaseErr)
+			}
+		}
 		if _, ok := err.(mongo.WriteException); ok {
 			return fmt.Errorf(errMsg, ErrUsernameTaken)
 		}
@@ -120,18 +368,107 @@ func (c *userCollection) AddUser(ctx context.Context, newUser *user.Model) error
 	return nil
 }
 
+// admitWaitingUser admits the user that has been waiting the longest if the session has a free slot
+// Errors:
+// - ErrSessionFull if there is no free slot
+// - ErrNoWaitingUser if nobody is waiting
+func (c *userCollection) admitWaitingUser(ctx context.Context, sessionID string) (*user.Model, error) {
+	if err := c.claimSlot(ctx, sessionID); err != nil {
+		return nil, err
+	}
+
+	filter := bson.M{
+		"session_id": sessionID,
+		"waiting":    true,
+	}
+	update := bson.M{
+		"$set": bson.M{"waiting": false},
+	}
+	after := options.After
+	opt := options.FindOneAndUpdateOptions{
+		Sort:           bson.M{"waiting_since": 1},
+		ReturnDocument: &after,
+	}
+
+	var admitted *user.Model
+	err := c.collection.FindOneAndUpdate(ctx, filter, update, &opt).Decode(&admitted)
+	if err != nil {
+		// nobody took the slot
+		if releaseErr := c.releaseSlot(ctx, sessionID); releaseErr != nil {
+			log.Errorf("[db] admit waiting user: %v", releaseErr)
+		}
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrNoWaitingUser
+		}
+		return nil, err
+	}
+	return admitted, nil
+}
+
+// AdmitWaitingUsers admits waiting users in the order they joined until the session is full
+// returns the admitted users
+func (c *userCollection) AdmitWaitingUsers(ctx context.Context, sessionID string) ([]*user.Model, error) {
+	errMsg := "[db] admit waiting users: %w"
+
+	admitted := make([]*user.Model, 0)
+	for {
+		usr, err := c.admitWaitingUser(ctx, sessionID)
+		if errors.Is(err, ErrSessionFull) || errors.Is(err, ErrNoWaitingUser) {
+			return admitted, nil
+		}
+		if err != nil {
+			return admitted, fmt.Errorf(errMsg, err)
+		}
+		admitted = append(admitted, usr)
+	}
+}
+
+// ListWaitingSessionIDs returns the ids of all sessions that have users on their waiting list
+func (c *userCollection) ListWaitingSessionIDs(ctx context.Context) ([]string, error) {
+	errMsg := "[db] list waiting session ids: %w"
+
+	res, err := c.collection.Distinct(ctx, "session_id", bson.M{"waiting": true})
+	if err != nil {
+		return nil, fmt.Errorf(errMsg, err)
+	}
+
+	sessionIDs := make([]string, 0, len(res))
+	for _, id := range res {
+		if sessionID, ok := id.(string); ok {
+			sessionIDs = append(sessionIDs, sessionID)
+		}
+	}
+	return sessionIDs, nil
+}
+
+// DeleteUser deletes a user and frees the slot the user occupied in the session
 func (c *userCollection) DeleteUser(ctx context.Context, userID string) error {
 	errMsg := "[db] delete user: %w"
 
 	filter := bson.M{"_id": userID}
-	res, err := c.collection.DeleteOne(ctx, filter)
+	projection := bson.M{
+		"session_id": 1,
+		"waiting":    1,
+	}
+	opt := options.FindOneAndDelete().SetProjection(projection)
+
+	var deleted *struct {
+		SessionID string `bson:"session_id"`
+		Waiting   bool   `bson:"waiting"`
+	}
+	err := c.collection.FindOneAndDelete(ctx, filter, opt).Decode(&deleted)
 	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return fmt.Errorf(errMsg, ErrNoUserWithID)
+		}
 		return fmt.Errorf(errMsg, err)
 	}
-	if res.DeletedCount == 0 {
-		return fmt.Errorf(errMsg, ErrNoUserWithID)
-	}
 
+	if !deleted.Waiting {
+		if err := c.releaseSlot(ctx, deleted.SessionID); err != nil {
+			return fmt.Errorf(errMsg, err)
+		}
+	}
 	return nil
 }
 
@@ -283,6 +620,65 @@ func (c *userCollection) SetAutoSync(ctx context.Context, userID string, autoSyn
 	return nil
 }
 
+// SetLastSuggestion stores the time of the latest suggestion of a user
+func (c *userCollection) SetLastSuggestion(ctx context.Context, userID string, lastSuggestion time.Time) error {
+	errMsg := "[db] set last suggestion: %w"
+	filter := bson.M{"_id": userID}
+	update := bson.M{
+		"$set": bson.M{"last_suggestion": lastSuggestion},
+	}
+
+	res, err := c.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf(errMsg, err)
+	}
+	if res.MatchedCount == 0 {
+		return fmt.Errorf(errMsg, ErrNoUserWithID)
+	}
+
+	return nil
+}
+
+// ClaimSuggestion sets the time of the latest suggestion of a user to now if the previous one is at least
+// minInterval ago. Checking and setting it in one update keeps concurrent suggestions from all passing the cooldown.
+// The user is returned as it was before the claim.
+// Errors:
+// - ErrNoUserWithID
+// - ErrSuggestionCooldown if the previous suggestion is too recent, the user is returned as well
+func (c *userCollection) ClaimSuggestion(
+	ctx context.Context,
+	userID string,
+	now time.Time,
+	minInterval time.Duration,
+) (*user.Model, error) {
+	errMsg := "[db] claim suggestion: %w"
+	// also matches users that never suggested a song
+	filter := bson.M{
+		"_id":             userID,
+		"last_suggestion": bson.M{"$not": bson.M{"$gt": now.Add(-minInterval)}},
+	}
+	update := bson.M{
+		"$set": bson.M{"last_suggestion": now},
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.Before)
+
+	var usr *user.Model
+	err := c.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&usr)
+	if err == nil {
+		return usr, nil
+	}
+	if !errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, fmt.Errorf(errMsg, err)
+	}
+
+	// tell a missing user from one that is still cooling down
+	usr, err = c.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf(errMsg, err)
+	}
+	return usr, fmt.Errorf(errMsg, ErrSuggestionCooldown)
+}
+
 // gets an authorized user's Spotify client
 func (c *userCollection) GetSpotifyClient(ctx context.Context, userID string) (*user.SpotifyClient, error) {
 	errMsg := "[db] get spotify client: %w"
@@ -386,6 +782,35 @@ func (c *userCollection) RemoveSSEConnection(ctx context.Context, userID string)
 	return c.incrementSSEConnections(ctx, userID, -1)
 }
 
+// returns the number of active sse connections of all users in a session
+func (c *userCollection) CountSSEConnections(ctx context.Context, sessionID string) (int, error) {
+	errMsg := "[db] count sse connections: %w"
+
+	pipeline := mongo.Pipeline{
+		{{"$match", bson.D{{"session_id", sessionID}}}},
+		{{"$group", bson.D{
+			{"_id", nil},
+			{"active_sse_connections", bson.D{{"$sum", "$active_sse_connections"}}},
+		}}},
+	}
+	cursor, err := c.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return 0, fmt.Errorf(errMsg, err)
+	}
+
+	var res []struct {
+		ActiveSSEConnections int `bson:"active_sse_connections"`
+	}
+	if err := cursor.All(ctx, &res); err != nil {
+		return 0, fmt.Errorf(errMsg, err)
+	}
+	// sessions without users have no group
+	if len(res) == 0 {
+		return 0, nil
+	}
+	return res[0].ActiveSSEConnections, nil
+}
+
 // resets the number of active sse connections of all users to 0. Required in case the server crashes while there
 // are still active SSE Connections.
 func (c *userCollection) ResetSSEConnections(ctx context.Context) error {
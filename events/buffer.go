@@ -0,0 +1,64 @@
+package events
+
+import "time"
+
+// BufferSize is the number of events kept per group, so clients can catch up after reconnecting
+const BufferSize = 256
+
+// idEpochShift leaves room for about a million events per second between the start of a group and a restart.
+// Ids stay below 2^53 for centuries, so javascript clients read them without losing precision.
+const idEpochShift = 20
+
+// InitialID returns the id preceding the first id of a sequence started at t.
+// Ids continue to grow across server restarts, so ids from before a restart are never mistaken for new ones.
+func InitialID(t time.Time) uint64 {
+	return uint64(t.Unix()) << idEpochShift
+}
+
+// ringBuffer numbers the events of a group and keeps the most recent ones
+type ringBuffer struct {
+	events []Event
+	// index of the oldest event
+	start int
+	count int
+	// id of the last event added
+	lastID uint64
+}
+
+// newRingBuffer creates a buffer whose first event gets the id offset+1
+func newRingBuffer(size int, offset uint64) *ringBuffer {
+	return &ringBuffer{events: make([]Event, size), lastID: offset}
+}
+
+// add assigns the next id to the event and stores it, overwriting the oldest event if the buffer is full
+func (b *ringBuffer) add(ev Event) Event {
+	b.lastID++
+	ev.ID = b.lastID
+
+	end := (b.start + b.count) % len(b.events)
+	b.events[end] = ev
+	if b.count < len(b.events) {
+		b.count++
+	} else {
+		b.start = (b.start + 1) % len(b.events)
+	}
+	return ev
+}
+
+// since returns the events with an id greater than lastID, oldest first.
+// ok is false if some of these events have been overwritten already or lastID has never been assigned.
+func (b *ringBuffer) since(lastID uint64) (events []Event, ok bool) {
+	if lastID > b.lastID {
+		return nil, false
+	}
+	missed := int(b.lastID - lastID)
+	if missed > b.count {
+		return nil, false
+	}
+
+	events = make([]Event, 0, missed)
+	for i := b.count - missed; i < b.count; i++ {
+		events = append(events, b.events[(b.start+i)%len(b.events)])
+	}
+	return events, true
+}
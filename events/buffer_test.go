@@ -0,0 +1,50 @@
+package events
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func ids(events []Event) []uint64 {
+	result := make([]uint64, 0, len(events))
+	for _, ev := range events {
+		result = append(result, ev.ID)
+	}
+	return result
+}
+
+func TestRingBuffer(t *testing.T) {
+	b := newRingBuffer(3, 10)
+	for i := 0; i < 2; i++ {
+		b.add(Event{Type: "event"})
+	}
+	assert.Equal(t, uint64(12), b.lastID)
+
+	events, ok := b.since(10)
+	assert.True(t, ok)
+	assert.Equal(t, []uint64{11, 12}, ids(events))
+
+	events, ok = b.since(12)
+	assert.True(t, ok)
+	assert.Empty(t, events)
+
+	// ids that have not been assigned yet
+	_, ok = b.since(13)
+	assert.False(t, ok)
+}
+
+func TestRingBuffer_Overrun(t *testing.T) {
+	b := newRingBuffer(3, 0)
+	for i := 0; i < 5; i++ {
+		b.add(Event{Type: "event"})
+	}
+
+	events, ok := b.since(2)
+	assert.True(t, ok)
+	assert.Equal(t, []uint64{3, 4, 5}, ids(events))
+
+	// event 2 has been overwritten
+	_, ok = b.since(1)
+	assert.False(t, ok)
+}
@@ -1,6 +1,7 @@
 package events
 
 import (
+	"strings"
 	"sync"
 	"time"
 
@@ -15,18 +16,81 @@ type EventType string
 type GroupID string
 type EventPayload interface{}
 
+// groupSeparator separates the levels of hierarchical group ids like "session/user"
+const groupSeparator = "/"
+
+// UserGroupID returns the group of a single user of a session. Events of a user group are only
+// delivered to subscribers of that group, but they are numbered and buffered with the session's events.
+func UserGroupID(sessionID, username string) GroupID {
+	return GroupID(sessionID + groupSeparator + username)
+}
+
+// Root returns the top level group of a hierarchical group id, e.g. the session of a user group
+func (g GroupID) Root() GroupID {
+	if i := strings.Index(string(g), groupSeparator); i >= 0 {
+		return g[:i]
+	}
+	return g
+}
+
 type Event struct {
+	// sequence number of the event within its group, assigned when an event of a recorded type is forwarded
+	ID      uint64
 	Type    EventType
 	GroupID GroupID
 	Data    EventPayload
 }
 
+// number of events waiting for delivery per subscription
+const deliveryQueueSize = 64
+
+// time a subscriber has to take an event before it is skipped
+const deliveryTimeout = 300 * time.Millisecond
+
 // subscription contains subscription info
-// and message channel
+// and message channel. Events are delivered to the channel in the order they were published.
+// The channel is closed when the subscription ends or a numbered event could not be delivered,
+// in which case the subscriber has to subscribe again and replay the missed events.
 type subscription struct {
 	Types   []EventType
 	Groups  []GroupID
 	Channel chan Event
+
+	// events forwarded to the subscription that wait for delivery
+	queue chan Event
+	// closed to stop the delivery
+	done     chan struct{}
+	doneOnce *sync.Once
+}
+
+// stop ends the delivery of the subscription, its channel is closed once the delivery has stopped
+func (s subscription) stop() {
+	s.doneOnce.Do(func() { close(s.done) })
+}
+
+// deliver passes the queued events to the subscriber one after the other
+func (s subscription) deliver() {
+	defer close(s.Channel)
+	msg := "[eventbus] deliver Event"
+	for {
+		select {
+		case ev := <-s.queue:
+			select {
+			case s.Channel <- ev:
+			case <-time.After(deliveryTimeout):
+				if ev.ID != 0 {
+					// the subscriber would miss a numbered event without noticing
+					log.Warnf("%v: channel is blocking -> closing subscription: type={%v} id={%v}", msg, ev.Type, ev.ID)
+					return
+				}
+				log.Warnf("%v: channel is blocking -> skipping: type={%v}", msg, ev.Type)
+			case <-s.done:
+				return
+			}
+		case <-s.done:
+			return
+		}
+	}
 }
 
 type EventBus interface {
@@ -36,30 +100,40 @@ type EventBus interface {
 	Unsubscribe(subscription)
 	RemoveGroups([]GroupID)
 	Publish(EventType, GroupID, EventPayload)
+	Record(...EventType)
+	Replay(groupID GroupID, lastID uint64) ([]Event, bool)
+	LastID(groupID GroupID) uint64
 }
 
 // eventBus stores the information about subscribers
 // listening to specific Event types and group ids
 type eventBus struct {
-	subscribers      map[EventType]map[GroupID]map[chan Event]bool
+	subscribers      map[EventType]map[GroupID]map[chan Event]subscription
 	newSubscriptions chan subscription
 	unsubscriptions  chan subscription
 	cleanups         chan []GroupID
 	eventChan        chan Event
 	quit             chan struct{}
 	mapMutex         sync.RWMutex
+
+	// recently forwarded events of the recorded types of every group
+	buffers     map[GroupID]*ringBuffer
+	recorded    map[EventType]bool
+	bufferMutex sync.Mutex
 }
 
 var _ EventBus = (*eventBus)(nil)
 
 func NewEventBus() EventBus {
 	return &eventBus{
-		subscribers:      make(map[EventType]map[GroupID]map[chan Event]bool),
+		subscribers:      make(map[EventType]map[GroupID]map[chan Event]subscription),
 		newSubscriptions: make(chan subscription),
 		unsubscriptions:  make(chan subscription),
 		cleanups:         make(chan []GroupID),
 		eventChan:        make(chan Event, 20),
 		quit:             make(chan struct{}),
+		buffers:          make(map[GroupID]*ringBuffer),
+		recorded:         make(map[EventType]bool),
 	}
 }
 
@@ -74,10 +148,14 @@ func (eb *eventBus) Stop() {
 
 func (eb *eventBus) Subscribe(types []EventType, groupIDs []GroupID) subscription {
 	subscription := subscription{
-		Types:   types,
-		Groups:  groupIDs,
-		Channel: make(chan Event, 5),
+		Types:    types,
+		Groups:   groupIDs,
+		Channel:  make(chan Event, 5),
+		queue:    make(chan Event, deliveryQueueSize),
+		done:     make(chan struct{}),
+		doneOnce: &sync.Once{},
 	}
+	go subscription.deliver()
 	eb.newSubscriptions <- subscription
 	return subscription
 }
@@ -101,6 +179,64 @@ func (eb *eventBus) Publish(eventType EventType, groupID GroupID, data EventPayl
 	eb.eventChan <- ev
 }
 
+// Record numbers and buffers the events of the given types, so clients can replay them.
+// Events of other types, e.g. the internal events of the player controller, keep the id 0.
+func (eb *eventBus) Record(types ...EventType) {
+	eb.bufferMutex.Lock()
+	defer eb.bufferMutex.Unlock()
+
+	for _, eventType := range types {
+		eb.recorded[eventType] = true
+	}
+}
+
+// Replay returns the events of a group and its subgroups published after the event with lastID, oldest first.
+// ok is false if some of these events are not buffered anymore or lastID is unknown.
+func (eb *eventBus) Replay(groupID GroupID, lastID uint64) (events []Event, ok bool) {
+	eb.bufferMutex.Lock()
+	defer eb.bufferMutex.Unlock()
+
+	buffer, exists := eb.buffers[groupID.Root()]
+	if !exists {
+		return nil, false
+	}
+	return buffer.since(lastID)
+}
+
+// LastID returns the id of the last event forwarded to a group or its subgroups, 0 if there is none
+func (eb *eventBus) LastID(groupID GroupID) uint64 {
+	eb.bufferMutex.Lock()
+	defer eb.bufferMutex.Unlock()
+
+	if buffer, ok := eb.buffers[groupID.Root()]; ok {
+		return buffer.lastID
+	}
+	return 0
+}
+
+// record numbers the event and adds it to the buffer of its group if its type is recorded
+func (eb *eventBus) record(ev Event) Event {
+	if ev.GroupID == GroupIDAny {
+		return ev
+	}
+
+	eb.bufferMutex.Lock()
+	defer eb.bufferMutex.Unlock()
+
+	if !eb.recorded[ev.Type] {
+		return ev
+	}
+
+	// subgroups share the numbering of their root group
+	root := ev.GroupID.Root()
+	buffer, ok := eb.buffers[root]
+	if !ok {
+		buffer = newRingBuffer(BufferSize, InitialID(time.Now()))
+		eb.buffers[root] = buffer
+	}
+	return buffer.add(ev)
+}
+
 func (eb *eventBus) loop() {
 	for {
 		select {
@@ -115,7 +251,7 @@ func (eb *eventBus) loop() {
 			eb.subscribe(sub)
 
 		case ev := <-eb.eventChan:
-			eb.forwardEvent(ev)
+			eb.forwardEvent(eb.record(ev))
 
 		case <-eb.quit:
 			log.Info("[eventbus] stopped")
@@ -127,40 +263,43 @@ func (eb *eventBus) loop() {
 
 func (eb *eventBus) forwardEvent(ev Event) {
 	eb.mapMutex.RLock()
+	defer eb.mapMutex.RUnlock()
 
 	msg := "[eventbus] forward Event"
 	log.Infof("%v: received Event: type={%v} groupID={%v}", msg, ev.Type, ev.GroupID)
 
-	broadcastList := make(map[chan Event]bool)
+	broadcastList := make(map[chan Event]subscription)
 
 	if groups, typeExists := eb.subscribers[ev.Type]; typeExists {
-		if chans, ok := groups[ev.GroupID]; ok {
-			// add channels in this group to broadcast list
-			for ch := range chans {
-				broadcastList[ch] = true
+		if subs, ok := groups[ev.GroupID]; ok {
+			// add subscriptions in this group to broadcast list
+			for ch, sub := range subs {
+				broadcastList[ch] = sub
 			}
 		}
 
 		// send Event to subscribers that listen to all groups
-		if chans, ok := groups[GroupIDAny]; ok {
-			for ch := range chans {
-				broadcastList[ch] = true
+		if subs, ok := groups[GroupIDAny]; ok {
+			for ch, sub := range subs {
+				broadcastList[ch] = sub
 			}
 		}
 	}
 
-	//broadcast in goroutine to avoid blocking
-	go func(channels map[chan Event]bool, ev Event) {
-		defer eb.mapMutex.RUnlock()
-		for ch := range channels {
-			select {
-			case ch <- ev:
-			case <-time.After(time.Millisecond * 300):
-				log.Warnf("%v: channel is blocking -> skipping", msg)
+	// every subscription delivers its events in order, without blocking the other subscriptions
+	for _, sub := range broadcastList {
+		select {
+		case sub.queue <- ev:
+		default:
+			if ev.ID != 0 {
+				log.Warnf("%v: queue is full -> closing subscription: type={%v} id={%v}", msg, ev.Type, ev.ID)
+				sub.stop()
+			} else {
+				log.Warnf("%v: queue is full -> skipping: type={%v}", msg, ev.Type)
 			}
 		}
-		log.Infof("%v: type={%v} groupID={%v} to %v clients", msg, ev.Type, ev.GroupID, len(channels))
-	}(broadcastList, ev)
+	}
+	log.Infof("%v: type={%v} groupID={%v} to %v clients", msg, ev.Type, ev.GroupID, len(broadcastList))
 }
 
 func (eb *eventBus) subscribe(sub subscription) {
@@ -170,18 +309,18 @@ func (eb *eventBus) subscribe(sub subscription) {
 	for _, evType := range sub.Types {
 		groups, ok := eb.subscribers[evType]
 		if !ok {
-			groups = make(map[GroupID]map[chan Event]bool)
+			groups = make(map[GroupID]map[chan Event]subscription)
 			eb.subscribers[evType] = groups
 		}
 
 		for _, id := range sub.Groups {
-			chans, ok := groups[id]
+			subs, ok := groups[id]
 			if !ok {
-				chans = make(map[chan Event]bool)
-				groups[id] = chans
+				subs = make(map[chan Event]subscription)
+				groups[id] = subs
 			}
 
-			chans[sub.Channel] = true
+			subs[sub.Channel] = sub
 		}
 	}
 
@@ -210,7 +349,8 @@ func (eb *eventBus) unsubscribe(sub subscription) {
 		}
 	}
 
-	close(sub.Channel)
+	// the delivery closes the channel
+	sub.stop()
 	log.Infof("%v: type=%v groups=%v", msg, sub.Types, sub.Groups)
 }
 
@@ -220,9 +360,24 @@ func (eb *eventBus) removeGroups(groups []GroupID) {
 
 	msg := "[eventbus] removeGroups"
 
+	removed := make(map[GroupID]bool, len(groups))
+	for _, id := range groups {
+		removed[id] = true
+	}
+
+	isRemoved := func(id GroupID) bool {
+		// subgroups are removed with their root group
+		return removed[id] || removed[id.Root()]
+	}
+	ended := make(map[chan Event]subscription)
 	for eventType, groupToChan := range eb.subscribers {
-		for _, id := range groups {
-			delete(groupToChan, id)
+		for id, subs := range groupToChan {
+			if isRemoved(id) {
+				for ch, sub := range subs {
+					ended[ch] = sub
+				}
+				delete(groupToChan, id)
+			}
 		}
 
 		// if this eventType does not have subscribers anymore
@@ -232,5 +387,22 @@ func (eb *eventBus) removeGroups(groups []GroupID) {
 		}
 	}
 
+	eb.bufferMutex.Lock()
+	for _, id := range groups {
+		delete(eb.buffers, id)
+	}
+	eb.bufferMutex.Unlock()
+
+	// subscriptions that only followed removed groups end, e.g. the event streams of a deleted session
+	for _, sub := range ended {
+		remaining := false
+		for _, id := range sub.Groups {
+			remaining = remaining || !isRemoved(id)
+		}
+		if !remaining {
+			sub.stop()
+		}
+	}
+
 	log.Infof("%v: groups=%v", msg, groups)
 }
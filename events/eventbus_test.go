@@ -126,3 +126,156 @@ func TestEventBus_Publish(t *testing.T) {
 
 	assert.Equal(t, 2, len(events1))
 }
+
+func TestEventBus_Replay(t *testing.T) {
+	bus := NewEventBus()
+	bus.Record("event1")
+	bus.Start()
+	defer bus.Stop()
+
+	sub := bus.Subscribe([]EventType{"event1"}, []GroupID{"group1"})
+	bus.Publish("event1", "group1", "first")
+	bus.Publish("event1", "group1", "second")
+	first, second := <-sub.Channel, <-sub.Channel
+	assert.Equal(t, "first", first.Data)
+	assert.Equal(t, first.ID+1, second.ID)
+	assert.Equal(t, second.ID, bus.LastID("group1"))
+
+	events, ok := bus.Replay("group1", first.ID)
+	assert.True(t, ok)
+	assert.Len(t, events, 1)
+	assert.Equal(t, "second", events[0].Data)
+
+	_, ok = bus.Replay("group2", first.ID)
+	assert.False(t, ok)
+
+	// ids of removed groups are not replayed
+	bus.RemoveGroups([]GroupID{"group1"})
+	<-time.After(time.Millisecond * 100)
+	_, ok = bus.Replay("group1", first.ID)
+	assert.False(t, ok)
+}
+
+func TestEventBus_UserGroup(t *testing.T) {
+	bus := NewEventBus()
+	bus.Record("event1")
+	bus.Start()
+	defer bus.Stop()
+
+	userGroup := UserGroupID("session", "user")
+	assert.Equal(t, GroupID("session"), userGroup.Root())
+	assert.Equal(t, GroupID("session"), GroupID("session").Root())
+
+	sessionSub := bus.Subscribe([]EventType{"event1"}, []GroupID{"session"})
+	userSub := bus.Subscribe([]EventType{"event1"}, []GroupID{"session", userGroup})
+	otherSub := bus.Subscribe([]EventType{"event1"}, []GroupID{"session", UserGroupID("session", "other")})
+	mixedSub := bus.Subscribe([]EventType{"event1"}, []GroupID{"session", "other"})
+
+	bus.Publish("event1", userGroup, "private")
+	received := <-userSub.Channel
+	assert.Equal(t, "private", received.Data)
+
+	// user events are numbered with the events of the session
+	bus.Publish("event1", "session", "public")
+	for _, sub := range []subscription{sessionSub, userSub, otherSub, mixedSub} {
+		ev := <-sub.Channel
+		assert.Equal(t, "public", ev.Data)
+		assert.Equal(t, received.ID+1, ev.ID)
+	}
+
+	events, ok := bus.Replay("session", received.ID-1)
+	assert.True(t, ok)
+	assert.Len(t, events, 2)
+	assert.Equal(t, userGroup, events[0].GroupID)
+
+	// user groups are removed with their session
+	bus.RemoveGroups([]GroupID{"session"})
+	<-time.After(time.Millisecond * 100)
+	bus.Publish("event1", userGroup, "removed")
+	select {
+	case ev, ok := <-userSub.Channel:
+		// the subscriptions of the removed session end
+		assert.False(t, ok, "event of a removed group has been delivered: %v", ev.Data)
+	case <-time.After(time.Millisecond * 100):
+		t.Fatal("subscription of a removed group has not been closed")
+	}
+
+	// subscriptions that also follow other groups stay open
+	bus.Publish("event1", "other", "remaining")
+	assert.Equal(t, "remaining", (<-mixedSub.Channel).Data)
+}
+
+func TestEventBus_Record(t *testing.T) {
+	bus := NewEventBus()
+	bus.Record("event1")
+	bus.Start()
+	defer bus.Stop()
+
+	sub := bus.Subscribe([]EventType{"event1", "internal"}, []GroupID{"group1"})
+	bus.Publish("internal", "group1", "internal")
+	internal := <-sub.Channel
+	assert.Equal(t, uint64(0), internal.ID)
+	assert.Equal(t, uint64(0), bus.LastID("group1"))
+
+	bus.Publish("event1", "group1", "recorded")
+	recorded := <-sub.Channel
+	assert.Equal(t, recorded.ID, bus.LastID("group1"))
+	// ids are read by javascript clients without losing precision
+	assert.Less(t, recorded.ID, uint64(1)<<53)
+
+	events, ok := bus.Replay("group1", recorded.ID-1)
+	assert.True(t, ok)
+	assert.Len(t, events, 1)
+}
+
+// test that a subscriber receives the events in the order they were published
+func TestEventBus_Order(t *testing.T) {
+	bus := NewEventBus()
+	bus.Record("event1")
+	bus.Start()
+	defer bus.Stop()
+
+	sub := bus.Subscribe([]EventType{"event1"}, []GroupID{"group1"})
+	for i := 0; i < 50; i++ {
+		bus.Publish("event1", "group1", i)
+	}
+	for i := 0; i < 50; i++ {
+		ev := <-sub.Channel
+		assert.Equal(t, i, ev.Data)
+	}
+}
+
+// test that a subscription that misses a numbered event is closed, so the subscriber can replay it,
+// while unnumbered events are skipped
+func TestEventBus_Blocking(t *testing.T) {
+	bus := NewEventBus()
+	bus.Record("event1")
+	bus.Start()
+	defer bus.Stop()
+
+	internal := bus.Subscribe([]EventType{"internal"}, []GroupID{"group1"})
+	recorded := bus.Subscribe([]EventType{"event1"}, []GroupID{"group1"})
+	for i := 0; i < 10; i++ {
+		bus.Publish("internal", "group1", i)
+		bus.Publish("event1", "group1", i)
+	}
+	<-time.After(deliveryTimeout * 2)
+
+	received := 0
+	for range recorded.Channel {
+		received++
+	}
+	assert.Less(t, received, 10)
+
+	// the channel of the internal subscription stays open and receives later events
+	for len(internal.Channel) > 0 {
+		<-internal.Channel
+	}
+	bus.Publish("internal", "group1", "later")
+	for ev := range internal.Channel {
+		if ev.Data == "later" {
+			break
+		}
+	}
+	bus.Unsubscribe(internal)
+}
@@ -7,6 +7,8 @@ import (
 	"github.com/encore-fm/backend/config"
 	"github.com/encore-fm/backend/db"
 	"github.com/encore-fm/backend/events"
+	"github.com/encore-fm/backend/playerctrl"
+	"github.com/encore-fm/backend/sse"
 	"github.com/sirupsen/logrus"
 )
 
@@ -71,6 +73,11 @@ func (gc garbageCollector) Stop() {
 }
 
 func (gc garbageCollector) clean() {
+	gc.removeExpiredSessions()
+	gc.admitWaitingUsers()
+}
+
+func (gc garbageCollector) removeExpiredSessions() {
 	msg := "[garbagecoll] clean"
 	ctx := context.Background()
 
@@ -94,7 +101,41 @@ func (gc garbageCollector) clean() {
 		return
 	}
 
-	gc.eventBus.RemoveGroups(events.AsGroupIDs(expiredSessions))
+	// the player controller cleans up the events of the sessions
+	for _, sessionID := range expiredSessions {
+		gc.eventBus.Publish(playerctrl.SessionDeletedEvent, events.GroupID(sessionID), playerctrl.SessionDeletedPayload{})
+	}
 
 	logrus.Infof("deleted %v session(s)", len(expiredSessions))
 }
+
+// admits waiting users into sessions whose users have been removed without the slot being handed on
+func (gc garbageCollector) admitWaitingUsers() {
+	msg := "[garbagecoll] admit waiting users"
+	ctx := context.Background()
+
+	sessionIDs, err := gc.userCollection.ListWaitingSessionIDs(ctx)
+	if err != nil {
+		logrus.Warnf("%v, %v", msg, err)
+		return
+	}
+
+	for _, sessionID := range sessionIDs {
+		admitted, err := gc.userCollection.AdmitWaitingUsers(ctx, sessionID)
+		if err != nil {
+			logrus.Warnf("%v, %v", msg, err)
+		}
+		if len(admitted) == 0 {
+			continue
+		}
+
+		userList, err := gc.userCollection.ListUsers(ctx, sessionID)
+		if err != nil {
+			logrus.Warnf("%v, %v", msg, err)
+			continue
+		}
+		gc.eventBus.Publish(sse.UserListChange, events.GroupID(sessionID), userList)
+
+		logrus.Infof("admitted %v waiting user(s) into session [%v]", len(admitted), sessionID)
+	}
+}
@@ -2,11 +2,17 @@ package handlers
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"net/http"
+	"strconv"
+	"time"
 
+	"github.com/encore-fm/backend/config"
 	"github.com/encore-fm/backend/db"
 	"github.com/encore-fm/backend/events"
+	"github.com/encore-fm/backend/playerctrl"
+	"github.com/encore-fm/backend/queue"
 	"github.com/encore-fm/backend/session"
 	"github.com/encore-fm/backend/sse"
 	"github.com/encore-fm/backend/user"
@@ -18,6 +24,17 @@ type AdminHandler interface {
 	CreateSession(w http.ResponseWriter, r *http.Request)
 	DeleteSession(w http.ResponseWriter, r *http.Request)
 	RemoveSong(w http.ResponseWriter, r *http.Request)
+	PinSong(w http.ResponseWriter, r *http.Request)
+	UnpinSong(w http.ResponseWriter, r *http.Request)
+	MoveSong(w http.ResponseWriter, r *http.Request)
+	GetSettings(w http.ResponseWriter, r *http.Request)
+	UpdateSettings(w http.ResponseWriter, r *http.Request)
+	SetPassword(w http.ResponseWriter, r *http.Request)
+	CreateInvite(w http.ResponseWriter, r *http.Request)
+	KickUser(w http.ResponseWriter, r *http.Request)
+	Promote(w http.ResponseWriter, r *http.Request)
+	Demote(w http.ResponseWriter, r *http.Request)
+	TransferOwnership(w http.ResponseWriter, r *http.Request)
 }
 
 var _ AdminHandler = (*handler)(nil)
@@ -36,6 +53,25 @@ func (h *handler) CreateSession(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// optional capacity settings
+	query := r.URL.Query()
+	if maxUsers := query.Get("max_users"); maxUsers != "" {
+		limit, err := strconv.Atoi(maxUsers)
+		if err != nil || limit < 0 || (config.Conf.MaxUsers > 0 && limit > config.Conf.MaxUsers) {
+			handleError(w, http.StatusBadRequest, log.WarnLevel, msg, ErrBadMaxUsers, RequestUrlMalformedError)
+			return
+		}
+		sess.MaxUsers = limit
+	}
+	if waitingList := query.Get("waiting_list"); waitingList != "" {
+		enabled, err := strconv.ParseBool(waitingList)
+		if err != nil {
+			handleError(w, http.StatusBadRequest, log.WarnLevel, msg, err, RequestUrlMalformedError)
+			return
+		}
+		sess.WaitingList = enabled
+	}
+
 	// create admin user. contains
 	// - user secret
 	// - state for spotify authentication
@@ -86,9 +122,11 @@ func (h *handler) CreateSession(w http.ResponseWriter, r *http.Request) {
 	response := &struct {
 		UserInfo *user.Model `json:"user_info"`
 		AuthUrl  string      `json:"auth_url"`
+		JoinCode string      `json:"join_code"`
 	}{
 		UserInfo: admin,
 		AuthUrl:  authUrl,
+		JoinCode: sess.JoinCode,
 	}
 
 	log.Infof("%v: [%v] successfully created session with id [%v]", msg, username, sess.ID)
@@ -122,7 +160,10 @@ func (h *handler) DeleteSession(w http.ResponseWriter, r *http.Request) {
 	err = h.SessionCollection.DeleteSession(ctx, sessionID)
 	if err != nil {
 		handleError(w, http.StatusInternalServerError, log.ErrorLevel, msg, err, InternalServerError)
+		return
 	}
+
+	h.eventBus.Publish(playerctrl.SessionDeletedEvent, events.GroupID(sessionID), playerctrl.SessionDeletedPayload{})
 }
 
 func (h *handler) RemoveSong(w http.ResponseWriter, r *http.Request) {
@@ -156,8 +197,392 @@ func (h *handler) RemoveSong(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	h.eventBus.Publish(sse.PlaylistChange, events.GroupID(sessionID), songList)
+	h.playlists.Publish(sessionID, songList)
 
 	log.Infof("%v: admin removed song [%v]", msg, songID)
 	jsonResponse(w, songList)
 }
+
+// PinSong pins a song behind the songs that are already pinned
+func (h *handler) PinSong(w http.ResponseWriter, r *http.Request) {
+	h.reorderQueue(w, r, "[handler] pin song", func(pinned []string, songID string) []string {
+		return queue.Move(pinned, songID, len(pinned))
+	})
+}
+
+// UnpinSong hands a song back to the queue ordering strategy
+func (h *handler) UnpinSong(w http.ResponseWriter, r *http.Request) {
+	h.reorderQueue(w, r, "[handler] unpin song", func(pinned []string, songID string) []string {
+		result := make([]string, 0, len(pinned))
+		for _, id := range pinned {
+			if id != songID {
+				result = append(result, id)
+			}
+		}
+		return result
+	})
+}
+
+// MoveSong moves a song to a position in the queue, which pins the song.
+// Positions after the last pinned song place the song behind the pinned songs.
+func (h *handler) MoveSong(w http.ResponseWriter, r *http.Request) {
+	msg := "[handler] move song"
+	position, err := strconv.Atoi(mux.Vars(r)["position"])
+	if err != nil || position < 0 {
+		handleError(w, http.StatusBadRequest, log.WarnLevel, msg, ErrBadPosition, RequestUrlMalformedError)
+		return
+	}
+	h.reorderQueue(w, r, msg, func(pinned []string, songID string) []string {
+		return queue.Move(pinned, songID, position)
+	})
+}
+
+// reorderQueue applies reorder to the pinned songs of a session, stores the new priorities
+// and broadcasts the resulting song list
+func (h *handler) reorderQueue(
+	w http.ResponseWriter,
+	r *http.Request,
+	msg string,
+	reorder func(pinned []string, songID string) []string,
+) {
+	ctx := context.Background()
+	vars := mux.Vars(r)
+	songID := vars["song_id"]
+	sessionID := r.Header.Get("Session")
+
+	// update session time stamp
+	h.SessionCollection.SetLastUpdated(ctx, sessionID)
+
+	songList, err := h.SongCollection.ListSongs(ctx, sessionID)
+	if err != nil {
+		handleError(w, http.StatusInternalServerError, log.ErrorLevel, msg, err, InternalServerError)
+		return
+	}
+	found := false
+	for _, s := range songList {
+		if s.ID == songID {
+			found = true
+			break
+		}
+	}
+	if !found {
+		handleError(w, http.StatusBadRequest, log.WarnLevel, msg, db.ErrNoSongWithID, SongNotFoundError)
+		return
+	}
+
+	pinned := queue.Pinned(songList)
+	priorities := queue.Priorities(reorder(pinned, songID))
+	// songs that are no longer pinned go back to the queue ordering strategy
+	for _, id := range pinned {
+		if _, ok := priorities[id]; !ok {
+			priorities[id] = 0
+		}
+	}
+	if _, ok := priorities[songID]; !ok {
+		priorities[songID] = 0
+	}
+
+	if err := h.SongCollection.SetPriorities(ctx, sessionID, priorities); err != nil {
+		if errors.Is(err, db.ErrNoSessionWithID) {
+			handleError(w, http.StatusBadRequest, log.WarnLevel, msg, err, SessionNotFoundError)
+		} else {
+			handleError(w, http.StatusInternalServerError, log.ErrorLevel, msg, err, InternalServerError)
+		}
+		return
+	}
+
+	songList, err = h.SongCollection.ListSongs(ctx, sessionID)
+	if err != nil {
+		handleError(w, http.StatusInternalServerError, log.ErrorLevel, msg, err, InternalServerError)
+		return
+	}
+
+	h.playlists.Publish(sessionID, songList)
+
+	log.Infof("%v: session [%v] song [%v]", msg, sessionID, songID)
+	jsonResponse(w, songList)
+}
+
+func (h *handler) GetSettings(w http.ResponseWriter, r *http.Request) {
+	msg := "[handler] get settings"
+	ctx := context.Background()
+	sessionID := r.Header.Get("Session")
+
+	settings, err := h.SessionCollection.GetSettings(ctx, sessionID)
+	if err != nil {
+		if errors.Is(err, db.ErrNoSessionWithID) {
+			handleError(w, http.StatusBadRequest, log.WarnLevel, msg, err, SessionNotFoundError)
+		} else {
+			handleError(w, http.StatusInternalServerError, log.ErrorLevel, msg, err, InternalServerError)
+		}
+		return
+	}
+
+	jsonResponse(w, settings)
+}
+
+// UpdateSettings updates the session's settings and notifies all connected clients.
+// The body is applied onto the current settings, fields missing from it keep their value.
+func (h *handler) UpdateSettings(w http.ResponseWriter, r *http.Request) {
+	msg := "[handler] update settings"
+	ctx := context.Background()
+	sessionID := r.Header.Get("Session")
+
+	// update session time stamp
+	h.SessionCollection.SetLastUpdated(ctx, sessionID)
+
+	settings, err := h.SessionCollection.GetSettings(ctx, sessionID)
+	if err != nil {
+		if errors.Is(err, db.ErrNoSessionWithID) {
+			handleError(w, http.StatusBadRequest, log.WarnLevel, msg, err, SessionNotFoundError)
+		} else {
+			handleError(w, http.StatusInternalServerError, log.ErrorLevel, msg, err, InternalServerError)
+		}
+		return
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(settings); err != nil {
+		handleError(w, http.StatusBadRequest, log.WarnLevel, msg, err, RequestBodyMalformedError)
+		return
+	}
+
+	if err := settings.Validate(); err != nil {
+		frontendErr := BadSettingsError
+		frontendErr.Description = err.Error()
+		handleError(w, http.StatusBadRequest, log.WarnLevel, msg, err, frontendErr)
+		return
+	}
+
+	if err := h.SessionCollection.UpdateSettings(ctx, sessionID, settings); err != nil {
+		if errors.Is(err, db.ErrNoSessionWithID) {
+			handleError(w, http.StatusBadRequest, log.WarnLevel, msg, err, SessionNotFoundError)
+		} else {
+			handleError(w, http.StatusInternalServerError, log.ErrorLevel, msg, err, InternalServerError)
+		}
+		return
+	}
+
+	h.eventBus.Publish(sse.SessionSettingsChange, events.GroupID(sessionID), settings)
+
+	log.Infof("%v: session=[%v]", msg, sessionID)
+	jsonResponse(w, settings)
+}
+
+// SetPassword sets the password required to join the session, an empty password removes it
+func (h *handler) SetPassword(w http.ResponseWriter, r *http.Request) {
+	msg := "[handler] set password"
+	ctx := context.Background()
+	sessionID := r.Header.Get("Session")
+
+	var body *struct {
+		Password string `json:"password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body == nil {
+		handleError(w, http.StatusBadRequest, log.WarnLevel, msg, err, RequestBodyMalformedError)
+		return
+	}
+
+	passwordHash := ""
+	if body.Password != "" {
+		hash, err := session.HashPassword(body.Password)
+		if err != nil {
+			if errors.Is(err, session.ErrPasswordTooLong) {
+				handleError(w, http.StatusBadRequest, log.DebugLevel, msg, err, PasswordTooLongError)
+			} else {
+				handleError(w, http.StatusInternalServerError, log.ErrorLevel, msg, err, InternalServerError)
+			}
+			return
+		}
+		passwordHash = hash
+	}
+
+	if err := h.SessionCollection.SetPassword(ctx, sessionID, passwordHash); err != nil {
+		if errors.Is(err, db.ErrNoSessionWithID) {
+			handleError(w, http.StatusBadRequest, log.WarnLevel, msg, err, SessionNotFoundError)
+		} else {
+			handleError(w, http.StatusInternalServerError, log.ErrorLevel, msg, err, InternalServerError)
+		}
+		return
+	}
+
+	log.Infof("%v: session=[%v] password_protected=[%v]", msg, sessionID, passwordHash != "")
+	w.WriteHeader(http.StatusOK)
+}
+
+// CreateInvite issues a new invite for the session
+func (h *handler) CreateInvite(w http.ResponseWriter, r *http.Request) {
+	msg := "[handler] create invite"
+	ctx := context.Background()
+	sessionID := r.Header.Get("Session")
+
+	var body *struct {
+		SingleUse  bool `json:"single_use"`
+		ExpiresInS int  `json:"expires_in_s"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body == nil {
+		handleError(w, http.StatusBadRequest, log.WarnLevel, msg, err, RequestBodyMalformedError)
+		return
+	}
+
+	invite, err := session.NewInvite(body.SingleUse, time.Duration(body.ExpiresInS)*time.Second)
+	if err != nil {
+		if errors.Is(err, session.ErrBadInviteExpiry) {
+			handleError(w, http.StatusBadRequest, log.DebugLevel, msg, err, RequestBodyMalformedError)
+		} else {
+			handleError(w, http.StatusInternalServerError, log.ErrorLevel, msg, err, InternalServerError)
+		}
+		return
+	}
+
+	if err := h.SessionCollection.AddInvite(ctx, sessionID, invite); err != nil {
+		if errors.Is(err, db.ErrNoSessionWithID) {
+			handleError(w, http.StatusBadRequest, log.WarnLevel, msg, err, SessionNotFoundError)
+		} else {
+			handleError(w, http.StatusInternalServerError, log.ErrorLevel, msg, err, InternalServerError)
+		}
+		return
+	}
+
+	log.Infof("%v: session=[%v] single_use=[%v]", msg, sessionID, invite.SingleUse)
+	jsonResponse(w, invite)
+}
+
+// KickUser removes a user from the session
+// - with the query parameter `ban=true` the user is also put on the session's ban list
+// - the kicked user's event streams are closed and its spotify client is paused
+func (h *handler) KickUser(w http.ResponseWriter, r *http.Request) {
+	msg := "[handler] kick user"
+	ctx := context.Background()
+
+	vars := mux.Vars(r)
+	target := vars["target"]
+	sessionID := r.Header.Get("Session")
+	targetID := user.GenerateUserID(target, sessionID)
+
+	ban := false
+	if banParam := r.URL.Query().Get("ban"); banParam != "" {
+		var err error
+		ban, err = strconv.ParseBool(banParam)
+		if err != nil {
+			handleError(w, http.StatusBadRequest, log.WarnLevel, msg, err, RequestUrlMalformedError)
+			return
+		}
+	}
+
+	usr, err := h.UserCollection.GetUserByID(ctx, targetID)
+	if err != nil {
+		if errors.Is(err, db.ErrNoUserWithID) {
+			handleError(w, http.StatusBadRequest, log.WarnLevel, msg, err, UserNotFoundError)
+		} else {
+			handleError(w, http.StatusInternalServerError, log.ErrorLevel, msg, err, InternalServerError)
+		}
+		return
+	}
+	// the admin can not kick himself (see delete session endpoint)
+	if usr.IsAdmin {
+		handleError(w, http.StatusBadRequest, log.WarnLevel, msg, ErrUserIsAdmin, ActionNotAllowedError)
+		return
+	}
+
+	if ban {
+		err := h.SessionCollection.AddBan(ctx, sessionID, session.NewBan(usr.Username, usr.Fingerprint))
+		if err != nil {
+			handleError(w, http.StatusInternalServerError, log.ErrorLevel, msg, err, InternalServerError)
+			return
+		}
+	}
+
+	if usr.SpotifySynchronized {
+		h.pauseSpotifyClient(ctx, msg, sessionID, targetID)
+	}
+
+	if err := h.removeUser(ctx, msg, sessionID, targetID); err != nil {
+		handleError(w, http.StatusInternalServerError, log.ErrorLevel, msg, err, InternalServerError)
+		return
+	}
+
+	// closes the kicked user's event streams
+	h.eventBus.Publish(
+		sse.UserKicked,
+		events.GroupID(sessionID),
+		sse.UserKickedPayload{Username: usr.Username, Banned: ban},
+	)
+
+	log.Infof("%v: user=[%v] session=[%v] banned=[%v]", msg, usr.Username, sessionID, ban)
+	w.WriteHeader(http.StatusOK)
+}
+
+// Promote makes a guest a moderator
+func (h *handler) Promote(w http.ResponseWriter, r *http.Request) {
+	h.setRole(w, r, "[handler] promote", user.RoleModerator)
+}
+
+// Demote makes a moderator a guest again
+func (h *handler) Demote(w http.ResponseWriter, r *http.Request) {
+	h.setRole(w, r, "[handler] demote", user.RoleGuest)
+}
+
+func (h *handler) setRole(w http.ResponseWriter, r *http.Request, msg string, role user.Role) {
+	ctx := context.Background()
+
+	vars := mux.Vars(r)
+	target := vars["target"]
+	sessionID := r.Header.Get("Session")
+
+	// the owner is never matched, their role can only be handed on with a transfer
+	err := h.UserCollection.SetRole(ctx, user.GenerateUserID(target, sessionID), role)
+	if err != nil {
+		if errors.Is(err, db.ErrNoUserWithID) {
+			handleError(w, http.StatusBadRequest, log.WarnLevel, msg, err, UserNotFoundError)
+		} else {
+			handleError(w, http.StatusInternalServerError, log.ErrorLevel, msg, err, InternalServerError)
+		}
+		return
+	}
+
+	h.publishUserList(ctx, msg, sessionID)
+	h.notifyUser(sessionID, target, sse.NotificationRoleChanged, sse.RoleChangedPayload{Role: role})
+
+	log.Infof("%v: user=[%v] session=[%v] role=[%v]", msg, target, sessionID, role)
+	w.WriteHeader(http.StatusOK)
+}
+
+// TransferOwnership makes another user the owner of the session, the previous owner becomes a moderator
+func (h *handler) TransferOwnership(w http.ResponseWriter, r *http.Request) {
+	msg := "[handler] transfer ownership"
+	ctx := context.Background()
+
+	vars := mux.Vars(r)
+	username := vars["username"]
+	target := vars["target"]
+	sessionID := r.Header.Get("Session")
+
+	if target == username {
+		handleError(w, http.StatusBadRequest, log.WarnLevel, msg, ErrUserIsAdmin, ActionNotAllowedError)
+		return
+	}
+
+	err := h.UserCollection.TransferOwnership(
+		ctx,
+		user.GenerateUserID(username, sessionID),
+		user.GenerateUserID(target, sessionID),
+	)
+	if err != nil {
+		if errors.Is(err, db.ErrNoUserWithID) {
+			handleError(w, http.StatusBadRequest, log.WarnLevel, msg, err, UserNotFoundError)
+		} else if errors.Is(err, db.ErrIllegalState) {
+			// the session has been handed on by a concurrent request
+			handleError(w, http.StatusUnauthorized, log.WarnLevel, msg, err, ActionNotAllowedError)
+		} else {
+			handleError(w, http.StatusInternalServerError, log.ErrorLevel, msg, err, InternalServerError)
+		}
+		return
+	}
+
+	h.publishUserList(ctx, msg, sessionID)
+	h.notifyUser(sessionID, target, sse.NotificationRoleChanged, sse.RoleChangedPayload{Role: user.RoleOwner})
+	h.notifyUser(sessionID, username, sse.NotificationRoleChanged, sse.RoleChangedPayload{Role: user.RoleModerator})
+
+	log.Infof("%v: [%v] handed session [%v] on to [%v]", msg, username, sessionID, target)
+	w.WriteHeader(http.StatusOK)
+}
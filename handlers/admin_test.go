@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
@@ -12,9 +13,13 @@ import (
 	"github.com/encore-fm/backend/db"
 	"github.com/encore-fm/backend/db/mocks"
 	"github.com/encore-fm/backend/events"
+	"github.com/encore-fm/backend/session"
 	"github.com/encore-fm/backend/song"
+	"github.com/encore-fm/backend/sse"
+	"github.com/encore-fm/backend/user"
 	"github.com/gorilla/mux"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 )
 
 // - song exists in db
@@ -271,3 +276,576 @@ func TestHandler_RemoveSong_UnknownError(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Equal(t, InternalServerError, frontendErr)
 }
+
+// settings are valid and get saved
+func TestHandler_UpdateSettings(t *testing.T) {
+	sessionID := "session_id"
+	username := "username"
+	settings := session.DefaultSettings()
+	settings.Name = "party"
+	settings.VotingRules.AllowDownvotes = false
+
+	// set up sessionCollection mock
+	var sessionCollection db.SessionCollection
+	sessionCollection = &mocks.SessionCollection{}
+
+	sessionCollection.(*mocks.SessionCollection).
+		On("SetLastUpdated", context.Background(), sessionID).
+		Return()
+
+	sessionCollection.(*mocks.SessionCollection).
+		On("GetSettings", context.Background(), sessionID).
+		Return(session.DefaultSettings(), nil)
+
+	sessionCollection.(*mocks.SessionCollection).
+		On("UpdateSettings", context.Background(), sessionID, settings).
+		Return(nil)
+
+	eventBus := events.NewEventBus()
+	eventBus.Start()
+	// create handler with mock collections
+	handler := &handler{
+		SessionCollection: sessionCollection,
+		eventBus:          eventBus,
+	}
+	adminHandler := AdminHandler(handler)
+
+	body, err := json.Marshal(settings)
+	assert.NoError(t, err)
+
+	// set up http request
+	req, err := http.NewRequest(
+		"PUT",
+		fmt.Sprintf("/admin/%v/settings", username),
+		bytes.NewReader(body),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req = mux.SetURLVars(req, map[string]string{
+		"username": username,
+	})
+	req.Header.Set("Session", sessionID)
+	rr := httptest.NewRecorder()
+
+	// call handler func
+	adminHandler.UpdateSettings(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var result *session.Settings
+	err = json.NewDecoder(rr.Body).Decode(&result)
+	assert.NoError(t, err)
+	assert.Equal(t, settings, result)
+}
+
+// fields missing from the body keep their current value
+func TestHandler_UpdateSettings_Partial(t *testing.T) {
+	sessionID := "session_id"
+	username := "username"
+	current := session.DefaultSettings()
+	current.InviteOnly = true
+	current.Permissions[session.ActionSkip] = user.RoleModerator
+
+	expected := session.DefaultSettings()
+	expected.InviteOnly = true
+	expected.Permissions[session.ActionSkip] = user.RoleModerator
+	expected.Name = "party"
+
+	// set up sessionCollection mock
+	var sessionCollection db.SessionCollection
+	sessionCollection = &mocks.SessionCollection{}
+
+	sessionCollection.(*mocks.SessionCollection).
+		On("SetLastUpdated", context.Background(), sessionID).
+		Return()
+
+	sessionCollection.(*mocks.SessionCollection).
+		On("GetSettings", context.Background(), sessionID).
+		Return(current, nil)
+
+	sessionCollection.(*mocks.SessionCollection).
+		On("UpdateSettings", context.Background(), sessionID, expected).
+		Return(nil)
+
+	eventBus := events.NewEventBus()
+	eventBus.Start()
+	// create handler with mock collections
+	handler := &handler{
+		SessionCollection: sessionCollection,
+		eventBus:          eventBus,
+	}
+	adminHandler := AdminHandler(handler)
+
+	// set up http request
+	req, err := http.NewRequest(
+		"PUT",
+		fmt.Sprintf("/admin/%v/settings", username),
+		bytes.NewReader([]byte(`{"name": "party"}`)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req = mux.SetURLVars(req, map[string]string{
+		"username": username,
+	})
+	req.Header.Set("Session", sessionID)
+	rr := httptest.NewRecorder()
+
+	// call handler func
+	adminHandler.UpdateSettings(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var result *session.Settings
+	err = json.NewDecoder(rr.Body).Decode(&result)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, result)
+}
+
+// settings fail validation and are not saved
+func TestHandler_UpdateSettings_Invalid(t *testing.T) {
+	sessionID := "session_id"
+	username := "username"
+	settings := session.DefaultSettings()
+	settings.Visibility = "secret"
+
+	// set up sessionCollection mock
+	var sessionCollection db.SessionCollection
+	sessionCollection = &mocks.SessionCollection{}
+
+	sessionCollection.(*mocks.SessionCollection).
+		On("SetLastUpdated", context.Background(), sessionID).
+		Return()
+
+	sessionCollection.(*mocks.SessionCollection).
+		On("GetSettings", context.Background(), sessionID).
+		Return(session.DefaultSettings(), nil)
+
+	// create handler with mock collections
+	handler := &handler{
+		SessionCollection: sessionCollection,
+	}
+	adminHandler := AdminHandler(handler)
+
+	body, err := json.Marshal(settings)
+	assert.NoError(t, err)
+
+	// set up http request
+	req, err := http.NewRequest(
+		"PUT",
+		fmt.Sprintf("/admin/%v/settings", username),
+		bytes.NewReader(body),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req = mux.SetURLVars(req, map[string]string{
+		"username": username,
+	})
+	req.Header.Set("Session", sessionID)
+	rr := httptest.NewRecorder()
+
+	// call handler func
+	adminHandler.UpdateSettings(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	var frontendErr FrontendError
+	err = json.NewDecoder(rr.Body).Decode(&frontendErr)
+	assert.NoError(t, err)
+	assert.Equal(t, BadSettingsError.Error, frontendErr.Error)
+	sessionCollection.(*mocks.SessionCollection).AssertNotCalled(t, "UpdateSettings", mock.Anything, mock.Anything, mock.Anything)
+}
+
+// admin creates a single use invite
+func TestHandler_CreateInvite(t *testing.T) {
+	sessionID := "session_id"
+	username := "username"
+
+	// set up sessionCollection mock
+	var sessionCollection db.SessionCollection
+	sessionCollection = &mocks.SessionCollection{}
+
+	sessionCollection.(*mocks.SessionCollection).
+		On("AddInvite", context.Background(), sessionID, mock.MatchedBy(func(invite *session.Invite) bool {
+			return invite.SingleUse && invite.Token != "" && !invite.ExpiresAt.IsZero()
+		})).
+		Return(nil)
+
+	// create handler with mock collections
+	handler := &handler{
+		SessionCollection: sessionCollection,
+	}
+	adminHandler := AdminHandler(handler)
+
+	// set up http request
+	req, err := http.NewRequest(
+		"POST",
+		fmt.Sprintf("/admin/%v/invites", username),
+		bytes.NewReader([]byte(`{"single_use": true, "expires_in_s": 3600}`)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req = mux.SetURLVars(req, map[string]string{
+		"username": username,
+	})
+	req.Header.Set("Session", sessionID)
+	rr := httptest.NewRecorder()
+
+	// call handler func
+	adminHandler.CreateInvite(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var result *session.Invite
+	err = json.NewDecoder(rr.Body).Decode(&result)
+	assert.NoError(t, err)
+	assert.True(t, result.SingleUse)
+	assert.NotEmpty(t, result.Token)
+	sessionCollection.(*mocks.SessionCollection).AssertNumberOfCalls(t, "AddInvite", 1)
+}
+
+// admin kicks and bans a user
+func TestHandler_KickUser(t *testing.T) {
+	sessionID := "session_id"
+	username := "username"
+	target := &user.Model{
+		ID:          user.GenerateUserID("troll", sessionID),
+		Username:    "troll",
+		SessionID:   sessionID,
+		Fingerprint: "fingerprint",
+	}
+
+	// set up collection mocks
+	var userCollection db.UserCollection
+	userCollection = &mocks.UserCollection{}
+
+	userCollection.(*mocks.UserCollection).
+		On("GetUserByID", context.Background(), target.ID).
+		Return(target, nil)
+
+	userCollection.(*mocks.UserCollection).
+		On("DeleteUser", context.Background(), target.ID).
+		Return(nil)
+
+	userCollection.(*mocks.UserCollection).
+		On("AdmitWaitingUsers", context.Background(), sessionID).
+		Return(nil, nil)
+
+	userCollection.(*mocks.UserCollection).
+		On("ListUsers", context.Background(), sessionID).
+		Return(make([]*user.ListElement, 0), nil)
+
+	var sessionCollection db.SessionCollection
+	sessionCollection = &mocks.SessionCollection{}
+
+	sessionCollection.(*mocks.SessionCollection).
+		On("AddBan", context.Background(), sessionID, mock.MatchedBy(func(ban *session.Ban) bool {
+			return ban.Username == target.Username && ban.Fingerprint == target.Fingerprint
+		})).
+		Return(nil)
+
+	eventBus := events.NewEventBus()
+	eventBus.Start()
+	sub := eventBus.Subscribe(
+		[]events.EventType{sse.UserKicked},
+		[]events.GroupID{events.GroupID(sessionID)},
+	)
+	defer eventBus.Unsubscribe(sub)
+
+	// create handler with mock collections
+	handler := &handler{
+		UserCollection:    userCollection,
+		SessionCollection: sessionCollection,
+		eventBus:          eventBus,
+	}
+	adminHandler := AdminHandler(handler)
+
+	// set up http request
+	req, err := http.NewRequest(
+		"DELETE",
+		fmt.Sprintf("/admin/%v/users/%v?ban=true", username, target.Username),
+		nil,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req = mux.SetURLVars(req, map[string]string{
+		"username": username,
+		"target":   target.Username,
+	})
+	req.Header.Set("Session", sessionID)
+	rr := httptest.NewRecorder()
+
+	// call handler func
+	adminHandler.KickUser(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	userCollection.(*mocks.UserCollection).AssertNumberOfCalls(t, "DeleteUser", 1)
+	sessionCollection.(*mocks.SessionCollection).AssertNumberOfCalls(t, "AddBan", 1)
+
+	event := <-sub.Channel
+	assert.Equal(t, sse.UserKickedPayload{Username: target.Username, Banned: true}, event.Data)
+}
+
+// owner promotes a guest to moderator
+func TestHandler_Promote(t *testing.T) {
+	sessionID := "session_id"
+	username := "username"
+	target := "guest"
+
+	var userCollection db.UserCollection
+	userCollection = &mocks.UserCollection{}
+
+	userCollection.(*mocks.UserCollection).
+		On("SetRole", context.Background(), user.GenerateUserID(target, sessionID), user.RoleModerator).
+		Return(nil)
+
+	userCollection.(*mocks.UserCollection).
+		On("ListUsers", context.Background(), sessionID).
+		Return(make([]*user.ListElement, 0), nil)
+
+	eventBus := events.NewEventBus()
+	eventBus.Start()
+	// create handler with mock collections
+	handler := &handler{
+		UserCollection: userCollection,
+		eventBus:       eventBus,
+	}
+	adminHandler := AdminHandler(handler)
+
+	// set up http request
+	req, err := http.NewRequest(
+		"POST",
+		fmt.Sprintf("/admin/%v/promote/%v", username, target),
+		nil,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req = mux.SetURLVars(req, map[string]string{
+		"username": username,
+		"target":   target,
+	})
+	req.Header.Set("Session", sessionID)
+	rr := httptest.NewRecorder()
+
+	// call handler func
+	adminHandler.Promote(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	userCollection.(*mocks.UserCollection).AssertNumberOfCalls(t, "SetRole", 1)
+}
+
+// owner tries to transfer the session to a user that does not exist
+func TestHandler_TransferOwnership_NoUserWithID(t *testing.T) {
+	sessionID := "session_id"
+	username := "username"
+	target := "unknown"
+
+	var userCollection db.UserCollection
+	userCollection = &mocks.UserCollection{}
+
+	userCollection.(*mocks.UserCollection).
+		On(
+			"TransferOwnership",
+			context.Background(),
+			user.GenerateUserID(username, sessionID),
+			user.GenerateUserID(target, sessionID),
+		).
+		Return(db.ErrNoUserWithID)
+
+	// create handler with mock collections
+	handler := &handler{
+		UserCollection: userCollection,
+	}
+	adminHandler := AdminHandler(handler)
+
+	// set up http request
+	req, err := http.NewRequest(
+		"POST",
+		fmt.Sprintf("/admin/%v/transferOwnership/%v", username, target),
+		nil,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req = mux.SetURLVars(req, map[string]string{
+		"username": username,
+		"target":   target,
+	})
+	req.Header.Set("Session", sessionID)
+	rr := httptest.NewRecorder()
+
+	// call handler func
+	adminHandler.TransferOwnership(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	var frontendErr FrontendError
+	err = json.NewDecoder(rr.Body).Decode(&frontendErr)
+	assert.NoError(t, err)
+	assert.Equal(t, UserNotFoundError, frontendErr)
+}
+
+// the session has been handed on by a concurrent transfer
+func TestHandler_TransferOwnership_Concurrent(t *testing.T) {
+	sessionID := "session_id"
+	username := "username"
+	target := "target"
+
+	var userCollection db.UserCollection
+	userCollection = &mocks.UserCollection{}
+
+	userCollection.(*mocks.UserCollection).
+		On(
+			"TransferOwnership",
+			context.Background(),
+			user.GenerateUserID(username, sessionID),
+			user.GenerateUserID(target, sessionID),
+		).
+		Return(fmt.Errorf("[db] transfer ownership: %w", db.ErrIllegalState))
+
+	// create handler with mock collections
+	handler := &handler{
+		UserCollection: userCollection,
+	}
+	adminHandler := AdminHandler(handler)
+
+	// set up http request
+	req, err := http.NewRequest(
+		"POST",
+		fmt.Sprintf("/admin/%v/transferOwnership/%v", username, target),
+		nil,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req = mux.SetURLVars(req, map[string]string{
+		"username": username,
+		"target":   target,
+	})
+	req.Header.Set("Session", sessionID)
+	rr := httptest.NewRecorder()
+
+	// call handler func
+	adminHandler.TransferOwnership(rr, req)
+
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+
+	var frontendErr FrontendError
+	err = json.NewDecoder(rr.Body).Decode(&frontendErr)
+	assert.NoError(t, err)
+	assert.Equal(t, ActionNotAllowedError, frontendErr)
+}
+
+// admin moves an unpinned song in front of the pinned songs
+func TestHandler_MoveSong(t *testing.T) {
+	sessionID := "session_id"
+	username := "username"
+	songList := []*song.Model{
+		{ID: "pinned", Priority: 1},
+		{ID: "song_id"},
+	}
+
+	var songCollection db.SongCollection
+	songCollection = &mocks.SongCollection{}
+
+	var sessionCollection db.SessionCollection
+	sessionCollection = &mocks.SessionCollection{}
+
+	sessionCollection.(*mocks.SessionCollection).
+		On("SetLastUpdated", context.Background(), sessionID).
+		Return()
+
+	songCollection.(*mocks.SongCollection).
+		On("ListSongs", context.Background(), sessionID).
+		Return(songList, nil)
+
+	songCollection.(*mocks.SongCollection).
+		On("SetPriorities", context.Background(), sessionID, map[string]int{"song_id": 1, "pinned": 2}).
+		Return(nil)
+
+	eventBus := events.NewEventBus()
+	eventBus.Start()
+	handler := &handler{
+		SongCollection:    songCollection,
+		SessionCollection: sessionCollection,
+		eventBus:          eventBus,
+		playlists:         sse.NewPlaylistTracker(eventBus),
+	}
+	adminHandler := AdminHandler(handler)
+
+	req, err := http.NewRequest(
+		"PUT",
+		fmt.Sprintf("/admin/%v/queue/%v/position/%v", username, "song_id", 0),
+		nil,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req = mux.SetURLVars(req, map[string]string{
+		"username": username,
+		"song_id":  "song_id",
+		"position": "0",
+	})
+	req.Header.Set("Session", sessionID)
+	rr := httptest.NewRecorder()
+
+	adminHandler.MoveSong(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	songCollection.(*mocks.SongCollection).AssertNumberOfCalls(t, "SetPriorities", 1)
+}
+
+// admin tries to pin a song that is not in the queue
+func TestHandler_PinSong_NoSongWithID(t *testing.T) {
+	sessionID := "session_id"
+	username := "username"
+
+	var songCollection db.SongCollection
+	songCollection = &mocks.SongCollection{}
+
+	var sessionCollection db.SessionCollection
+	sessionCollection = &mocks.SessionCollection{}
+
+	sessionCollection.(*mocks.SessionCollection).
+		On("SetLastUpdated", context.Background(), sessionID).
+		Return()
+
+	songCollection.(*mocks.SongCollection).
+		On("ListSongs", context.Background(), sessionID).
+		Return(make([]*song.Model, 0), nil)
+
+	handler := &handler{
+		SongCollection:    songCollection,
+		SessionCollection: sessionCollection,
+	}
+	adminHandler := AdminHandler(handler)
+
+	req, err := http.NewRequest(
+		"POST",
+		fmt.Sprintf("/admin/%v/queue/%v/pin", username, "song_id"),
+		nil,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req = mux.SetURLVars(req, map[string]string{
+		"username": username,
+		"song_id":  "song_id",
+	})
+	req.Header.Set("Session", sessionID)
+	rr := httptest.NewRecorder()
+
+	adminHandler.PinSong(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	var response FrontendError
+	err = json.NewDecoder(rr.Body).Decode(&response)
+	assert.NoError(t, err)
+	assert.Equal(t, SongNotFoundError, response)
+}
@@ -7,6 +7,7 @@ import (
 	"net/http"
 
 	"github.com/encore-fm/backend/db"
+	"github.com/encore-fm/backend/session"
 	"github.com/encore-fm/backend/user"
 	"github.com/gorilla/mux"
 	log "github.com/sirupsen/logrus"
@@ -14,42 +15,31 @@ import (
 
 type AuthFunc = func(http.Handler) http.Handler
 
-func authenticate(userCollection db.UserCollection, checkAdmin bool) AuthFunc {
+// roleLookup returns the least role required for a request to the given session
+type roleLookup func(ctx context.Context, sessionID string) (user.Role, error)
+
+func staticRole(role user.Role) roleLookup {
+	return func(context.Context, string) (user.Role, error) {
+		return role, nil
+	}
+}
+
+// authenticate checks the user's secret and whether the user has at least the required role
+func authenticate(userCollection db.UserCollection, name string, requiredRole roleLookup, allowWaiting bool) AuthFunc {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			ctx := context.Background()
 
-			userOrAdmin := "user"
-			if checkAdmin {
-				userOrAdmin = "admin"
-			}
-			msg := fmt.Sprintf("[auth] authenticate %v request", userOrAdmin)
+			msg := fmt.Sprintf("[auth] authenticate %v request", name)
 
 			vars := mux.Vars(r)
 			username := vars["username"]
 
 			secret := r.Header.Get("Authorization")
 			sessID := r.Header.Get("Session")
-			userID := user.GenerateUserID(username, sessID)
 
-			u, err := userCollection.GetUserByID(ctx, userID)
-			// error while looking up user
-			if errors.Is(err, db.ErrNoUserWithID) {
-				handleError(w, http.StatusUnauthorized, log.WarnLevel, msg, err, RequestNotAuthorizedError)
-				return
-			}
-			if err != nil {
-				handleError(w, http.StatusInternalServerError, log.ErrorLevel, msg, err, InternalServerError)
-				return
-			}
-
-			if secret != u.Secret {
-				handleError(w, http.StatusUnauthorized, log.WarnLevel, msg, ErrWrongUserSecret, RequestNotAuthorizedError)
-				return
-			}
-
-			if checkAdmin && !u.IsAdmin {
-				handleError(w, http.StatusUnauthorized, log.WarnLevel, msg, ErrUserNotAdmin, ActionNotAllowedError)
+			if _, reqErr := authorizeUser(ctx, userCollection, username, sessID, secret, requiredRole, allowWaiting); reqErr != nil {
+				reqErr.write(w, msg)
 				return
 			}
 
@@ -58,10 +48,75 @@ func authenticate(userCollection db.UserCollection, checkAdmin bool) AuthFunc {
 	}
 }
 
+// authorizeUser returns the user if the secret is the user's one and the user has at least the required role
+func authorizeUser(
+	ctx context.Context,
+	userCollection db.UserCollection,
+	username, sessionID, secret string,
+	requiredRole roleLookup,
+	allowWaiting bool,
+) (*user.Model, *requestError) {
+	u, err := userCollection.GetUserByID(ctx, user.GenerateUserID(username, sessionID))
+	// error while looking up user
+	if errors.Is(err, db.ErrNoUserWithID) {
+		return nil, newRequestError(http.StatusUnauthorized, log.WarnLevel, err, RequestNotAuthorizedError)
+	}
+	if err != nil {
+		return nil, internalError(err)
+	}
+
+	if secret != u.Secret {
+		return nil, newRequestError(http.StatusUnauthorized, log.WarnLevel, ErrWrongUserSecret, RequestNotAuthorizedError)
+	}
+
+	role, err := requiredRole(ctx, sessionID)
+	if err != nil {
+		if errors.Is(err, db.ErrNoSessionWithID) {
+			return nil, newRequestError(http.StatusBadRequest, log.WarnLevel, err, SessionNotFoundError)
+		}
+		return nil, internalError(err)
+	}
+	if !u.GetRole().Includes(role) {
+		return nil, newRequestError(http.StatusUnauthorized, log.WarnLevel, ErrMissingRole, ActionNotAllowedError)
+	}
+
+	if !allowWaiting && u.Waiting {
+		return nil, newRequestError(http.StatusForbidden, log.InfoLevel, ErrUserWaiting, UserWaitingError)
+	}
+	return u, nil
+}
+
 func UserAuth(userCollection db.UserCollection) AuthFunc {
-	return authenticate(userCollection, false)
+	return authenticate(userCollection, "user", staticRole(user.RoleGuest), false)
+}
+
+// WaitingUserAuth also accepts users that are still on the session's waiting list
+func WaitingUserAuth(userCollection db.UserCollection) AuthFunc {
+	return authenticate(userCollection, "user", staticRole(user.RoleGuest), true)
 }
 
+// AdminAuth only accepts the session owner
 func AdminAuth(userCollection db.UserCollection) AuthFunc {
-	return authenticate(userCollection, true)
+	return authenticate(userCollection, "admin", staticRole(user.RoleOwner), false)
+}
+
+// AuthorizeFunc creates the middleware that guards an action
+type AuthorizeFunc = func(action session.Action) AuthFunc
+
+// Authorize accepts users whose role may perform an action according to the session's permissions
+func Authorize(userCollection db.UserCollection, sessionCollection db.SessionCollection) AuthorizeFunc {
+	return func(action session.Action) AuthFunc {
+		return authenticate(userCollection, string(action), actionRole(sessionCollection, action), false)
+	}
+}
+
+// actionRole looks up the least role that may perform an action according to the session's permissions
+func actionRole(sessionCollection db.SessionCollection, action session.Action) roleLookup {
+	return func(ctx context.Context, sessionID string) (user.Role, error) {
+		settings, err := sessionCollection.GetSettings(ctx, sessionID)
+		if err != nil {
+			return "", err
+		}
+		return settings.Permissions.RequiredRole(action), nil
+	}
 }
@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/encore-fm/backend/db"
+	"github.com/encore-fm/backend/db/mocks"
+	"github.com/encore-fm/backend/session"
+	"github.com/encore-fm/backend/user"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+// sends a request for the given user through the authorization middleware
+func authorizeRequest(
+	userCollection db.UserCollection,
+	sessionCollection db.SessionCollection,
+	action session.Action,
+	usr *user.Model,
+) *httptest.ResponseRecorder {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	authorized := Authorize(userCollection, sessionCollection)(action)(next)
+
+	req := httptest.NewRequest("POST", fmt.Sprintf("/users/%v/player/play", usr.Username), nil)
+	req = mux.SetURLVars(req, map[string]string{
+		"username": usr.Username,
+	})
+	req.Header.Set("Authorization", usr.Secret)
+	req.Header.Set("Session", usr.SessionID)
+	rr := httptest.NewRecorder()
+
+	authorized.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestAuthorize(t *testing.T) {
+	sessionID := "session_id"
+
+	guest, err := user.New("guest", sessionID)
+	assert.NoError(t, err)
+	admin, err := user.NewAdmin("admin", sessionID)
+	assert.NoError(t, err)
+
+	// guests may skip in this session
+	settings := session.DefaultSettings()
+	settings.Permissions[session.ActionSkip] = user.RoleGuest
+
+	var userCollection db.UserCollection
+	userCollection = &mocks.UserCollection{}
+
+	userCollection.(*mocks.UserCollection).
+		On("GetUserByID", context.Background(), guest.ID).
+		Return(guest, nil)
+
+	userCollection.(*mocks.UserCollection).
+		On("GetUserByID", context.Background(), admin.ID).
+		Return(admin, nil)
+
+	var sessionCollection db.SessionCollection
+	sessionCollection = &mocks.SessionCollection{}
+
+	sessionCollection.(*mocks.SessionCollection).
+		On("GetSettings", context.Background(), sessionID).
+		Return(settings, nil)
+
+	tests := []struct {
+		usr          *user.Model
+		action       session.Action
+		expectedCode int
+	}{
+		{usr: guest, action: session.ActionSkip, expectedCode: http.StatusOK},
+		{usr: guest, action: session.ActionPlayPause, expectedCode: http.StatusUnauthorized},
+		{usr: guest, action: session.ActionSeek, expectedCode: http.StatusUnauthorized},
+		{usr: guest, action: session.ActionRemoveSong, expectedCode: http.StatusUnauthorized},
+		{usr: admin, action: session.ActionPlayPause, expectedCode: http.StatusOK},
+		{usr: admin, action: session.ActionRemoveSong, expectedCode: http.StatusOK},
+	}
+
+	for _, test := range tests {
+		rr := authorizeRequest(userCollection, sessionCollection, test.action, test.usr)
+		assert.Equal(t, test.expectedCode, rr.Code, "user=%v action=%v", test.usr.Username, test.action)
+
+		if test.expectedCode != http.StatusOK {
+			var response FrontendError
+			err = json.NewDecoder(rr.Body).Decode(&response)
+			assert.NoError(t, err)
+			assert.Equal(t, ActionNotAllowedError, response)
+		}
+	}
+}
+
+func TestAuthorize_NoUserWithID(t *testing.T) {
+	usr := &user.Model{ID: user.GenerateUserID("username", "session_id"), Username: "username", SessionID: "session_id"}
+
+	var userCollection db.UserCollection
+	userCollection = &mocks.UserCollection{}
+
+	userCollection.(*mocks.UserCollection).
+		On("GetUserByID", context.Background(), usr.ID).
+		Return(nil, db.ErrNoUserWithID)
+
+	rr := authorizeRequest(userCollection, &mocks.SessionCollection{}, session.ActionPlayPause, usr)
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+
+	var response FrontendError
+	err := json.NewDecoder(rr.Body).Decode(&response)
+	assert.NoError(t, err)
+	assert.Equal(t, RequestNotAuthorizedError, response)
+}
+
+func TestAuthorize_InternalError(t *testing.T) {
+	usr, err := user.New("username", "session_id")
+	assert.NoError(t, err)
+
+	var userCollection db.UserCollection
+	userCollection = &mocks.UserCollection{}
+
+	userCollection.(*mocks.UserCollection).
+		On("GetUserByID", context.Background(), usr.ID).
+		Return(usr, nil)
+
+	var sessionCollection db.SessionCollection
+	sessionCollection = &mocks.SessionCollection{}
+
+	sessionCollection.(*mocks.SessionCollection).
+		On("GetSettings", context.Background(), usr.SessionID).
+		Return(nil, errors.New("test"))
+
+	rr := authorizeRequest(userCollection, sessionCollection, session.ActionPlayPause, usr)
+	assert.Equal(t, http.StatusInternalServerError, rr.Code)
+
+	var response FrontendError
+	err = json.NewDecoder(rr.Body).Decode(&response)
+	assert.NoError(t, err)
+	assert.Equal(t, InternalServerError, response)
+}
@@ -10,6 +10,7 @@ import (
 
 type DebugHandler interface {
 	ResetControllerState(w http.ResponseWriter, r *http.Request)
+	TrackCacheStats(w http.ResponseWriter, r *http.Request)
 }
 
 var _ DebugHandler = (*handler)(nil)
@@ -26,3 +27,15 @@ func (h *handler) ResetControllerState(w http.ResponseWriter, r *http.Request) {
 		},
 	)
 }
+
+func (h *handler) TrackCacheStats(w http.ResponseWriter, r *http.Request) {
+	hits, misses := h.Spotify.TrackCacheStats()
+	response := &struct {
+		Hits   uint64 `json:"hits"`
+		Misses uint64 `json:"misses"`
+	}{
+		Hits:   hits,
+		Misses: misses,
+	}
+	jsonResponse(w, response)
+}
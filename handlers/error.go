@@ -3,8 +3,13 @@ package handlers
 import (
 	"errors"
 	"fmt"
+	"math"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/encore-fm/backend/session"
 	"github.com/encore-fm/backend/user"
 	log "github.com/sirupsen/logrus"
 )
@@ -14,6 +19,18 @@ type FrontendError struct {
 	Description string `json:"description"`
 }
 
+// RetryableFrontendError tells the client when the request can be retried
+type RetryableFrontendError struct {
+	FrontendError
+	RetryAfter *time.Time `json:"retry_after,omitempty"`
+}
+
+// RejectedFrontendError lists the content rules a song violates
+type RejectedFrontendError struct {
+	FrontendError
+	Violations []session.Violation `json:"violations"`
+}
+
 var (
 	// Spotify errors
 	ErrSpotifyNotAuthenticated = errors.New("spotify not authenticated")
@@ -21,11 +38,33 @@ var (
 	ErrBadVoteAction = errors.New(`vote action must be in {"up", "down"}`)
 	// Authentication Errors
 	ErrWrongUserSecret = errors.New("user secret wrong")
-	ErrUserNotAdmin    = errors.New("user not an admin")
+	ErrMissingRole     = errors.New("user does not have the required role")
 	// Actions that cannot be performed by the admin e.g. leaving session
 	ErrUserIsAdmin = errors.New("the action cannot be performed by an admin")
 	// specifies that a sync mode did not match expected form
 	ErrBadSyncMode = errors.New(`sync mode must be in {"FORCE_SYNC", "FORCE_DESYNC", "AUTO"}`)
+	// Actions that cannot be performed by users on the waiting list
+	ErrUserWaiting = errors.New("user has not been admitted to the session yet")
+	// specifies that a user limit is out of range
+	ErrBadMaxUsers = errors.New("max users must be between 0 and the global user limit")
+	// Actions prohibited by the session settings
+	ErrDownvotesDisabled = errors.New("downvotes are disabled in this session")
+	ErrSongRejected      = errors.New("song violates the content rules of this session")
+	ErrRecentlyPlayed    = errors.New("song has been played recently")
+	ErrBadBatchSize      = errors.New("a batch must contain between 1 and 50 songs")
+	// Session access errors
+	ErrInviteRequired = errors.New("session can only be joined with an invite")
+	ErrWrongPassword  = errors.New("session password wrong")
+	ErrUserBanned     = errors.New("user has been banned from the session")
+	// Queue errors
+	ErrBadPosition = errors.New("queue position must be a non-negative integer")
+	// Player errors
+	ErrNoSongPlaying = errors.New("no song is playing")
+	ErrEmptyHistory  = errors.New("no song has been played in this session yet")
+	// Role errors
+	ErrNoSuccessor = errors.New("there is no user the session could be handed on to")
+	// Event stream errors
+	ErrTooManyStreams = errors.New("the maximum number of concurrent event streams has been reached")
 
 	// Frontend errors
 	UsernameTooShortError = FrontendError{
@@ -88,6 +127,66 @@ var (
 		Error:       "UserNotFoundError",
 		Description: "No user with the specified ID exists.",
 	}
+	SessionFullError = FrontendError{
+		Error:       "SessionFullError",
+		Description: "The session has reached its maximum number of users.",
+	}
+	UserWaitingError = FrontendError{
+		Error:       "UserWaitingError",
+		Description: "The user is on the session's waiting list and has not been admitted yet.",
+	}
+	BadSettingsError = FrontendError{
+		Error:       "BadSettingsError",
+		Description: "The session settings are invalid.",
+	}
+	DownvotesDisabledError = FrontendError{
+		Error:       "DownvotesDisabledError",
+		Description: "Downvotes are disabled in this session.",
+	}
+	SongRejectedError = FrontendError{
+		Error:       "SongRejectedError",
+		Description: "The song violates the content rules of this session.",
+	}
+	SuggestionLimitError = FrontendError{
+		Error:       "SuggestionLimitError",
+		Description: "The user has reached the suggestion limits of this session.",
+	}
+	BadCollectionURIError = FrontendError{
+		Error:       "BadCollectionURIError",
+		Description: "The uri has to point to a spotify playlist or album.",
+	}
+	EmptyHistoryError = FrontendError{
+		Error:       "EmptyHistoryError",
+		Description: "No song has been played in this session yet.",
+	}
+	RecentlyPlayedError = FrontendError{
+		Error:       "RecentlyPlayedError",
+		Description: "The song has been played recently and can not be suggested again yet.",
+	}
+	JoinCodeNotFoundError = FrontendError{
+		Error:       "JoinCodeNotFoundError",
+		Description: "There is no session with this join code.",
+	}
+	InviteRequiredError = FrontendError{
+		Error:       "InviteRequiredError",
+		Description: "A valid invite is required to join this session.",
+	}
+	WrongPasswordError = FrontendError{
+		Error:       "WrongPasswordError",
+		Description: "The session password is wrong.",
+	}
+	PasswordTooLongError = FrontendError{
+		Error:       "PasswordTooLongError",
+		Description: fmt.Sprintf("password should not have more than %v characters.", session.MaxPasswordLen),
+	}
+	UserBannedError = FrontendError{
+		Error:       "UserBannedError",
+		Description: "You have been banned from this session.",
+	}
+	TooManyStreamsError = FrontendError{
+		Error:       "TooManyStreamsError",
+		Description: "The maximum number of concurrent event streams of the user or session has been reached.",
+	}
 	ActionNotAllowedError = FrontendError{
 		Error:       "ActionNotAllowedError",
 		Description: "User does not have sufficient permissions to perform this action.",
@@ -99,6 +198,80 @@ var (
 )
 
 func handleError(w http.ResponseWriter, status int, logLevel log.Level, msg string, err error, frontendError FrontendError) {
+	logError(logLevel, msg, err)
+	jsonResponseWithStatus(w, status, frontendError)
+}
+
+// requestError is the error response of a request that is performed for http and websocket clients
+type requestError struct {
+	status int
+	level  log.Level
+	err    error
+	body   interface{}
+	// time at which the client may retry, nil if unknown
+	retryAfter *time.Time
+}
+
+func newRequestError(status int, logLevel log.Level, err error, frontendError FrontendError) *requestError {
+	return &requestError{status: status, level: logLevel, err: err, body: frontendError}
+}
+
+// retryableError also tells the client when to retry if that time is known
+func retryableError(
+	status int,
+	logLevel log.Level,
+	err error,
+	frontendError FrontendError,
+	retryAfter *time.Time,
+) *requestError {
+	return &requestError{
+		status:     status,
+		level:      logLevel,
+		err:        err,
+		body:       RetryableFrontendError{FrontendError: frontendError, RetryAfter: retryAfter},
+		retryAfter: retryAfter,
+	}
+}
+
+// rejectedSongError is a SongRejectedError describing the violated content rules
+func rejectedSongError(violations []session.Violation) *requestError {
+	return &requestError{
+		status: http.StatusForbidden,
+		level:  log.InfoLevel,
+		err:    ErrSongRejected,
+		body:   RejectedFrontendError{FrontendError: songRejectedError(violations), Violations: violations},
+	}
+}
+
+// internalError is the response to unexpected errors
+func internalError(err error) *requestError {
+	return newRequestError(http.StatusInternalServerError, log.ErrorLevel, err, InternalServerError)
+}
+
+// write logs the error and responds with it
+func (e *requestError) write(w http.ResponseWriter, msg string) {
+	logError(e.level, msg, e.err)
+	if e.retryAfter != nil {
+		// round up so clients never retry too early
+		seconds := int(math.Ceil(time.Until(*e.retryAfter).Seconds()))
+		w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	}
+	jsonResponseWithStatus(w, e.status, e.body)
+}
+
+// songRejectedError returns a SongRejectedError whose description lists the violated content rules
+func songRejectedError(violations []session.Violation) FrontendError {
+	descriptions := make([]string, 0, len(violations))
+	for _, violation := range violations {
+		descriptions = append(descriptions, violation.Description)
+	}
+	return FrontendError{
+		Error:       SongRejectedError.Error,
+		Description: fmt.Sprintf("The song violates the content rules of this session: %v.", strings.Join(descriptions, "; ")),
+	}
+}
+
+func logError(logLevel log.Level, msg string, err error) {
 	switch logLevel {
 	case log.WarnLevel:
 		log.Warnf("%v: %v", msg, err)
@@ -110,5 +283,4 @@ func handleError(w http.ResponseWriter, status int, logLevel log.Level, msg stri
 		log.Infof("%v: %v", msg, err)
 		break
 	}
-	jsonResponseWithStatus(w, status, frontendError)
 }
@@ -4,11 +4,13 @@ import (
 	"github.com/encore-fm/backend/db"
 	"github.com/encore-fm/backend/events"
 	"github.com/encore-fm/backend/spotifycl"
+	"github.com/encore-fm/backend/sse"
 	"github.com/zmb3/spotify"
 )
 
 type handler struct {
 	eventBus             events.EventBus
+	playlists            *sse.PlaylistTracker
 	spotifyAuthenticator spotify.Authenticator
 	Spotify              *spotifycl.SpotifyClient
 	UserCollection       db.UserCollection
@@ -19,6 +21,7 @@ type handler struct {
 
 func New(
 	eventBus events.EventBus,
+	playlists *sse.PlaylistTracker,
 	userCollection db.UserCollection,
 	sessCollection db.SessionCollection,
 	songCollection db.SongCollection,
@@ -28,6 +31,7 @@ func New(
 ) *handler {
 	return &handler{
 		eventBus:             eventBus,
+		playlists:            playlists,
 		spotifyAuthenticator: auth,
 		Spotify:              client,
 		UserCollection:       userCollection,
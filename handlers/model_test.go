@@ -6,12 +6,14 @@ import (
 	"github.com/encore-fm/backend/db"
 	"github.com/encore-fm/backend/events"
 	"github.com/encore-fm/backend/spotifycl"
+	"github.com/encore-fm/backend/sse"
 	"github.com/stretchr/testify/assert"
 	"github.com/zmb3/spotify"
 )
 
 func TestNew(t *testing.T) {
 	eventBus := events.NewEventBus()
+	playlists := sse.NewPlaylistTracker(eventBus)
 	auth := spotify.NewAuthenticator("http://123.de")
 	cli := &spotifycl.SpotifyClient{}
 	userCol := db.UserCollection(nil)
@@ -21,6 +23,7 @@ func TestNew(t *testing.T) {
 
 	expected := &handler{
 		eventBus:             eventBus,
+		playlists:            playlists,
 		spotifyAuthenticator: auth,
 		Spotify:              cli,
 		UserCollection:       userCol,
@@ -28,7 +31,7 @@ func TestNew(t *testing.T) {
 		SongCollection:       songCol,
 	}
 
-	result := New(eventBus, userCol, sessCol, songCol, playerCol, auth, cli)
+	result := New(eventBus, playlists, userCol, sessCol, songCol, playerCol, auth, cli)
 
 	assert.Equal(t, expected, result)
 }
@@ -3,7 +3,6 @@ package handlers
 import (
 	"context"
 	"errors"
-	"fmt"
 	"net/http"
 	"strconv"
 	"time"
@@ -21,6 +20,7 @@ type PlayerHandler interface {
 	Play(w http.ResponseWriter, r *http.Request)
 	Pause(w http.ResponseWriter, r *http.Request)
 	Skip(w http.ResponseWriter, r *http.Request)
+	VoteSkip(w http.ResponseWriter, r *http.Request)
 	Seek(w http.ResponseWriter, r *http.Request)
 	GetState(w http.ResponseWriter, r *http.Request)
 	Synchronize(w http.ResponseWriter, r *http.Request)
@@ -29,47 +29,24 @@ type PlayerHandler interface {
 
 var _ PlayerHandler = (*handler)(nil)
 
-func (h *handler) checkUserPermissions(w http.ResponseWriter, msg string, userID string) bool {
-	msg = fmt.Sprintf("%v: check user permissions", msg)
-	ctx := context.Background()
-
-	userInfo, err := h.UserCollection.GetUserByID(ctx, userID)
-	if err != nil {
-		if errors.Is(err, db.ErrNoUserWithID) {
-			handleError(w, http.StatusUnauthorized, log.WarnLevel, msg, err, RequestNotAuthorizedError)
-			return false
-		}
-		handleError(w, http.StatusInternalServerError, log.ErrorLevel, msg, err, InternalServerError)
-		return false
-	}
-
-	if !userInfo.IsAdmin {
-		handleError(w, http.StatusUnauthorized, log.WarnLevel, msg, err, ActionNotAllowedError)
-		return false
-	}
-	return true
+func (h *handler) setPausedState(w http.ResponseWriter, r *http.Request, paused bool) {
+	vars := mux.Vars(r)
+	h.setPaused(context.Background(), r.Header.Get("Session"), vars["username"], paused)
 }
 
-func (h *handler) setPausedState(w http.ResponseWriter, r *http.Request, paused bool) {
+// setPaused plays or pauses the player of the session for http and websocket clients
+func (h *handler) setPaused(ctx context.Context, sessionID, username string, paused bool) {
 	msg := "[player handler]: play / pause"
-	ctx := context.Background()
-
-	vars := mux.Vars(r)
-	username := vars["username"]
-	sessionID := r.Header.Get("Session")
 
 	// update session time stamp
 	h.SessionCollection.SetLastUpdated(ctx, sessionID)
 
-	if ok := h.checkUserPermissions(w, msg, user.GenerateUserID(username, sessionID)); !ok {
-		return
-	}
-
 	h.eventBus.Publish(
 		playerctrl.PlayPauseEvent,
 		events.GroupID(sessionID),
 		playerctrl.PlayPausePayload{Paused: paused},
 	)
+	log.Infof("%v: user=[%v] session=[%v] paused=[%v]", msg, username, sessionID, paused)
 }
 
 // Play toggles play on
@@ -93,19 +70,18 @@ func (h *handler) Skip(w http.ResponseWriter, r *http.Request) {
 	// update session time stamp
 	h.SessionCollection.SetLastUpdated(ctx, sessionID)
 
-	if ok := h.checkUserPermissions(w, msg, user.GenerateUserID(username, sessionID)); !ok {
-		return
-	}
-
 	h.eventBus.Publish(
 		playerctrl.SkipEvent,
 		events.GroupID(sessionID),
 		playerctrl.SkipPayload{},
 	)
+	log.Infof("%v: user=[%v] session=[%v]", msg, username, sessionID)
 }
 
-func (h *handler) Seek(w http.ResponseWriter, r *http.Request) {
-	msg := "[player handler]: seek"
+// VoteSkip votes to skip the song that is currently playing
+// the player controller skips the song once enough users voted
+func (h *handler) VoteSkip(w http.ResponseWriter, r *http.Request) {
+	msg := "[player handler]: vote skip"
 	ctx := context.Background()
 
 	vars := mux.Vars(r)
@@ -115,16 +91,56 @@ func (h *handler) Seek(w http.ResponseWriter, r *http.Request) {
 	// update session time stamp
 	h.SessionCollection.SetLastUpdated(ctx, sessionID)
 
-	positionMs, err := strconv.Atoi(vars["position_ms"])
+	playr, err := h.PlayerCollection.GetPlayer(ctx, sessionID)
 	if err != nil {
-		handleError(w, http.StatusBadRequest, log.WarnLevel, msg, err, RequestUrlMalformedError)
+		handleError(w, http.StatusInternalServerError, log.ErrorLevel, msg, err, InternalServerError)
+		return
+	}
+	if playr == nil || playr.IsEmpty() {
+		handleError(w, http.StatusBadRequest, log.DebugLevel, msg, ErrNoSongPlaying, SongNotFoundError)
 		return
 	}
 
-	if ok := h.checkUserPermissions(w, msg, user.GenerateUserID(username, sessionID)); !ok {
+	playr, err = h.PlayerCollection.AddSkipVote(ctx, sessionID, playr.CurrentSong.ID, username)
+	if err != nil {
+		// the song changed in the meantime
+		if errors.Is(err, db.ErrNoSongWithID) {
+			handleError(w, http.StatusBadRequest, log.DebugLevel, msg, err, SongNotFoundError)
+		} else {
+			handleError(w, http.StatusInternalServerError, log.ErrorLevel, msg, err, InternalServerError)
+		}
 		return
 	}
 
+	h.eventBus.Publish(
+		playerctrl.VoteSkipEvent,
+		events.GroupID(sessionID),
+		playerctrl.VoteSkipPayload{SongID: playr.CurrentSong.ID, SongStart: playr.SongStart, Votes: len(playr.SkipVotes)},
+	)
+	log.Infof("%v: user=[%v] session=[%v] votes=[%v]", msg, username, sessionID, len(playr.SkipVotes))
+}
+
+func (h *handler) Seek(w http.ResponseWriter, r *http.Request) {
+	msg := "[player handler]: seek"
+	ctx := context.Background()
+
+	vars := mux.Vars(r)
+	sessionID := r.Header.Get("Session")
+	if reqErr := h.seek(ctx, msg, sessionID, vars["username"], vars["position_ms"]); reqErr != nil {
+		reqErr.write(w, msg)
+	}
+}
+
+// seek moves the player of the session to the position for http and websocket clients
+func (h *handler) seek(ctx context.Context, msg, sessionID, username, position string) *requestError {
+	// update session time stamp
+	h.SessionCollection.SetLastUpdated(ctx, sessionID)
+
+	positionMs, err := strconv.Atoi(position)
+	if err != nil {
+		return newRequestError(http.StatusBadRequest, log.WarnLevel, err, RequestUrlMalformedError)
+	}
+
 	h.eventBus.Publish(
 		playerctrl.SeekEvent,
 		events.GroupID(sessionID),
@@ -132,6 +148,8 @@ func (h *handler) Seek(w http.ResponseWriter, r *http.Request) {
 			Progress: time.Millisecond * time.Duration(positionMs),
 		},
 	)
+	log.Infof("%v: user=[%v] session=[%v] position=[%vms]", msg, username, sessionID, positionMs)
+	return nil
 }
 
 // todo: add component tests
@@ -3,7 +3,6 @@ package handlers
 import (
 	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -14,7 +13,9 @@ import (
 	"github.com/encore-fm/backend/db"
 	"github.com/encore-fm/backend/db/mocks"
 	"github.com/encore-fm/backend/events"
+	"github.com/encore-fm/backend/player"
 	"github.com/encore-fm/backend/playerctrl"
+	"github.com/encore-fm/backend/song"
 	"github.com/encore-fm/backend/user"
 	"github.com/gorilla/mux"
 	"github.com/stretchr/testify/assert"
@@ -101,187 +102,6 @@ func TestHandler_Play(t *testing.T) {
 	assert.Equal(t, false, payload.Paused)
 }
 
-func TestHandler_Play_NotAdmin(t *testing.T) {
-	username := "username"
-	sessionID := "sessionID"
-	userID := user.GenerateUserID(username, sessionID)
-
-	testUser, err := user.New(username, sessionID)
-	assert.NoError(t, err)
-
-	var userCollection db.UserCollection
-	userCollection = &mocks.UserCollection{}
-
-	userCollection.(*mocks.UserCollection).
-		On("GetUserByID", context.TODO(), userID).
-		Return(
-			testUser,
-			nil,
-		)
-
-	// set up songCollection mock
-	var sessionCollection db.SessionCollection
-	sessionCollection = &mocks.SessionCollection{}
-
-	sessionCollection.(*mocks.SessionCollection).
-		On("SetLastUpdated", context.TODO(), sessionID).
-		Return()
-
-	eventBus := events.NewEventBus()
-	eventBus.Start()
-	defer eventBus.Stop()
-
-	handler := &handler{
-		eventBus:          eventBus,
-		UserCollection:    userCollection,
-		SessionCollection: sessionCollection,
-	}
-
-	playerHandler := PlayerHandler(handler)
-
-	// set up http request
-	req, err := http.NewRequest(
-		"POST",
-		fmt.Sprintf("/users/%v/player/play", username),
-		nil,
-	)
-	if err != nil {
-		t.Fatal(err)
-	}
-	req = mux.SetURLVars(req, map[string]string{
-		"username": username,
-	})
-	req.Header.Set("Session", sessionID)
-	rr := httptest.NewRecorder()
-
-	// call handler func
-	playerHandler.Play(rr, req)
-	assert.Equal(t, rr.Code, http.StatusUnauthorized)
-
-	var response FrontendError
-	err = json.NewDecoder(rr.Body).Decode(&response)
-	assert.NoError(t, err)
-
-	assert.Equal(t, ActionNotAllowedError, response)
-}
-
-func TestHandler_Play_NoUserWithID(t *testing.T) {
-	username := "username"
-	sessionID := "sessionID"
-	userID := user.GenerateUserID(username, sessionID)
-
-	var userCollection db.UserCollection
-	userCollection = &mocks.UserCollection{}
-
-	userCollection.(*mocks.UserCollection).
-		On("GetUserByID", context.TODO(), userID).
-		Return(
-			nil,
-			db.ErrNoUserWithID,
-		)
-
-	// set up songCollection mock
-	var sessionCollection db.SessionCollection
-	sessionCollection = &mocks.SessionCollection{}
-
-	sessionCollection.(*mocks.SessionCollection).
-		On("SetLastUpdated", context.TODO(), sessionID).
-		Return()
-
-	eventBus := events.NewEventBus()
-	eventBus.Start()
-	defer eventBus.Stop()
-
-	handler := &handler{
-		eventBus:          eventBus,
-		UserCollection:    userCollection,
-		SessionCollection: sessionCollection,
-	}
-
-	playerHandler := PlayerHandler(handler)
-
-	// set up http request
-	req, err := http.NewRequest(
-		"POST",
-		fmt.Sprintf("/users/%v/player/play", username),
-		nil,
-	)
-	if err != nil {
-		t.Fatal(err)
-	}
-	req = mux.SetURLVars(req, map[string]string{
-		"username": username,
-	})
-	req.Header.Set("Session", sessionID)
-	rr := httptest.NewRecorder()
-
-	// call handler func
-	playerHandler.Play(rr, req)
-	assert.Equal(t, rr.Code, http.StatusUnauthorized)
-
-	var response FrontendError
-	err = json.NewDecoder(rr.Body).Decode(&response)
-	assert.NoError(t, err)
-
-	assert.Equal(t, RequestNotAuthorizedError, response)
-}
-
-func TestHandler_Play_InternalError(t *testing.T) {
-	username := "username"
-	sessionID := "sessionID"
-	userID := user.GenerateUserID(username, sessionID)
-
-	var userCollection db.UserCollection
-	userCollection = &mocks.UserCollection{}
-
-	userCollection.(*mocks.UserCollection).
-		On("GetUserByID", context.TODO(), userID).
-		Return(
-			nil,
-			errors.New("test"),
-		)
-
-	// set up songCollection mock
-	var sessionCollection db.SessionCollection
-	sessionCollection = &mocks.SessionCollection{}
-
-	sessionCollection.(*mocks.SessionCollection).
-		On("SetLastUpdated", context.TODO(), sessionID).
-		Return()
-
-	handler := &handler{
-		UserCollection:    userCollection,
-		SessionCollection: sessionCollection,
-	}
-
-	playerHandler := PlayerHandler(handler)
-
-	// set up http request
-	req, err := http.NewRequest(
-		"POST",
-		fmt.Sprintf("/users/%v/player/play", username),
-		nil,
-	)
-	if err != nil {
-		t.Fatal(err)
-	}
-	req = mux.SetURLVars(req, map[string]string{
-		"username": username,
-	})
-	req.Header.Set("Session", sessionID)
-	rr := httptest.NewRecorder()
-
-	// call handler func
-	playerHandler.Play(rr, req)
-	assert.Equal(t, rr.Code, http.StatusInternalServerError)
-
-	var response FrontendError
-	err = json.NewDecoder(rr.Body).Decode(&response)
-	assert.NoError(t, err)
-
-	assert.Equal(t, InternalServerError, response)
-}
-
 func TestHandler_Pause(t *testing.T) {
 	username := "username"
 	sessionID := "sessionID"
@@ -443,70 +263,6 @@ func TestHandler_Skip(t *testing.T) {
 	assert.True(t, ok)
 }
 
-func TestHandler_Skip_NotAdmin(t *testing.T) {
-	username := "username"
-	sessionID := "sessionID"
-	userID := user.GenerateUserID(username, sessionID)
-
-	testUser, err := user.New(username, sessionID)
-	assert.NoError(t, err)
-
-	var userCollection db.UserCollection
-	userCollection = &mocks.UserCollection{}
-
-	userCollection.(*mocks.UserCollection).
-		On("GetUserByID", context.TODO(), userID).
-		Return(
-			testUser,
-			nil,
-		)
-
-	// set up songCollection mock
-	var sessionCollection db.SessionCollection
-	sessionCollection = &mocks.SessionCollection{}
-
-	sessionCollection.(*mocks.SessionCollection).
-		On("SetLastUpdated", context.TODO(), sessionID).
-		Return()
-
-	eventBus := events.NewEventBus()
-	eventBus.Start()
-	defer eventBus.Stop()
-
-	handler := &handler{
-		eventBus:          eventBus,
-		UserCollection:    userCollection,
-		SessionCollection: sessionCollection,
-	}
-
-	playerHandler := PlayerHandler(handler)
-
-	// set up http request
-	req, err := http.NewRequest(
-		"POST",
-		fmt.Sprintf("/users/%v/player/skip", username),
-		nil,
-	)
-	if err != nil {
-		t.Fatal(err)
-	}
-	req = mux.SetURLVars(req, map[string]string{
-		"username": username,
-	})
-	req.Header.Set("Session", sessionID)
-	rr := httptest.NewRecorder()
-
-	// call handler func
-	playerHandler.Skip(rr, req)
-	assert.Equal(t, rr.Code, http.StatusUnauthorized)
-
-	var response FrontendError
-	err = json.NewDecoder(rr.Body).Decode(&response)
-	assert.NoError(t, err)
-
-	assert.Equal(t, ActionNotAllowedError, response)
-}
-
 func TestHandler_Seek(t *testing.T) {
 	username := "username"
 	sessionID := "sessionID"
@@ -591,24 +347,14 @@ func TestHandler_Seek(t *testing.T) {
 	assert.Equal(t, progress, payload.Progress)
 }
 
-func TestHandler_Seek_NotAdmin(t *testing.T) {
+func TestHandler_Seek_UrlMalformed(t *testing.T) {
 	username := "username"
 	sessionID := "sessionID"
-	userID := user.GenerateUserID(username, sessionID)
-	progress := time.Second * 30
-
-	testUser, err := user.New(username, sessionID)
-	assert.NoError(t, err)
-
-	var userCollection db.UserCollection
-	userCollection = &mocks.UserCollection{}
+	progress := "123malformed"
 
-	userCollection.(*mocks.UserCollection).
-		On("GetUserByID", context.TODO(), userID).
-		Return(
-			testUser,
-			nil,
-		)
+	eventBus := events.NewEventBus()
+	eventBus.Start()
+	defer eventBus.Stop()
 
 	// set up songCollection mock
 	var sessionCollection db.SessionCollection
@@ -618,13 +364,8 @@ func TestHandler_Seek_NotAdmin(t *testing.T) {
 		On("SetLastUpdated", context.TODO(), sessionID).
 		Return()
 
-	eventBus := events.NewEventBus()
-	eventBus.Start()
-	defer eventBus.Stop()
-
 	handler := &handler{
 		eventBus:          eventBus,
-		UserCollection:    userCollection,
 		SessionCollection: sessionCollection,
 	}
 
@@ -633,7 +374,7 @@ func TestHandler_Seek_NotAdmin(t *testing.T) {
 	// set up http request
 	req, err := http.NewRequest(
 		"POST",
-		fmt.Sprintf("/users/%v/player/seek/%v", username, strconv.Itoa(int(progress.Milliseconds()))),
+		fmt.Sprintf("/users/%v/player/seek/%v", username, progress),
 		nil,
 	)
 	if err != nil {
@@ -641,69 +382,80 @@ func TestHandler_Seek_NotAdmin(t *testing.T) {
 	}
 	req = mux.SetURLVars(req, map[string]string{
 		"username":    username,
-		"position_ms": strconv.Itoa(int(progress.Milliseconds())),
+		"position_ms": progress,
 	})
 	req.Header.Set("Session", sessionID)
 	rr := httptest.NewRecorder()
 
 	// call handler func
 	playerHandler.Seek(rr, req)
-	assert.Equal(t, rr.Code, http.StatusUnauthorized)
+	assert.Equal(t, rr.Code, http.StatusBadRequest)
 
 	var response FrontendError
 	err = json.NewDecoder(rr.Body).Decode(&response)
 	assert.NoError(t, err)
 
-	assert.Equal(t, ActionNotAllowedError, response)
+	assert.Equal(t, RequestUrlMalformedError, response)
 }
 
-func TestHandler_Seek_UrlMalformed(t *testing.T) {
+func TestHandler_VoteSkip(t *testing.T) {
 	username := "username"
 	sessionID := "sessionID"
-	progress := "123malformed"
-
-	eventBus := events.NewEventBus()
-	eventBus.Start()
-	defer eventBus.Stop()
+	currentSong := &song.Model{ID: "song_id", Duration: 60000}
 
-	// set up songCollection mock
 	var sessionCollection db.SessionCollection
 	sessionCollection = &mocks.SessionCollection{}
 
 	sessionCollection.(*mocks.SessionCollection).
-		On("SetLastUpdated", context.TODO(), sessionID).
+		On("SetLastUpdated", context.Background(), sessionID).
 		Return()
 
+	var playerCollection db.PlayerCollection
+	playerCollection = &mocks.PlayerCollection{}
+
+	playerCollection.(*mocks.PlayerCollection).
+		On("GetPlayer", context.Background(), sessionID).
+		Return(&player.Player{CurrentSong: currentSong, SongStart: time.Now()}, nil)
+
+	playerCollection.(*mocks.PlayerCollection).
+		On("AddSkipVote", context.Background(), sessionID, currentSong.ID, username).
+		Return(&player.Player{CurrentSong: currentSong, SkipVotes: []string{"other", username}}, nil)
+
+	eventBus := events.NewEventBus()
+	eventBus.Start()
+	defer eventBus.Stop()
+
 	handler := &handler{
 		eventBus:          eventBus,
 		SessionCollection: sessionCollection,
+		PlayerCollection:  playerCollection,
 	}
-
 	playerHandler := PlayerHandler(handler)
 
+	sub := eventBus.Subscribe([]events.EventType{playerctrl.VoteSkipEvent}, []events.GroupID{events.GroupID(sessionID)})
+	defer eventBus.Unsubscribe(sub)
+
 	// set up http request
 	req, err := http.NewRequest(
 		"POST",
-		fmt.Sprintf("/users/%v/player/seek/%v", username, progress),
+		fmt.Sprintf("/users/%v/player/voteSkip", username),
 		nil,
 	)
 	if err != nil {
 		t.Fatal(err)
 	}
 	req = mux.SetURLVars(req, map[string]string{
-		"username":    username,
-		"position_ms": progress,
+		"username": username,
 	})
 	req.Header.Set("Session", sessionID)
 	rr := httptest.NewRecorder()
 
 	// call handler func
-	playerHandler.Seek(rr, req)
-	assert.Equal(t, rr.Code, http.StatusBadRequest)
-
-	var response FrontendError
-	err = json.NewDecoder(rr.Body).Decode(&response)
-	assert.NoError(t, err)
+	playerHandler.VoteSkip(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
 
-	assert.Equal(t, RequestUrlMalformedError, response)
+	ev := <-sub.Channel
+	payload, ok := ev.Data.(playerctrl.VoteSkipPayload)
+	assert.True(t, ok)
+	assert.Equal(t, playerctrl.VoteSkipPayload{SongID: currentSong.ID, Votes: 2}, payload)
 }
@@ -143,6 +143,7 @@ func (h *handler) deleteSessionAndUsers(ctx context.Context, sessionID string) {
 	if err != nil {
 		log.Errorf("%v: %v", msg, err)
 	} else {
+		h.eventBus.Publish(playerctrl.SessionDeletedEvent, events.GroupID(sessionID), playerctrl.SessionDeletedPayload{})
 		log.Infof("%v: successfully deleted session [%v]", msg, sessionID)
 	}
 }
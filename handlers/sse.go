@@ -3,10 +3,14 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
 	"time"
 
+	"github.com/encore-fm/backend/config"
+	"github.com/encore-fm/backend/db"
 	"github.com/encore-fm/backend/playerctrl"
 	"github.com/encore-fm/backend/user"
 
@@ -23,6 +27,30 @@ type SSEHandler interface {
 
 var _ SSEHandler = (*handler)(nil)
 
+// event types forwarded to sse clients besides the playlist events
+var sseEventTypes = []events.EventType{
+	sse.PlayerStateChange,
+	sse.UserListChange,
+	sse.UserSynchronizedChange,
+	sse.SessionSettingsChange,
+	sse.UserKicked,
+	sse.SkipVoteChange,
+	sse.UserNotification,
+}
+
+// fullPlaylistMode is the value of the playlist query parameter that opts in to receiving the
+// full song list on every change instead of the playlist patches
+const fullPlaylistMode = "full"
+
+// streamEventTypes returns the event types forwarded to a client
+func streamEventTypes(fullPlaylist bool) []events.EventType {
+	types := append([]events.EventType{}, sseEventTypes...)
+	if fullPlaylist {
+		return append(types, sse.PlaylistChange)
+	}
+	return append(types, sse.PlaylistPatchTypes...)
+}
+
 // This Broker method handles and HTTP request at the "/events/{username}/{session_id}" URL.
 func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
@@ -40,23 +68,34 @@ func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// subscribe to changes
-	sub := h.eventBus.Subscribe(
-		[]events.EventType{sse.PlaylistChange, sse.PlayerStateChange, sse.UserListChange, sse.UserSynchronizedChange},
-		[]events.GroupID{events.GroupID(sessionID)},
-	)
+	// EventSource can not set headers, so browsers pass the secret as query parameter
+	secret := r.URL.Query().Get("secret")
+	if secret == "" {
+		secret = r.Header.Get("Authorization")
+	}
+	if _, reqErr := authorizeUser(
+		context.Background(),
+		h.UserCollection,
+		username,
+		sessionID,
+		secret,
+		staticRole(user.RoleGuest),
+		false,
+	); reqErr != nil {
+		reqErr.write(w, msg)
+		return
+	}
 
-	// register active sse connection
-	_, err := h.UserCollection.AddSSEConnection(ctx, userID)
-	if err != nil {
-		log.Errorf("%v: %v", msg, err)
+	if err := h.connect(ctx, msg, userID, sessionID); err != nil {
+		status, level, frontendErr := connectError(err)
+		handleError(w, status, level, msg, err, frontendErr)
+		return
 	}
-	// publish an sse connection established event to sync user
-	h.eventBus.Publish(
-		playerctrl.SSEConnectionEvent,
-		events.GroupID(sessionID),
-		playerctrl.SSEConnectionPayload{UserID: userID, ConnectionEstablished: true},
-	)
+
+	// subscribe to changes
+	eventTypes := streamEventTypes(r.URL.Query().Get("playlist") == fullPlaylistMode)
+	groups := streamGroups(sessionID, username)
+	sub := h.eventBus.Subscribe(eventTypes, groups)
 
 	// Listen to the closing of the http connection
 	go func() {
@@ -64,20 +103,7 @@ func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		// Remove this client from the map of attached clients
 		// when `EventHandler` exits.
 		h.eventBus.Unsubscribe(sub)
-
-		// unregister sse connection
-		numberOfConnections, err := h.UserCollection.RemoveSSEConnection(context.Background(), userID)
-		if err != nil {
-			log.Errorf("%v: %v", msg, err)
-		}
-		// desynchronize user if no more connections are active
-		if numberOfConnections == 0 {
-			h.eventBus.Publish(
-				playerctrl.SSEConnectionEvent,
-				events.GroupID(sessionID),
-				playerctrl.SSEConnectionPayload{UserID: userID, ConnectionEstablished: false},
-			)
-		}
+		h.disconnect(msg, userID, sessionID)
 
 		log.Info("[sse] HTTP connection just closed")
 	}()
@@ -88,30 +114,244 @@ func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Connection", "keep-alive")
 	w.Header().Set("Transfer-Encoding", "chunked")
 
-	h.sendSessionInfo(ctx, w, f, msg, sessionID)
+	// forward sends an event to the client, it returns false if the stream has to end
+	forward := func(event events.Event) bool {
+		// streams whose writes fail are closed
+		if err := sendEvent(w, f, event.ID, event.Type, event.GroupID, event.Data); err != nil {
+			log.Infof("%v: %v", msg, err)
+			return false
+		}
+		return !endsStream(event, username)
+	}
+	heartbeat := func() error {
+		if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+			return err
+		}
+		f.Flush()
+		return nil
+	}
 
-	// Don't close the connection, instead loop endlessly.
-	for {
-		// Read from our messageChan.
-		event, open := <-sub.Channel
-		if !open {
-			// If our messageChan was closed, this means that the client has
-			// disconnected.
+	// reconnecting clients get the events they missed, a snapshot is only needed
+	// if the client is new or the missed events are not buffered anymore.
+	// Events up to covered have been replayed or are part of the snapshot.
+	stopped := false
+	covered, catchUp := h.catchUp(ctx, msg, sessionID, r.Header.Get("Last-Event-ID"), eventTypes, groups)
+	for _, event := range catchUp {
+		if !forward(event) {
+			stopped = true
 			break
 		}
-		sendEvent(w, f, msg, event.Type, event.GroupID, event.Data)
+	}
+
+	// Don't close the connection, instead follow the events until the client disconnects.
+	if !stopped {
+		followEvents(msg, config.Conf.Events, sub.Channel, covered, forward, heartbeat)
 	}
 
 	log.Infof(msg, r.URL.Path)
 }
 
-func (h *handler) sendSessionInfo(
-	ctx context.Context,
-	w http.ResponseWriter,
-	f http.Flusher,
+// connect registers an event stream of a user, users with a stream are synchronized with the player.
+// ErrTooManyStreams is returned if the stream exceeds the limits of the user or session,
+// db.ErrNoUserWithID if the user is not a member of the session (anymore).
+func (h *handler) connect(ctx context.Context, msg, userID, sessionID string) error {
+	connections, err := h.UserCollection.AddSSEConnection(ctx, userID)
+	if err != nil {
+		return err
+	}
+	limits := config.Conf.Events
+	if limits.MaxStreamsPerUser > 0 && connections > limits.MaxStreamsPerUser {
+		h.release(msg, userID)
+		return ErrTooManyStreams
+	}
+
+	if limits.MaxStreamsPerSession > 0 {
+		connections, err := h.UserCollection.CountSSEConnections(ctx, sessionID)
+		if err != nil {
+			log.Errorf("%v: %v", msg, err)
+		} else if connections > limits.MaxStreamsPerSession {
+			h.release(msg, userID)
+			return ErrTooManyStreams
+		}
+	}
+
+	// publish an sse connection established event to sync user
+	h.eventBus.Publish(
+		playerctrl.SSEConnectionEvent,
+		events.GroupID(sessionID),
+		playerctrl.SSEConnectionPayload{UserID: userID, ConnectionEstablished: true},
+	)
+	return nil
+}
+
+// connectError returns the response to a stream that could not be connected
+func connectError(err error) (int, log.Level, FrontendError) {
+	switch {
+	case errors.Is(err, ErrTooManyStreams):
+		return http.StatusTooManyRequests, log.InfoLevel, TooManyStreamsError
+	case errors.Is(err, db.ErrNoUserWithID):
+		return http.StatusUnauthorized, log.WarnLevel, RequestNotAuthorizedError
+	default:
+		return http.StatusInternalServerError, log.ErrorLevel, InternalServerError
+	}
+}
+
+// release unregisters a rejected stream, the user has not been synchronized because of it
+func (h *handler) release(msg, userID string) {
+	if _, err := h.UserCollection.RemoveSSEConnection(context.Background(), userID); err != nil {
+		log.Errorf("%v: %v", msg, err)
+	}
+}
+
+// followEvents forwards the live events of a stream until forward returns false, the subscription ends
+// or no event has been forwarded for the idle timeout. Events up to covered have been sent already.
+// heartbeat keeps idle connections open, the stream ends if it fails.
+func followEvents(
 	msg string,
-	sessionID string,
+	limits *config.EventsConfig,
+	channel <-chan events.Event,
+	covered uint64,
+	forward func(events.Event) bool,
+	heartbeat func() error,
 ) {
+	// nil channels never fire
+	var heartbeats <-chan time.Time
+	if limits.HeartbeatIntervalInS > 0 {
+		ticker := time.NewTicker(time.Duration(limits.HeartbeatIntervalInS) * time.Second)
+		defer ticker.Stop()
+		heartbeats = ticker.C
+	}
+	idleTimeout := time.Duration(limits.IdleTimeoutInS) * time.Second
+	var idle <-chan time.Time
+	var idleTimer *time.Timer
+	if idleTimeout > 0 {
+		idleTimer = time.NewTimer(idleTimeout)
+		defer idleTimer.Stop()
+		idle = idleTimer.C
+	}
+
+	for {
+		select {
+		case event, open := <-channel:
+			// the channel is closed when the client has disconnected or an event could not be delivered,
+			// reconnecting clients replay the events after their last event id
+			if !open {
+				return
+			}
+			// the subscription starts before the catch up, so it can repeat events covered by it
+			if event.ID <= covered {
+				continue
+			}
+			if !forward(event) {
+				return
+			}
+			if idleTimer != nil {
+				if !idleTimer.Stop() {
+					<-idleTimer.C
+				}
+				idleTimer.Reset(idleTimeout)
+			}
+
+		case <-heartbeats:
+			if err := heartbeat(); err != nil {
+				log.Infof("%v: heartbeat: %v", msg, err)
+				return
+			}
+
+		case <-idle:
+			log.Infof("%v: closing idle stream", msg)
+			return
+		}
+	}
+}
+
+// disconnect unregisters an event stream of a user
+func (h *handler) disconnect(msg, userID, sessionID string) {
+	numberOfConnections, err := h.UserCollection.RemoveSSEConnection(context.Background(), userID)
+	if err != nil {
+		log.Errorf("%v: %v", msg, err)
+	}
+	// desynchronize user if no more connections are active
+	if numberOfConnections == 0 {
+		h.eventBus.Publish(
+			playerctrl.SSEConnectionEvent,
+			events.GroupID(sessionID),
+			playerctrl.SSEConnectionPayload{UserID: userID, ConnectionEstablished: false},
+		)
+	}
+}
+
+// streamGroups returns the groups a stream of a user subscribes to,
+// the session and the user's own group for events that are only meant for them
+func streamGroups(sessionID, username string) []events.GroupID {
+	return []events.GroupID{events.GroupID(sessionID), events.UserGroupID(sessionID, username)}
+}
+
+// endsStream returns true if the stream of the user ends after the event
+func endsStream(event events.Event, username string) bool {
+	// the stream of a kicked user ends after notifying the client
+	kicked, ok := event.Data.(sse.UserKickedPayload)
+	return ok && kicked.Username == username
+}
+
+// catchUp returns the events a client has to receive before following the live events of a session.
+// These are the events published after lastEventID if they are still buffered, a snapshot of the session otherwise.
+// covered is the id of the last event that is included.
+func (h *handler) catchUp(
+	ctx context.Context,
+	msg, sessionID, lastEventID string,
+	eventTypes []events.EventType,
+	groups []events.GroupID,
+) (covered uint64, catchUp []events.Event) {
+	if lastID, missed, ok := h.replayEvents(sessionID, lastEventID, eventTypes, groups); ok {
+		covered = lastID
+		for _, event := range missed {
+			covered = event.ID
+		}
+		return covered, missed
+	}
+	covered = h.eventBus.LastID(events.GroupID(sessionID))
+	return covered, h.sessionSnapshot(ctx, msg, sessionID, covered, eventTypes)
+}
+
+// replayEvents returns the events of the given types and groups of a session published after lastEventID.
+// ok is false if lastEventID is missing or the events are not buffered anymore.
+func (h *handler) replayEvents(
+	sessionID, lastEventID string,
+	eventTypes []events.EventType,
+	groups []events.GroupID,
+) (lastID uint64, missed []events.Event, ok bool) {
+	if lastEventID == "" {
+		return 0, nil, false
+	}
+	lastID, err := strconv.ParseUint(lastEventID, 10, 64)
+	if err != nil {
+		return 0, nil, false
+	}
+
+	buffered, ok := h.eventBus.Replay(events.GroupID(sessionID), lastID)
+	if !ok {
+		return 0, nil, false
+	}
+	missed = make([]events.Event, 0, len(buffered))
+	for _, event := range buffered {
+		// the session's buffer holds the events of all its users
+		if containsType(eventTypes, event.Type) && containsGroup(groups, event.GroupID) {
+			missed = append(missed, event)
+		}
+	}
+	return lastID, missed, true
+}
+
+// sessionSnapshot returns the current state of the session as events,
+// they carry the id of the last event that is included in the snapshot.
+// The playlist is sent in full if the client receives full playlists, as a playlist snapshot otherwise.
+func (h *handler) sessionSnapshot(
+	ctx context.Context,
+	msg, sessionID string,
+	eventID uint64,
+	eventTypes []events.EventType,
+) []events.Event {
 	playr, err := h.PlayerCollection.GetPlayer(ctx, sessionID)
 	if err != nil {
 		log.Errorf("%v: %v", msg, err)
@@ -132,6 +372,11 @@ func (h *handler) sendSessionInfo(
 		log.Errorf("%v: %v", msg, err)
 	}
 
+	settings, err := h.SessionCollection.GetSettings(ctx, sessionID)
+	if err != nil {
+		log.Errorf("%v: %v", msg, err)
+	}
+
 	playerState := sse.PlayerStateChangePayload{
 		CurrentSong: playr.CurrentSong,
 		IsPlaying:   !playr.Paused,
@@ -139,28 +384,63 @@ func (h *handler) sendSessionInfo(
 		Timestamp:   time.Now(),
 	}
 
-	sendEvent(w, f, msg, sse.PlayerStateChange, events.GroupID(sessionID), playerState)
-	sendEvent(w, f, msg, sse.PlaylistChange, events.GroupID(sessionID), playlist)
-	sendEvent(w, f, msg, sse.UserListChange, events.GroupID(sessionID), userList)
+	groupID := events.GroupID(sessionID)
+	playlistEvent := events.Event{ID: eventID, Type: sse.PlaylistChange, GroupID: groupID, Data: playlist}
+	if !containsType(eventTypes, sse.PlaylistChange) {
+		playlistEvent.Type = sse.PlaylistSnapshot
+		playlistEvent.Data = h.playlists.Snapshot(sessionID, playlist)
+	}
+	snapshot := []events.Event{
+		{ID: eventID, Type: sse.PlayerStateChange, GroupID: groupID, Data: playerState},
+		playlistEvent,
+		{ID: eventID, Type: sse.UserListChange, GroupID: groupID, Data: userList},
+	}
+	if settings != nil {
+		snapshot = append(snapshot, events.Event{ID: eventID, Type: sse.SessionSettingsChange, GroupID: groupID, Data: settings})
+	}
+	return snapshot
+}
+
+func containsType(eventTypes []events.EventType, eventType events.EventType) bool {
+	for _, t := range eventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+func containsGroup(groups []events.GroupID, groupID events.GroupID) bool {
+	for _, g := range groups {
+		if g == groupID {
+			return true
+		}
+	}
+	return false
 }
 
 func sendEvent(
 	w http.ResponseWriter,
 	f http.Flusher,
-	msg string,
+	eventID uint64,
 	eventType events.EventType,
 	groupID events.GroupID,
 	payload interface{},
-) {
+) error {
 	data, err := json.Marshal(payload)
 	if err != nil {
-		log.Errorf(msg, err)
+		return err
 	}
 
 	// Write to the ResponseWriter, `w`.
-	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", eventType, data)
+	// the id lets clients resume the stream with the Last-Event-ID header, 0 means no event was published yet
+	if eventID > 0 {
+		_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", eventID, eventType, data)
+	} else {
+		_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", eventType, data)
+	}
 	if err != nil {
-		log.Errorf(msg, err)
+		return err
 	}
 
 	log.Infof("[sse] sent event: type=%v group=%v", eventType, groupID)
@@ -168,4 +448,5 @@ func sendEvent(
 	// Flush the response. This is only possible if
 	// the response supports streaming.
 	f.Flush()
+	return nil
 }
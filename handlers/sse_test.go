@@ -0,0 +1,307 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/encore-fm/backend/config"
+	"github.com/encore-fm/backend/db"
+	"github.com/encore-fm/backend/db/mocks"
+	"github.com/encore-fm/backend/events"
+	"github.com/encore-fm/backend/player"
+	"github.com/encore-fm/backend/session"
+	"github.com/encore-fm/backend/song"
+	"github.com/encore-fm/backend/sse"
+	"github.com/encore-fm/backend/user"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// test that idle streams receive heartbeats and are closed after the idle timeout
+func TestFollowEvents_Idle(t *testing.T) {
+	limits := &config.EventsConfig{HeartbeatIntervalInS: 1, IdleTimeoutInS: 2}
+	channel := make(chan events.Event, 2)
+	channel <- events.Event{ID: 1}
+	channel <- events.Event{ID: 2}
+
+	forwarded := make([]uint64, 0)
+	forward := func(event events.Event) bool {
+		forwarded = append(forwarded, event.ID)
+		return true
+	}
+	heartbeats := 0
+	heartbeat := func() error {
+		heartbeats++
+		return nil
+	}
+
+	start := time.Now()
+	followEvents("test", limits, channel, 1, forward, heartbeat)
+
+	assert.Equal(t, []uint64{2}, forwarded)
+	assert.GreaterOrEqual(t, heartbeats, 1)
+	assert.GreaterOrEqual(t, time.Since(start).Seconds(), 2.0)
+}
+
+// test that a stream ends as soon as a write fails
+func TestFollowEvents_WriteFailure(t *testing.T) {
+	limits := &config.EventsConfig{HeartbeatIntervalInS: 1}
+	channel := make(chan events.Event)
+
+	heartbeat := func() error {
+		return errors.New("broken pipe")
+	}
+	done := make(chan struct{})
+	go func() {
+		followEvents("test", limits, channel, 0, func(events.Event) bool { return true }, heartbeat)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(3 * time.Second):
+		t.Fatal("stream has not been closed")
+	}
+}
+
+// test that a stream ends when the subscription is closed
+func TestFollowEvents_Unsubscribed(t *testing.T) {
+	limits := &config.EventsConfig{}
+	channel := make(chan events.Event)
+	close(channel)
+
+	followEvents("test", limits, channel, 0, func(events.Event) bool { return true }, func() error { return nil })
+}
+
+// test that only members of the session with the right secret can open a stream
+func TestHandler_ServeHTTP_NotAuthorized(t *testing.T) {
+	sessionID := "session_id"
+
+	member, err := user.New("member", sessionID)
+	assert.NoError(t, err)
+
+	// set up userCollection mock
+	var userCollection db.UserCollection
+	userCollection = &mocks.UserCollection{}
+
+	userCollection.(*mocks.UserCollection).
+		On("GetUserByID", context.Background(), member.ID).
+		Return(member, nil)
+	userCollection.(*mocks.UserCollection).
+		On("GetUserByID", context.Background(), user.GenerateUserID("stranger", sessionID)).
+		Return(nil, fmt.Errorf("[db] get user by id: %w", db.ErrNoUserWithID))
+
+	eventBus := events.NewEventBus()
+	// create handler with mock collections
+	handler := &handler{
+		UserCollection: userCollection,
+		eventBus:       eventBus,
+	}
+
+	testCases := []struct {
+		name     string
+		username string
+		query    string
+		header   string
+	}{
+		{name: "not a member", username: "stranger", query: "?secret=" + member.Secret},
+		{name: "no secret", username: member.Username},
+		{name: "wrong secret", username: member.Username, query: "?secret=wrong"},
+		{name: "wrong header", username: member.Username, header: "wrong"},
+	}
+
+	for _, tc := range testCases {
+		req, err := http.NewRequest("GET", fmt.Sprintf("/events/%v/%v%v", tc.username, sessionID, tc.query), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req = mux.SetURLVars(req, map[string]string{
+			"username":   tc.username,
+			"session_id": sessionID,
+		})
+		if tc.header != "" {
+			req.Header.Set("Authorization", tc.header)
+		}
+		rr := httptest.NewRecorder()
+
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code, tc.name)
+
+		var frontendErr FrontendError
+		err = json.NewDecoder(rr.Body).Decode(&frontendErr)
+		assert.NoError(t, err, tc.name)
+		assert.Equal(t, RequestNotAuthorizedError, frontendErr, tc.name)
+	}
+	userCollection.(*mocks.UserCollection).AssertNotCalled(t, "AddSSEConnection", mock.Anything, mock.Anything)
+}
+
+// test that events covered by the catch up are skipped and the stream ends with its subscription
+func TestFollowEvents_Covered(t *testing.T) {
+	limits := &config.EventsConfig{}
+	channel := make(chan events.Event, 3)
+	channel <- events.Event{ID: 3}
+	channel <- events.Event{ID: 4}
+	channel <- events.Event{ID: 5}
+	close(channel)
+
+	forwarded := make([]uint64, 0)
+	forward := func(event events.Event) bool {
+		forwarded = append(forwarded, event.ID)
+		return true
+	}
+	followEvents("test", limits, channel, 4, forward, func() error { return nil })
+
+	assert.Equal(t, []uint64{5}, forwarded)
+}
+
+// newCatchUpHandler returns a handler whose session has an empty player and song list and no users
+func newCatchUpHandler(sessionID string) *handler {
+	userCollection := &mocks.UserCollection{}
+	userCollection.On("ListUsers", mock.Anything, sessionID).Return([]*user.ListElement{}, nil)
+	songCollection := &mocks.SongCollection{}
+	songCollection.On("ListSongs", mock.Anything, sessionID).Return([]*song.Model{}, nil)
+	playerCollection := &mocks.PlayerCollection{}
+	playerCollection.On("GetPlayer", mock.Anything, sessionID).Return(player.New(), nil)
+	sessionCollection := &mocks.SessionCollection{}
+	sessionCollection.On("GetSettings", mock.Anything, sessionID).Return(session.DefaultSettings(), nil)
+
+	eventBus := events.NewEventBus()
+	eventBus.Record(sse.ClientEventTypes()...)
+	eventBus.Start()
+	return &handler{
+		eventBus:          eventBus,
+		playlists:         sse.NewPlaylistTracker(eventBus),
+		UserCollection:    userCollection,
+		SongCollection:    songCollection,
+		PlayerCollection:  playerCollection,
+		SessionCollection: sessionCollection,
+	}
+}
+
+// publishRecorded publishes events and returns them once they have been numbered
+func publishRecorded(t *testing.T, eventBus events.EventBus, published ...events.Event) []events.Event {
+	types := make([]events.EventType, 0, len(published))
+	groups := make([]events.GroupID, 0, len(published))
+	for _, ev := range published {
+		types = append(types, ev.Type)
+		groups = append(groups, ev.GroupID)
+	}
+	sub := eventBus.Subscribe(types, groups)
+	defer eventBus.Unsubscribe(sub)
+
+	// waits for each event so the subscription never falls behind
+	received := make([]events.Event, 0, len(published))
+	for _, ev := range published {
+		eventBus.Publish(ev.Type, ev.GroupID, ev.Data)
+		select {
+		case ev, ok := <-sub.Channel:
+			if !ok {
+				t.Fatal("subscription has been closed")
+			}
+			received = append(received, ev)
+		case <-time.After(time.Second):
+			t.Fatal("event has not been published")
+		}
+	}
+	return received
+}
+
+// test that reconnecting clients receive the events published after their last event id
+func TestHandler_CatchUp_Replay(t *testing.T) {
+	sessionID := "session_id"
+	h := newCatchUpHandler(sessionID)
+	defer h.eventBus.Stop()
+
+	groupID := events.GroupID(sessionID)
+	published := publishRecorded(t, h.eventBus,
+		events.Event{Type: sse.UserListChange, GroupID: groupID, Data: "first"},
+		events.Event{Type: sse.SkipVoteChange, GroupID: groupID, Data: "second"},
+		events.Event{Type: sse.UserKicked, GroupID: groupID, Data: "third"},
+	)
+
+	eventTypes := streamEventTypes(false)
+	groups := streamGroups(sessionID, "username")
+	lastEventID := strconv.FormatUint(published[0].ID, 10)
+	covered, catchUp := h.catchUp(context.Background(), "test", sessionID, lastEventID, eventTypes, groups)
+
+	assert.Equal(t, published[2].ID, covered)
+	assert.Equal(t, published[1:], catchUp)
+
+	// clients that are up to date receive nothing
+	lastEventID = strconv.FormatUint(published[2].ID, 10)
+	covered, catchUp = h.catchUp(context.Background(), "test", sessionID, lastEventID, eventTypes, groups)
+	assert.Equal(t, published[2].ID, covered)
+	assert.Empty(t, catchUp)
+}
+
+// test that new clients and clients whose missed events are not buffered anymore receive a snapshot
+func TestHandler_CatchUp_Snapshot(t *testing.T) {
+	sessionID := "session_id"
+	h := newCatchUpHandler(sessionID)
+	defer h.eventBus.Stop()
+
+	groupID := events.GroupID(sessionID)
+	overrun := make([]events.Event, events.BufferSize+1)
+	for i := range overrun {
+		overrun[i] = events.Event{Type: sse.SkipVoteChange, GroupID: groupID, Data: i}
+	}
+	published := publishRecorded(t, h.eventBus, overrun...)
+	lastID := published[len(published)-1].ID
+
+	testCases := []struct {
+		name        string
+		lastEventID string
+	}{
+		{name: "new client"},
+		{name: "not a number", lastEventID: "abc"},
+		{name: "unknown id", lastEventID: strconv.FormatUint(lastID+1, 10)},
+		{name: "overrun buffer", lastEventID: strconv.FormatUint(published[0].ID-1, 10)},
+	}
+
+	for _, tc := range testCases {
+		eventTypes := streamEventTypes(false)
+		groups := streamGroups(sessionID, "username")
+		covered, catchUp := h.catchUp(context.Background(), "test", sessionID, tc.lastEventID, eventTypes, groups)
+
+		assert.Equal(t, lastID, covered, tc.name)
+		types := make([]events.EventType, 0, len(catchUp))
+		for _, ev := range catchUp {
+			types = append(types, ev.Type)
+			// the snapshot includes all events up to the last one
+			assert.Equal(t, lastID, ev.ID, tc.name)
+		}
+		expected := []events.EventType{sse.PlayerStateChange, sse.PlaylistSnapshot, sse.UserListChange, sse.SessionSettingsChange}
+		assert.Equal(t, expected, types, tc.name)
+	}
+}
+
+// test that the notifications of a user are not replayed to the other users of the session
+func TestHandler_CatchUp_UserNotification(t *testing.T) {
+	sessionID := "session_id"
+	h := newCatchUpHandler(sessionID)
+	defer h.eventBus.Stop()
+
+	groupID := events.GroupID(sessionID)
+	published := publishRecorded(t, h.eventBus,
+		events.Event{Type: sse.UserListChange, GroupID: groupID, Data: "before"},
+		events.Event{Type: sse.UserNotification, GroupID: events.UserGroupID(sessionID, "alice"), Data: "private"},
+		events.Event{Type: sse.UserListChange, GroupID: groupID, Data: "public"},
+	)
+	lastEventID := strconv.FormatUint(published[0].ID, 10)
+	eventTypes := streamEventTypes(false)
+
+	_, catchUp := h.catchUp(context.Background(), "test", sessionID, lastEventID, eventTypes, streamGroups(sessionID, "bob"))
+	assert.Equal(t, published[2:], catchUp)
+
+	_, catchUp = h.catchUp(context.Background(), "test", sessionID, lastEventID, eventTypes, streamGroups(sessionID, "alice"))
+	assert.Equal(t, published[1:], catchUp)
+}
@@ -2,15 +2,22 @@ package handlers
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"time"
 
+	"github.com/encore-fm/backend/config"
 	"github.com/encore-fm/backend/playerctrl"
+	"github.com/encore-fm/backend/spotifycl"
 	"github.com/encore-fm/backend/sse"
 
 	"github.com/encore-fm/backend/db"
 	"github.com/encore-fm/backend/events"
+	"github.com/encore-fm/backend/player"
+	"github.com/encore-fm/backend/session"
 	"github.com/encore-fm/backend/song"
 	"github.com/encore-fm/backend/user"
 	"github.com/gorilla/mux"
@@ -25,19 +32,32 @@ type UserHandler interface {
 	UserPing(w http.ResponseWriter, r *http.Request)
 	ListUsers(w http.ResponseWriter, r *http.Request)
 	SuggestSong(w http.ResponseWriter, r *http.Request)
+	SuggestSongs(w http.ResponseWriter, r *http.Request)
+	ImportSongs(w http.ResponseWriter, r *http.Request)
 	ListSongs(w http.ResponseWriter, r *http.Request)
+	Search(w http.ResponseWriter, r *http.Request)
+	History(w http.ResponseWriter, r *http.Request)
+	ExportPlaylist(w http.ResponseWriter, r *http.Request)
 	Vote(w http.ResponseWriter, r *http.Request)
 	ClientToken(w http.ResponseWriter, r *http.Request)
 	AuthToken(w http.ResponseWriter, r *http.Request)
 	SessionInfo(w http.ResponseWriter, r *http.Request)
+	ResolveJoinCode(w http.ResponseWriter, r *http.Request)
 	ListFavouriteSongs(w http.ResponseWriter, r *http.Request)
 	SetSyncMode(w http.ResponseWriter, r *http.Request)
 }
 
 var _ UserHandler = (*handler)(nil)
 
+// optional credentials sent with a join request
+type joinCredentials struct {
+	Password string `json:"password"`
+	Invite   string `json:"invite"`
+}
+
 // Join adds user to session
 // - check if session with this id exists
+// - check password or invite
 // - create new user and save in db
 // - create auth url
 func (h *handler) Join(w http.ResponseWriter, r *http.Request) {
@@ -48,6 +68,14 @@ func (h *handler) Join(w http.ResponseWriter, r *http.Request) {
 	username := vars["username"]
 	sessionID := vars["session_id"]
 
+	var credentials joinCredentials
+	if r.Body != nil {
+		if err := json.NewDecoder(r.Body).Decode(&credentials); err != nil && !errors.Is(err, io.EOF) {
+			handleError(w, http.StatusBadRequest, log.WarnLevel, msg, err, RequestBodyMalformedError)
+			return
+		}
+	}
+
 	// check if session with given id exists
 	sess, err := h.SessionCollection.GetSessionByID(ctx, sessionID)
 	if err != nil {
@@ -59,6 +87,24 @@ func (h *handler) Join(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	fingerprint := r.Header.Get("Fingerprint")
+	if sess.IsBanned(username, fingerprint) {
+		handleError(w, http.StatusForbidden, log.InfoLevel, msg, ErrUserBanned, UserBannedError)
+		return
+	}
+
+	// an invite grants access regardless of the session password
+	if credentials.Invite == "" {
+		if sess.Settings != nil && sess.Settings.InviteOnly {
+			handleError(w, http.StatusForbidden, log.InfoLevel, msg, ErrInviteRequired, InviteRequiredError)
+			return
+		}
+		if !sess.CheckPassword(credentials.Password) {
+			handleError(w, http.StatusUnauthorized, log.InfoLevel, msg, ErrWrongPassword, WrongPasswordError)
+			return
+		}
+	}
+
 	// update session time stamp
 	h.SessionCollection.SetLastUpdated(ctx, sessionID)
 
@@ -80,11 +126,43 @@ func (h *handler) Join(w http.ResponseWriter, r *http.Request) {
 		handleError(w, http.StatusInternalServerError, log.ErrorLevel, msg, err, InternalServerError)
 		return
 	}
+	newUser.Fingerprint = fingerprint
+
+	var invite *session.Invite
+	if credentials.Invite != "" {
+		invite, err = h.SessionCollection.RedeemInvite(ctx, sessionID, credentials.Invite)
+		if err != nil {
+			if errors.Is(err, db.ErrInvalidInvite) {
+				handleError(w, http.StatusForbidden, log.InfoLevel, msg, err, InviteRequiredError)
+			} else {
+				handleError(w, http.StatusInternalServerError, log.ErrorLevel, msg, err, InternalServerError)
+			}
+			return
+		}
+	}
 
 	// save user in db
-	if err := h.UserCollection.AddUser(ctx, newUser); err != nil {
+	err = h.UserCollection.AddUser(ctx, newUser)
+	// put user on the waiting list if the session is full
+	if errors.Is(err, db.ErrSessionFull) && sess.WaitingList {
+		newUser.Waiting = true
+		newUser.WaitingSince = time.Now()
+		err = h.UserCollection.AddUser(ctx, newUser)
+	}
+	if err != nil {
+		// a single use invite was not used up if the user could not join
+		if invite != nil && invite.SingleUse {
+			if err := h.SessionCollection.AddInvite(ctx, sessionID, invite); err != nil {
+				log.Errorf("%v: restore invite: %v", msg, err)
+			}
+		}
+
 		if errors.Is(err, db.ErrUsernameTaken) {
 			handleError(w, http.StatusConflict, log.ErrorLevel, msg, err, UserConflictError)
+		} else if errors.Is(err, db.ErrSessionFull) {
+			handleError(w, http.StatusForbidden, log.InfoLevel, msg, err, SessionFullError)
+		} else if errors.Is(err, db.ErrNoSessionWithID) {
+			handleError(w, http.StatusBadRequest, log.ErrorLevel, msg, err, SessionNotFoundError)
 		} else {
 			handleError(w, http.StatusInternalServerError, log.ErrorLevel, msg, err, InternalServerError)
 		}
@@ -133,43 +211,83 @@ func (h *handler) Leave(w http.ResponseWriter, r *http.Request) {
 		handleError(w, http.StatusInternalServerError, log.ErrorLevel, msg, err, InternalServerError)
 		return
 	}
-	// admin should not be allowed the leave his own session (see delete session endpoint)
+	// the owner hands the session on before leaving,
+	// if nobody is left the session has to be deleted instead (see delete session endpoint)
 	if usr.IsAdmin {
-		handleError(w, http.StatusBadRequest, log.WarnLevel, msg, ErrUserIsAdmin, ActionNotAllowedError)
-		return
+		successor, err := h.UserCollection.GetSuccessor(ctx, sessionID, userID)
+		if err != nil {
+			if errors.Is(err, db.ErrNoUserWithID) {
+				handleError(w, http.StatusBadRequest, log.WarnLevel, msg, ErrNoSuccessor, ActionNotAllowedError)
+			} else {
+				handleError(w, http.StatusInternalServerError, log.ErrorLevel, msg, err, InternalServerError)
+			}
+			return
+		}
+		if err := h.UserCollection.TransferOwnership(ctx, userID, successor.ID); err != nil {
+			handleError(w, http.StatusInternalServerError, log.ErrorLevel, msg, err, InternalServerError)
+			return
+		}
+		h.notifyUser(sessionID, successor.Username, sse.NotificationRoleChanged, sse.RoleChangedPayload{Role: user.RoleOwner})
+		log.Infof("%v: [%v] is the new owner of session [%v]", msg, successor.Username, sessionID)
 	}
 
 	// pause the user's spotify client
 	if usr.SpotifySynchronized {
-		clients, err := h.UserCollection.GetSyncedSpotifyClients(ctx, sessionID)
-		if err != nil {
-			log.Errorf("%v: %v", msg, err)
-		}
-		// find user's client
-		for _, client := range clients {
-			if client.ID == userID {
-				spotifyClient := h.spotifyAuthenticator.NewClient(client.AuthToken)
-				err = spotifyClient.Pause()
-				if err != nil {
-					log.Errorf("%v: %v", msg, err)
-				}
-				break
+		h.pauseSpotifyClient(ctx, msg, sessionID, userID)
+	}
+
+	if err := h.removeUser(ctx, msg, sessionID, userID); err != nil {
+		handleError(w, http.StatusInternalServerError, log.ErrorLevel, msg, err, InternalServerError)
+		return
+	}
+}
+
+// pauses the spotify client of a single user
+func (h *handler) pauseSpotifyClient(ctx context.Context, msg, sessionID, userID string) {
+	clients, err := h.UserCollection.GetSyncedSpotifyClients(ctx, sessionID)
+	if err != nil {
+		log.Errorf("%v: %v", msg, err)
+	}
+	// find user's client
+	for _, client := range clients {
+		if client.ID == userID {
+			spotifyClient := h.spotifyAuthenticator.NewClient(client.AuthToken)
+			err = spotifyClient.Pause()
+			if err != nil {
+				log.Errorf("%v: %v", msg, err)
 			}
+			break
 		}
 	}
+}
 
-	err = h.UserCollection.DeleteUser(ctx, userID)
+// deletes a user, hands the freed slot on to the waiting list and notifies the session
+func (h *handler) removeUser(ctx context.Context, msg, sessionID, userID string) error {
+	err := h.UserCollection.DeleteUser(ctx, userID)
 	if err != nil {
-		handleError(w, http.StatusInternalServerError, log.ErrorLevel, msg, err, InternalServerError)
+		return err
 	}
 
-	// get new user list for sse event
+	// the freed slot can be taken by a user on the waiting list
+	admitted, err := h.UserCollection.AdmitWaitingUsers(ctx, sessionID)
+	if err != nil {
+		log.Errorf("%v: %v", msg, err)
+	}
+	for _, usr := range admitted {
+		log.Infof("%v: admitted waiting user [%v]", msg, usr.Username)
+	}
+
+	h.publishUserList(ctx, msg, sessionID)
+	return nil
+}
+
+// sends the session's current user list as sse event
+func (h *handler) publishUserList(ctx context.Context, msg, sessionID string) {
 	userList, err := h.UserCollection.ListUsers(ctx, sessionID)
 	if err != nil {
 		log.Errorf("%v: %v", msg, err)
 	}
 
-	// send sse event that a user has joined a session
 	h.eventBus.Publish(
 		sse.UserListChange,
 		events.GroupID(sessionID),
@@ -254,40 +372,475 @@ func (h *handler) SuggestSong(w http.ResponseWriter, r *http.Request) {
 	songID := vars["song_id"]
 	sessionID := r.Header.Get("Session")
 
+	songInfo, reqErr := h.suggestSong(ctx, msg, sessionID, username, songID)
+	if reqErr != nil {
+		reqErr.write(w, msg)
+		return
+	}
+	jsonResponse(w, songInfo)
+}
+
+// suggestSong adds a song suggested by the user to the song list, for http and websocket clients
+func (h *handler) suggestSong(
+	ctx context.Context,
+	msg, sessionID, username, songID string,
+) (*song.Model, *requestError) {
 	// update session time stamp
 	h.SessionCollection.SetLastUpdated(ctx, sessionID)
 
-	fullTrack, err := h.Spotify.Client.GetTrack(spotify.ID(songID))
+	track, err := h.Spotify.GetTrackInfo(ctx, songID)
 	if err != nil {
 		// todo: should mostly be UserError -> better checks
-		handleError(w, http.StatusInternalServerError, log.ErrorLevel, msg, err, InternalServerError)
-		return
+		return nil, internalError(err)
+	}
+
+	// check the song against the session's rules
+	settings, err := h.SessionCollection.GetSettings(ctx, sessionID)
+	if err != nil {
+		return nil, internalError(err)
 	}
 
 	// if user suggest's song he automatically votes up
-	songInfo := song.New(username, 1, fullTrack)
+	songInfo := track.Song(username, 1)
 	songInfo.Upvoters = append(songInfo.Upvoters, username)
 
-	if err := h.SongCollection.AddSong(ctx, sessionID, songInfo); err != nil {
-		if errors.Is(err, db.ErrSongAlreadyInSession) {
-			handleError(w, http.StatusConflict, log.WarnLevel, msg, err, SongConflictError)
-		} else {
-			handleError(w, http.StatusInternalServerError, log.ErrorLevel, msg, err, InternalServerError)
+	limits := settings.SuggestionLimits
+	var songList []*song.Model
+	if settings.ContentRules.MaxPerArtist > 0 {
+		songList, err = h.SongCollection.ListSongs(ctx, sessionID)
+		if err != nil {
+			return nil, internalError(err)
 		}
-		return
+	}
+	if violations := settings.CheckContent(songInfo, songList); len(violations) > 0 {
+		return nil, rejectedSongError(violations)
+	}
+
+	now := time.Now()
+	if repeatWindow := limits.RepeatWindow(); repeatWindow > 0 {
+		history, err := h.PlayerCollection.GetHistory(ctx, sessionID)
+		if err != nil {
+			return nil, internalError(err)
+		}
+		if entry := player.PlayedWithin(history, songID, now.Add(-repeatWindow)); entry != nil {
+			retryAfter := entry.Started.Add(repeatWindow)
+			return nil, retryableError(http.StatusConflict, log.InfoLevel, ErrRecentlyPlayed, RecentlyPlayedError, &retryAfter)
+		}
+	}
+
+	userID := user.GenerateUserID(username, sessionID)
+	allowance, previous, reqErr := h.claimSuggestion(ctx, userID, limits, now)
+	if reqErr != nil {
+		return nil, reqErr
+	}
+
+	if err := h.SongCollection.AddSong(ctx, sessionID, songInfo, allowance); err != nil {
+		h.releaseSuggestion(ctx, msg, userID, limits, previous)
+		if errors.Is(err, db.ErrNoSessionWithID) {
+			return nil, newRequestError(http.StatusBadRequest, log.WarnLevel, err, SessionNotFoundError)
+		} else if errors.Is(err, db.ErrSongAlreadyInSession) {
+			return nil, newRequestError(http.StatusConflict, log.WarnLevel, err, SongConflictError)
+		} else if errors.Is(err, db.ErrTooManySuggestions) {
+			// a slot only frees up once one of the user's songs has been played, so there is no retry time
+			return nil, retryableError(http.StatusTooManyRequests, log.InfoLevel, err, SuggestionLimitError, nil)
+		}
+		return nil, internalError(err)
 	}
 
 	log.Infof("%v: by [%v] songID [%v]", msg, username, songID)
-	jsonResponse(w, songInfo)
 
 	// fetch songList and send event
+	h.publishPlaylist(ctx, msg, sessionID)
+	// notify the player controller of a new song being suggested
+	h.eventBus.Publish(playerctrl.SongAdded, events.GroupID(sessionID), nil)
+	return songInfo, nil
+}
+
+// claimSuggestion starts the cooldown of a new suggestion of the user. It returns the number of songs the user
+// may have in the queue, 0 means no limit, and the time of the previous suggestion, which releaseSuggestion
+// restores if no song gets added. The database checks and claims the cooldown in one update and enforces
+// the allowance when adding songs, so concurrent suggestions can not exceed the limits.
+func (h *handler) claimSuggestion(
+	ctx context.Context,
+	userID string,
+	limits session.SuggestionLimits,
+	now time.Time,
+) (allowance int, previous time.Time, reqErr *requestError) {
+	if limits.MaxPending == 0 && limits.MinIntervalS == 0 {
+		return 0, previous, nil
+	}
+
+	var usr *user.Model
+	var err error
+	if limits.MinIntervalS > 0 {
+		usr, err = h.UserCollection.ClaimSuggestion(ctx, userID, now, limits.MinInterval())
+		if errors.Is(err, db.ErrSuggestionCooldown) {
+			next := limits.NextSuggestion(usr.LastSuggestion)
+			return 0, previous, retryableError(http.StatusTooManyRequests, log.InfoLevel, err, SuggestionLimitError, &next)
+		}
+	} else {
+		usr, err = h.UserCollection.GetUserByID(ctx, userID)
+	}
+	if err != nil {
+		return 0, previous, internalError(err)
+	}
+	return limits.PendingAllowance(usr.Score), usr.LastSuggestion, nil
+}
+
+// releaseSuggestion gives back the cooldown claimed for a suggestion that did not add any song
+func (h *handler) releaseSuggestion(
+	ctx context.Context,
+	msg, userID string,
+	limits session.SuggestionLimits,
+	previous time.Time,
+) {
+	if limits.MinIntervalS == 0 {
+		return
+	}
+	if err := h.UserCollection.SetLastSuggestion(ctx, userID, previous); err != nil {
+		log.Errorf("%v: %v", msg, err)
+	}
+}
+
+// spotify looks up at most this many tracks at once
+const maxBatchSuggestions = 50
+
+// suggestResult is the outcome of one song of a batch suggestion,
+// either song or error is set
+type suggestResult struct {
+	SongID string         `json:"song_id"`
+	Song   *song.Model    `json:"song,omitempty"`
+	Error  *FrontendError `json:"error,omitempty"`
+	// set if the song was rejected because of the content rules
+	Violations []session.Violation `json:"violations,omitempty"`
+}
+
+// SuggestSongs adds several songs to the song_list at once.
+// The songs are checked like single suggestions, the result of every song is reported separately.
+func (h *handler) SuggestSongs(w http.ResponseWriter, r *http.Request) {
+	msg := "[handler] suggest songs"
+	ctx := context.Background()
+
+	vars := mux.Vars(r)
+	username := vars["username"]
+	sessionID := r.Header.Get("Session")
+
+	// update session time stamp
+	h.SessionCollection.SetLastUpdated(ctx, sessionID)
+
+	var songIDs []string
+	if err := json.NewDecoder(r.Body).Decode(&songIDs); err != nil {
+		handleError(w, http.StatusBadRequest, log.WarnLevel, msg, err, RequestBodyMalformedError)
+		return
+	}
+	if len(songIDs) == 0 || len(songIDs) > maxBatchSuggestions {
+		handleError(w, http.StatusBadRequest, log.WarnLevel, msg, ErrBadBatchSize, RequestBodyMalformedError)
+		return
+	}
+
+	settings, err := h.SessionCollection.GetSettings(ctx, sessionID)
+	if err != nil {
+		handleError(w, http.StatusInternalServerError, log.ErrorLevel, msg, err, InternalServerError)
+		return
+	}
+	limits := settings.SuggestionLimits
+	userID := user.GenerateUserID(username, sessionID)
+	now := time.Now()
+
 	songList, err := h.SongCollection.ListSongs(ctx, sessionID)
 	if err != nil {
-		log.Errorf("%v: event: %v", msg, err)
+		handleError(w, http.StatusInternalServerError, log.ErrorLevel, msg, err, InternalServerError)
+		return
+	}
+	// songs in the queue and songs that appear twice in the batch are conflicts
+	queued := make(map[string]bool, len(songList)+len(songIDs))
+	pending := 0
+	for _, s := range songList {
+		queued[s.ID] = true
+		if s.SuggestedBy == username {
+			pending++
+		}
 	}
 
-	h.eventBus.Publish(sse.PlaylistChange, events.GroupID(sessionID), songList)
-	// notify the player controller of a new song being suggested
+	var history []*player.HistoryEntry
+	repeatWindow := limits.RepeatWindow()
+	if repeatWindow > 0 {
+		history, err = h.PlayerCollection.GetHistory(ctx, sessionID)
+		if err != nil {
+			handleError(w, http.StatusInternalServerError, log.ErrorLevel, msg, err, InternalServerError)
+			return
+		}
+	}
+
+	trackIDs := make([]spotify.ID, 0, len(songIDs))
+	for _, songID := range songIDs {
+		trackIDs = append(trackIDs, spotify.ID(songID))
+	}
+	// unknown ids are returned as nil tracks
+	tracks, err := h.Spotify.Client.GetTracks(trackIDs...)
+	if err != nil {
+		var spotifyErr spotify.Error
+		if errors.As(err, &spotifyErr) && spotifyErr.Status == http.StatusBadRequest {
+			handleError(w, http.StatusBadRequest, log.WarnLevel, msg, err, RequestBodyMalformedError)
+		} else {
+			handleError(w, http.StatusInternalServerError, log.ErrorLevel, msg, err, InternalServerError)
+		}
+		return
+	}
+
+	// a batch counts as a single suggestion for the cooldown
+	allowance, previous, reqErr := h.claimSuggestion(ctx, userID, limits, now)
+	if reqErr != nil {
+		reqErr.write(w, msg)
+		return
+	}
+
+	results := make([]*suggestResult, 0, len(songIDs))
+	newSongs := make([]*song.Model, 0, len(songIDs))
+	for i, songID := range songIDs {
+		result := &suggestResult{SongID: songID}
+		results = append(results, result)
+
+		if i >= len(tracks) || tracks[i] == nil {
+			result.Error = &SongNotFoundError
+			continue
+		}
+		// if user suggest's song he automatically votes up
+		songInfo := song.New(username, 1, tracks[i])
+		songInfo.Upvoters = append(songInfo.Upvoters, username)
+
+		violations := settings.CheckContent(songInfo, songList)
+		switch {
+		case queued[songID]:
+			result.Error = &SongConflictError
+		case len(violations) > 0:
+			rejected := songRejectedError(violations)
+			result.Error = &rejected
+			result.Violations = violations
+		case repeatWindow > 0 && player.PlayedWithin(history, songID, now.Add(-repeatWindow)) != nil:
+			result.Error = &RecentlyPlayedError
+		case allowance > 0 && pending >= allowance:
+			result.Error = &SuggestionLimitError
+		default:
+			result.Song = songInfo
+			newSongs = append(newSongs, songInfo)
+			// songs of the batch count towards the artist limit of later songs
+			songList = append(songList, songInfo)
+			queued[songID] = true
+			pending++
+		}
+	}
+
+	if err := h.SongCollection.AddSongs(ctx, sessionID, newSongs, allowance); err != nil {
+		var frontendErr *FrontendError
+		switch {
+		// another request added one of the songs in the meantime, so none of them was added
+		case errors.Is(err, db.ErrSongAlreadyInSession):
+			frontendErr = &SongConflictError
+		// another request used up the allowance in the meantime
+		case errors.Is(err, db.ErrTooManySuggestions):
+			frontendErr = &SuggestionLimitError
+		case errors.Is(err, db.ErrNoSessionWithID):
+			handleError(w, http.StatusBadRequest, log.WarnLevel, msg, err, SessionNotFoundError)
+			return
+		default:
+			h.releaseSuggestion(ctx, msg, userID, limits, previous)
+			handleError(w, http.StatusInternalServerError, log.ErrorLevel, msg, err, InternalServerError)
+			return
+		}
+		log.Warnf("%v: %v", msg, err)
+		for _, result := range results {
+			if result.Song != nil {
+				result.Song = nil
+				result.Error = frontendErr
+			}
+		}
+		newSongs = newSongs[:0]
+	}
+
+	if len(newSongs) == 0 {
+		h.releaseSuggestion(ctx, msg, userID, limits, previous)
+	}
+
+	log.Infof("%v: by [%v] added [%v] of [%v]", msg, username, len(newSongs), len(songIDs))
+	jsonResponse(w, results)
+
+	if len(newSongs) == 0 {
+		return
+	}
+	h.publishPlaylist(ctx, msg, sessionID)
+	// notify the player controller of new songs being suggested
+	h.eventBus.Publish(playerctrl.SongAdded, events.GroupID(sessionID), nil)
+}
+
+type importStatus string
+
+const (
+	importAdded          importStatus = "added"
+	importDuplicate      importStatus = "duplicate"
+	importRejected       importStatus = "rejected"
+	importRecentlyPlayed importStatus = "recently_played"
+	importLimited        importStatus = "suggestion_limit"
+	importFailed         importStatus = "error"
+)
+
+type importResult struct {
+	SongID string       `json:"song_id"`
+	Name   string       `json:"name"`
+	Status importStatus `json:"status"`
+	// set if the track was rejected because of the content rules
+	Violations []session.Violation `json:"violations,omitempty"`
+}
+
+// importResponse reports the outcome of every visited track,
+// truncated is set if the import stopped before the end of the playlist or album
+type importResponse struct {
+	Added     int             `json:"added"`
+	Truncated bool            `json:"truncated"`
+	Results   []*importResult `json:"results"`
+}
+
+// ImportSongs suggests the tracks of a spotify playlist or album, at most config.Conf.MaxImportTracks of them
+func (h *handler) ImportSongs(w http.ResponseWriter, r *http.Request) {
+	msg := "[handler] import songs"
+	ctx := context.Background()
+
+	vars := mux.Vars(r)
+	username := vars["username"]
+	sessionID := r.Header.Get("Session")
+
+	// update session time stamp
+	h.SessionCollection.SetLastUpdated(ctx, sessionID)
+
+	var body struct {
+		URI string `json:"uri"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		handleError(w, http.StatusBadRequest, log.WarnLevel, msg, err, BadCollectionURIError)
+		return
+	}
+	if _, _, err := spotifycl.ParseCollectionURI(body.URI); err != nil {
+		handleError(w, http.StatusBadRequest, log.WarnLevel, msg, err, BadCollectionURIError)
+		return
+	}
+
+	// imported songs follow the same content rules and suggestion limits as single suggestions
+	settings, err := h.SessionCollection.GetSettings(ctx, sessionID)
+	if err != nil {
+		handleError(w, http.StatusInternalServerError, log.ErrorLevel, msg, err, InternalServerError)
+		return
+	}
+	limits := settings.SuggestionLimits
+	var history []*player.HistoryEntry
+	repeatWindow := limits.RepeatWindow()
+	if repeatWindow > 0 {
+		history, err = h.PlayerCollection.GetHistory(ctx, sessionID)
+		if err != nil {
+			handleError(w, http.StatusInternalServerError, log.ErrorLevel, msg, err, InternalServerError)
+			return
+		}
+	}
+	var songList []*song.Model
+	if settings.ContentRules.MaxPerArtist > 0 || limits.MaxPending > 0 {
+		songList, err = h.SongCollection.ListSongs(ctx, sessionID)
+		if err != nil {
+			handleError(w, http.StatusInternalServerError, log.ErrorLevel, msg, err, InternalServerError)
+			return
+		}
+	}
+	pending := 0
+	for _, s := range songList {
+		if s.SuggestedBy == username {
+			pending++
+		}
+	}
+
+	// an import counts as a single suggestion for the cooldown
+	userID := user.GenerateUserID(username, sessionID)
+	now := time.Now()
+	allowance, previous, reqErr := h.claimSuggestion(ctx, userID, limits, now)
+	if reqErr != nil {
+		reqErr.write(w, msg)
+		return
+	}
+
+	response := &importResponse{Results: make([]*importResult, 0)}
+	err = h.Spotify.VisitCollectionTracks(body.URI, func(track *spotify.FullTrack) bool {
+		if config.Conf.MaxImportTracks > 0 && response.Added >= config.Conf.MaxImportTracks {
+			response.Truncated = true
+			return false
+		}
+
+		result := &importResult{SongID: string(track.ID), Name: track.Name}
+		response.Results = append(response.Results, result)
+
+		// like with single suggestions, the user automatically votes up
+		songInfo := song.New(username, 1, track)
+		songInfo.Upvoters = append(songInfo.Upvoters, username)
+
+		if violations := settings.CheckContent(songInfo, songList); len(violations) > 0 {
+			result.Status = importRejected
+			result.Violations = violations
+			return true
+		}
+		if repeatWindow > 0 && player.PlayedWithin(history, result.SongID, now.Add(-repeatWindow)) != nil {
+			result.Status = importRecentlyPlayed
+			return true
+		}
+		// no later track fits into the allowance either
+		if allowance > 0 && pending >= allowance {
+			result.Status = importLimited
+			response.Truncated = true
+			return false
+		}
+
+		if err := h.SongCollection.AddSong(ctx, sessionID, songInfo, allowance); err != nil {
+			if errors.Is(err, db.ErrSongAlreadyInSession) {
+				result.Status = importDuplicate
+			} else if errors.Is(err, db.ErrTooManySuggestions) {
+				// another request used up the allowance in the meantime
+				result.Status = importLimited
+				response.Truncated = true
+				return false
+			} else if errors.Is(err, db.ErrNoSessionWithID) {
+				// the session was deleted during the import
+				log.Warnf("%v: %v", msg, err)
+				result.Status = importFailed
+				response.Truncated = true
+				return false
+			} else {
+				log.Errorf("%v: %v", msg, err)
+				result.Status = importFailed
+			}
+			return true
+		}
+		result.Status = importAdded
+		response.Added++
+		songList = append(songList, songInfo)
+		pending++
+		return true
+	})
+	if response.Added == 0 {
+		h.releaseSuggestion(ctx, msg, userID, limits, previous)
+	}
+	if err != nil {
+		// report the songs that made it into the queue before spotify failed
+		if response.Added == 0 {
+			handleError(w, http.StatusInternalServerError, log.ErrorLevel, msg, err, InternalServerError)
+			return
+		}
+		log.Errorf("%v: %v", msg, err)
+		response.Truncated = true
+	}
+
+	log.Infof("%v: by [%v] uri [%v] added [%v]", msg, username, body.URI, response.Added)
+	jsonResponse(w, response)
+
+	if response.Added == 0 {
+		return
+	}
+	h.publishPlaylist(ctx, msg, sessionID)
+	// notify the player controller of new songs being suggested
 	h.eventBus.Publish(playerctrl.SongAdded, events.GroupID(sessionID), nil)
 }
 
@@ -319,6 +872,117 @@ func (h *handler) ListSongs(w http.ResponseWriter, r *http.Request) {
 	jsonResponse(w, songList)
 }
 
+// searchResult is a track found by a search, annotated with its state in the session
+type searchResult struct {
+	ID         string   `json:"id"`
+	Name       string   `json:"name"`
+	Artists    []string `json:"artists"`
+	Duration   int      `json:"duration_ms"`
+	CoverUrl   string   `json:"cover_url"`
+	AlbumName  string   `json:"album_name"`
+	PreviewUrl string   `json:"preview_url"`
+	Explicit   bool     `json:"explicit"`
+	Queued     bool     `json:"queued"`
+	Playing    bool     `json:"playing"`
+}
+
+// Search searches spotify for tracks, so clients do not need a spotify token of their own
+func (h *handler) Search(w http.ResponseWriter, r *http.Request) {
+	msg := "[handler] search"
+	ctx := context.Background()
+
+	vars := mux.Vars(r)
+	username := vars["username"]
+	sessionID := r.Header.Get("Session")
+	query := r.URL.Query().Get("q")
+
+	// update session time stamp
+	h.SessionCollection.SetLastUpdated(ctx, sessionID)
+
+	tracks, err := h.Spotify.SearchTracks(query)
+	if err != nil {
+		if errors.Is(err, spotifycl.ErrEmptyQuery) {
+			handleError(w, http.StatusBadRequest, log.WarnLevel, msg, err, RequestUrlMalformedError)
+		} else {
+			handleError(w, http.StatusInternalServerError, log.ErrorLevel, msg, err, InternalServerError)
+		}
+		return
+	}
+
+	songList, err := h.SongCollection.ListSongs(ctx, sessionID)
+	if err != nil {
+		handleError(w, http.StatusInternalServerError, log.ErrorLevel, msg, err, InternalServerError)
+		return
+	}
+	queued := make(map[string]bool, len(songList))
+	for _, s := range songList {
+		queued[s.ID] = true
+	}
+	playr, err := h.PlayerCollection.GetPlayer(ctx, sessionID)
+	if err != nil {
+		handleError(w, http.StatusInternalServerError, log.ErrorLevel, msg, err, InternalServerError)
+		return
+	}
+	playing := ""
+	if playr != nil && !playr.IsEmpty() {
+		playing = playr.CurrentSong.ID
+	}
+
+	results := make([]*searchResult, 0, len(tracks))
+	for _, track := range tracks {
+		info := song.New(username, 0, track)
+		results = append(results, &searchResult{
+			ID:         info.ID,
+			Name:       info.Name,
+			Artists:    info.Artists,
+			Duration:   info.Duration,
+			CoverUrl:   info.CoverUrl,
+			AlbumName:  info.AlbumName,
+			PreviewUrl: info.PreviewUrl,
+			Explicit:   track.Explicit,
+			Queued:     queued[info.ID],
+			Playing:    info.ID == playing,
+		})
+	}
+
+	log.Infof("%v: user [%v] query [%v]", msg, username, query)
+	jsonResponse(w, results)
+}
+
+// History returns the songs played in a session, most recent first
+func (h *handler) History(w http.ResponseWriter, r *http.Request) {
+	msg := "[handler] history"
+	ctx := context.Background()
+
+	vars := mux.Vars(r)
+	username := vars["username"]
+	sessionID := r.Header.Get("Session")
+
+	// update session time stamp
+	h.SessionCollection.SetLastUpdated(ctx, sessionID)
+
+	history, err := h.PlayerCollection.GetHistory(ctx, sessionID)
+	if err != nil {
+		if errors.Is(err, db.ErrNoSessionWithID) {
+			handleError(w, http.StatusBadRequest, log.WarnLevel, msg, err, SessionNotFoundError)
+		} else {
+			handleError(w, http.StatusInternalServerError, log.ErrorLevel, msg, err, InternalServerError)
+		}
+		return
+	}
+
+	recent := make([]*player.HistoryEntry, 0, len(history))
+	for i := len(history) - 1; i >= 0; i-- {
+		recent = append(recent, history[i])
+	}
+
+	log.Infof("%v: user [%v]", msg, username)
+	jsonResponse(w, recent)
+}
+
+// removalReasonHeader tells the voter why the vote removed the song from the queue
+const removalReasonHeader = "Removal-Reason"
+
 // Vote handles user votes on songs
 func (h *handler) Vote(w http.ResponseWriter, r *http.Request) {
 	msg := "[handler] vote"
@@ -332,36 +996,71 @@ func (h *handler) Vote(w http.ResponseWriter, r *http.Request) {
 	// get session id from headers
 	sessionID := r.Header.Get("Session")
 
+	songList, removalReason, reqErr := h.vote(ctx, msg, sessionID, username, songID, voteAction)
+	if reqErr != nil {
+		reqErr.write(w, msg)
+		return
+	}
+	if removalReason != "" {
+		w.Header().Set(removalReasonHeader, string(removalReason))
+	}
+	jsonResponse(w, songList)
+}
+
+// vote applies the vote of the user for http and websocket clients. It returns the updated song list
+// and why the song has been removed from the queue, if the vote removed it.
+func (h *handler) vote(
+	ctx context.Context,
+	msg, sessionID, username, songID, voteAction string,
+) ([]*song.Model, session.RemovalReason, *requestError) {
 	// update session time stamp
 	h.SessionCollection.SetLastUpdated(ctx, sessionID)
 
 	if voteAction != "up" && voteAction != "down" {
-		handleError(w, http.StatusBadRequest, log.ErrorLevel, msg, ErrBadVoteAction, BadVoteError)
-		return
+		return nil, "", newRequestError(http.StatusBadRequest, log.ErrorLevel, ErrBadVoteAction, BadVoteError)
+	}
+
+	songInfo, err := h.SongCollection.GetSongByID(ctx, sessionID, songID)
+	if err != nil {
+		return nil, "", internalError(err)
 	}
 
 	// the scoreChange of the song score has to be applied to the user score
 	var scoreChange int
-	var err error
+	var removalReason session.RemovalReason
 
 	if voteAction == "up" {
 		scoreChange, err = h.SongCollection.VoteUp(ctx, sessionID, songID, username)
 		if err != nil {
-			handleError(w, http.StatusInternalServerError, log.ErrorLevel, msg, err, InternalServerError)
-			return
+			return nil, "", internalError(err)
 		}
 	} else {
-		scoreChange, err = h.SongCollection.VoteDown(ctx, sessionID, songID, username)
+		settings, err := h.SessionCollection.GetSettings(ctx, sessionID)
 		if err != nil {
-			handleError(w, http.StatusInternalServerError, log.ErrorLevel, msg, err, InternalServerError)
-			return
+			return nil, "", internalError(err)
+		}
+		if !settings.VotingRules.AllowDownvotes {
+			return nil, "", newRequestError(http.StatusForbidden, log.InfoLevel, ErrDownvotesDisabled, DownvotesDisabledError)
 		}
-	}
 
-	songInfo, err := h.SongCollection.GetSongByID(ctx, sessionID, songID)
-	if err != nil {
-		handleError(w, http.StatusInternalServerError, log.ErrorLevel, msg, err, InternalServerError)
-		return
+		members := 0
+		if settings.VotingRules.RemovalRatio > 0 {
+			members, err = h.countMembers(ctx, sessionID)
+			if err != nil {
+				return nil, "", internalError(err)
+			}
+		}
+
+		scoreChange, removalReason, err = h.SongCollection.VoteDown(
+			ctx,
+			sessionID,
+			songID,
+			username,
+			settings.VotingRules.RemovalThreshold(members),
+		)
+		if err != nil {
+			return nil, "", internalError(err)
+		}
 	}
 
 	// if user updates his vote on own song -> dont update his score
@@ -372,22 +1071,62 @@ func (h *handler) Vote(w http.ResponseWriter, r *http.Request) {
 			user.GenerateUserID(songInfo.SuggestedBy, sessionID),
 			scoreChange,
 		); err != nil {
-			handleError(w, http.StatusInternalServerError, log.ErrorLevel, msg, err, InternalServerError)
-			return
+			return nil, "", internalError(err)
 		}
 	}
 
 	// return updated song list
 	songList, err := h.SongCollection.ListSongs(ctx, sessionID)
 	if err != nil {
-		handleError(w, http.StatusInternalServerError, log.ErrorLevel, msg, err, InternalServerError)
-		return
+		return nil, "", internalError(err)
 	}
 
 	log.Infof("user [%v] %vvoted song [%v]", username, voteAction, songID)
-	jsonResponse(w, songList)
+	h.playlists.Publish(sessionID, songList)
+
+	if removalReason != "" {
+		log.Infof("%v: song [%v] removed from session [%v]: %v", msg, songID, sessionID, removalReason)
+		h.notifyUser(sessionID, songInfo.SuggestedBy, sse.NotificationSongRemoved, sse.SongRemovedPayload{
+			SongID:      songID,
+			SuggestedBy: songInfo.SuggestedBy,
+			Reason:      removalReason,
+		})
+	}
+	return songList, removalReason, nil
+}
 
-	h.eventBus.Publish(sse.PlaylistChange, events.GroupID(sessionID), songList)
+// notifyUser publishes a notification that only the given user of the session receives
+func (h *handler) notifyUser(sessionID, username string, kind sse.NotificationKind, data interface{}) {
+	h.eventBus.Publish(
+		sse.UserNotification,
+		events.UserGroupID(sessionID, username),
+		sse.UserNotificationPayload{Kind: kind, Data: data},
+	)
+}
+
+// publishPlaylist fetches the song list of a session and publishes the changes
+func (h *handler) publishPlaylist(ctx context.Context, msg, sessionID string) {
+	songList, err := h.SongCollection.ListSongs(ctx, sessionID)
+	if err != nil {
+		log.Errorf("%v: event: %v", msg, err)
+		return
+	}
+	h.playlists.Publish(sessionID, songList)
+}
+
+// countMembers returns the number of admitted users in a session
+func (h *handler) countMembers(ctx context.Context, sessionID string) (int, error) {
+	users, err := h.UserCollection.ListUsers(ctx, sessionID)
+	if err != nil {
+		return 0, err
+	}
+	members := 0
+	for _, u := range users {
+		if !u.Waiting {
+			members++
+		}
+	}
+	return members, nil
 }
 
 // returns client token
@@ -481,18 +1220,63 @@ func (h *handler) SessionInfo(w http.ResponseWriter, r *http.Request) {
 		currentSong = player.CurrentSong
 	}
 
+	// tell the frontend whether it has to prompt for a password or an invite
+	sess, err := h.SessionCollection.GetSessionByID(ctx, sessionID)
+	if err != nil {
+		if errors.Is(err, db.ErrNoSessionWithID) {
+			handleError(w, http.StatusBadRequest, log.WarnLevel, msg, err, SessionNotFoundError)
+		} else {
+			handleError(w, http.StatusInternalServerError, log.ErrorLevel, msg, err, InternalServerError)
+		}
+		return
+	}
+
 	response := &struct {
-		AdminName   string      `json:"admin_name"`
-		CurrentSong *song.Model `json:"current_song"`
+		AdminName         string      `json:"admin_name"`
+		CurrentSong       *song.Model `json:"current_song"`
+		PasswordProtected bool        `json:"password_protected"`
+		InviteOnly        bool        `json:"invite_only"`
+		JoinCode          string      `json:"join_code"`
 	}{
-		AdminName:   admin.Username,
-		CurrentSong: currentSong,
+		AdminName:         admin.Username,
+		CurrentSong:       currentSong,
+		PasswordProtected: sess.IsPasswordProtected(),
+		InviteOnly:        sess.Settings != nil && sess.Settings.InviteOnly,
+		JoinCode:          sess.JoinCode,
 	}
 
 	jsonResponse(w, response)
 	log.Infof("%v: session=[%v]", msg, sessionID)
 }
 
+// ResolveJoinCode returns the id of the session a join code belongs to
+func (h *handler) ResolveJoinCode(w http.ResponseWriter, r *http.Request) {
+	msg := "[handler] resolve join code"
+	ctx := context.Background()
+
+	vars := mux.Vars(r)
+	code := vars["code"]
+
+	sessionID, err := h.SessionCollection.ResolveJoinCode(ctx, code)
+	if err != nil {
+		if errors.Is(err, db.ErrNoSessionWithJoinCode) {
+			handleError(w, http.StatusBadRequest, log.DebugLevel, msg, err, JoinCodeNotFoundError)
+		} else {
+			handleError(w, http.StatusInternalServerError, log.ErrorLevel, msg, err, InternalServerError)
+		}
+		return
+	}
+
+	response := &struct {
+		SessionID string `json:"session_id"`
+	}{
+		SessionID: sessionID,
+	}
+
+	jsonResponse(w, response)
+	log.Infof("%v: code=[%v], session=[%v]", msg, code, sessionID)
+}
+
 func (h *handler) ListFavouriteSongs(w http.ResponseWriter, r *http.Request) {
 	msg := "[handler] list favourite songs"
 	ctx := context.Background()
@@ -526,10 +1310,120 @@ func (h *handler) ListFavouriteSongs(w http.ResponseWriter, r *http.Request) {
 		handleError(w, http.StatusInternalServerError, log.ErrorLevel, msg, err, InternalServerError)
 		return
 	}
+	// favourite songs are likely to be suggested next
+	h.Spotify.CacheTracks(ctx, topTracks.Tracks)
 
 	jsonResponse(w, topTracks.Tracks)
 }
 
+// spotify accepts at most this many tracks per request when adding tracks to a playlist
+const playlistBatchSize = 100
+
+type exportRequest struct {
+	Name   string `json:"name"`
+	Public bool   `json:"public"`
+}
+
+// ExportPlaylist creates a playlist in the user's spotify account with the songs played in the session
+func (h *handler) ExportPlaylist(w http.ResponseWriter, r *http.Request) {
+	msg := "[handler] export playlist"
+	ctx := context.Background()
+	vars := mux.Vars(r)
+
+	sessionID := r.Header.Get("Session")
+	username := vars["username"]
+
+	// update session time stamp
+	h.SessionCollection.SetLastUpdated(ctx, sessionID)
+
+	// the body is optional
+	var body exportRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil && !errors.Is(err, io.EOF) {
+		handleError(w, http.StatusBadRequest, log.WarnLevel, msg, err, RequestBodyMalformedError)
+		return
+	}
+
+	userInfo, err := h.UserCollection.GetUserByID(ctx, user.GenerateUserID(username, sessionID))
+	if err != nil {
+		handleError(w, http.StatusInternalServerError, log.ErrorLevel, msg, err, InternalServerError)
+		return
+	}
+	if !userInfo.SpotifyAuthorized {
+		handleError(w, http.StatusUnauthorized, log.WarnLevel, msg, ErrSpotifyNotAuthenticated, SpotifyNotAuthenticatedError)
+		return
+	}
+
+	setlist, played, err := h.PlayerCollection.GetSetlist(ctx, sessionID)
+	if err != nil {
+		handleError(w, http.StatusInternalServerError, log.ErrorLevel, msg, err, InternalServerError)
+		return
+	}
+	if len(setlist) == 0 {
+		handleError(w, http.StatusBadRequest, log.InfoLevel, msg, ErrEmptyHistory, EmptyHistoryError)
+		return
+	}
+	trackIDs := make([]spotify.ID, len(setlist))
+	for i, entry := range setlist {
+		trackIDs[i] = spotify.ID(entry.SongID)
+	}
+
+	name := body.Name
+	if name == "" {
+		name = fmt.Sprintf("encore.fm %v", setlist[0].Started.Format("2006-01-02"))
+	}
+
+	client := h.spotifyAuthenticator.NewClient(userInfo.AuthToken)
+
+	spotifyUser, err := client.CurrentUser()
+	if err != nil {
+		handleSpotifyError(w, msg, err)
+		return
+	}
+	playlist, err := client.CreatePlaylistForUser(spotifyUser.ID, name, "songs played at encore.fm", body.Public)
+	if err != nil {
+		handleSpotifyError(w, msg, err)
+		return
+	}
+	for start := 0; start < len(trackIDs); start += playlistBatchSize {
+		end := start + playlistBatchSize
+		if end > len(trackIDs) {
+			end = len(trackIDs)
+		}
+		if _, err := client.AddTracksToPlaylist(playlist.ID, trackIDs[start:end]...); err != nil {
+			handleSpotifyError(w, msg, err)
+			return
+		}
+	}
+
+	response := &struct {
+		PlaylistID string `json:"playlist_id"`
+		URL        string `json:"url"`
+		Tracks     int    `json:"tracks"`
+		// set if the first songs of the session have been dropped from the setlist
+		Truncated bool `json:"truncated"`
+	}{
+		PlaylistID: string(playlist.ID),
+		URL:        playlist.ExternalURLs["spotify"],
+		Tracks:     len(trackIDs),
+		Truncated:  played > len(setlist),
+	}
+
+	log.Infof("%v: user=[%v] session=[%v] tracks=[%v]", msg, username, sessionID, len(trackIDs))
+	jsonResponse(w, response)
+}
+
+// handleSpotifyError asks the user to authorize again if spotify rejected the token,
+// e.g. because it was issued before a scope was added
+func handleSpotifyError(w http.ResponseWriter, msg string, err error) {
+	var spotifyErr spotify.Error
+	if errors.As(err, &spotifyErr) &&
+		(spotifyErr.Status == http.StatusUnauthorized || spotifyErr.Status == http.StatusForbidden) {
+		handleError(w, http.StatusForbidden, log.WarnLevel, msg, err, SpotifyNotAuthenticatedError)
+		return
+	}
+	handleError(w, http.StatusInternalServerError, log.ErrorLevel, msg, err, InternalServerError)
+}
+
 type SyncMode string
 
 const (
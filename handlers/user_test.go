@@ -6,7 +6,9 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/encore-fm/backend/player"
 	"github.com/encore-fm/backend/session"
@@ -15,6 +17,7 @@ import (
 	"github.com/encore-fm/backend/db/mocks"
 	"github.com/encore-fm/backend/events"
 	"github.com/encore-fm/backend/song"
+	"github.com/encore-fm/backend/sse"
 	"github.com/encore-fm/backend/user"
 	"github.com/gorilla/mux"
 	"github.com/stretchr/testify/assert"
@@ -106,6 +109,349 @@ func TestHandler_Join(t *testing.T) {
 	assert.Equal(t, sessionID, response.UserInfo.SessionID)
 }
 
+// test join request on a full session without waiting list
+func TestHandler_Join_SessionFull(t *testing.T) {
+	sessionID := "session_id"
+	username := "username"
+
+	// set up sessionCollection mock
+	var sessionCollection db.SessionCollection
+	sessionCollection = &mocks.SessionCollection{}
+
+	sessionCollection.(*mocks.SessionCollection).
+		On("GetSessionByID", context.Background(), sessionID).
+		Return(
+			&session.Session{ID: sessionID, SongList: make([]*song.Model, 0), MaxUsers: 1, WaitingList: false},
+			nil,
+		)
+
+	sessionCollection.(*mocks.SessionCollection).
+		On("SetLastUpdated", context.Background(), sessionID).
+		Return()
+
+	// set up userCollection mock
+	var userCollection db.UserCollection
+	userCollection = &mocks.UserCollection{}
+
+	// session has no free slot
+	userCollection.(*mocks.UserCollection).
+		On("AddUser", context.Background(), mock.Anything).
+		Return(db.ErrSessionFull)
+
+	// create handler with mock collections
+	handler := &handler{
+		UserCollection:    userCollection,
+		SessionCollection: sessionCollection,
+	}
+	userHandler := UserHandler(handler)
+
+	// set up http request
+	req, err := http.NewRequest(
+		"POST",
+		fmt.Sprintf("/users/%v/join/%v", username, sessionID),
+		nil,
+	)
+	assert.NoError(t, err)
+	req = mux.SetURLVars(req, map[string]string{
+		"username":   username,
+		"session_id": sessionID,
+	})
+	rr := httptest.NewRecorder()
+
+	// call handler func
+	userHandler.Join(rr, req)
+
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+
+	var frontendErr FrontendError
+	err = json.NewDecoder(rr.Body).Decode(&frontendErr)
+	assert.NoError(t, err)
+	assert.Equal(t, SessionFullError, frontendErr)
+	userCollection.(*mocks.UserCollection).AssertNumberOfCalls(t, "AddUser", 1)
+}
+
+// test join request with a wrong session password
+func TestHandler_Join_WrongPassword(t *testing.T) {
+	sessionID := "session_id"
+	username := "username"
+
+	passwordHash, err := session.HashPassword("secret")
+	assert.NoError(t, err)
+
+	// set up sessionCollection mock
+	var sessionCollection db.SessionCollection
+	sessionCollection = &mocks.SessionCollection{}
+
+	sessionCollection.(*mocks.SessionCollection).
+		On("GetSessionByID", context.Background(), sessionID).
+		Return(
+			&session.Session{ID: sessionID, SongList: make([]*song.Model, 0), PasswordHash: passwordHash},
+			nil,
+		)
+
+	var userCollection db.UserCollection
+	userCollection = &mocks.UserCollection{}
+
+	// create handler with mock collections
+	handler := &handler{
+		UserCollection:    userCollection,
+		SessionCollection: sessionCollection,
+	}
+	userHandler := UserHandler(handler)
+
+	// set up http request
+	req, err := http.NewRequest(
+		"POST",
+		fmt.Sprintf("/users/%v/join/%v", username, sessionID),
+		strings.NewReader(`{"password": "wrong"}`),
+	)
+	assert.NoError(t, err)
+	req = mux.SetURLVars(req, map[string]string{
+		"username":   username,
+		"session_id": sessionID,
+	})
+	rr := httptest.NewRecorder()
+
+	// call handler func
+	userHandler.Join(rr, req)
+
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+
+	var frontendErr FrontendError
+	err = json.NewDecoder(rr.Body).Decode(&frontendErr)
+	assert.NoError(t, err)
+	assert.Equal(t, WrongPasswordError, frontendErr)
+	userCollection.(*mocks.UserCollection).AssertNotCalled(t, "AddUser", mock.Anything, mock.Anything)
+}
+
+// banned users can not join again, neither with their username nor from the same client
+func TestHandler_Join_Banned(t *testing.T) {
+	sessionID := "session_id"
+
+	// set up sessionCollection mock
+	var sessionCollection db.SessionCollection
+	sessionCollection = &mocks.SessionCollection{}
+
+	sessionCollection.(*mocks.SessionCollection).
+		On("GetSessionByID", context.Background(), sessionID).
+		Return(
+			&session.Session{
+				ID:       sessionID,
+				SongList: make([]*song.Model, 0),
+				Bans:     []*session.Ban{session.NewBan("troll", "fingerprint")},
+			},
+			nil,
+		)
+
+	var userCollection db.UserCollection
+	userCollection = &mocks.UserCollection{}
+
+	// create handler with mock collections
+	handler := &handler{
+		UserCollection:    userCollection,
+		SessionCollection: sessionCollection,
+	}
+	userHandler := UserHandler(handler)
+
+	tests := []struct {
+		username    string
+		fingerprint string
+	}{
+		{username: "troll", fingerprint: ""},
+		{username: "new_name", fingerprint: "fingerprint"},
+	}
+
+	for _, test := range tests {
+		req, err := http.NewRequest(
+			"POST",
+			fmt.Sprintf("/users/%v/join/%v", test.username, sessionID),
+			nil,
+		)
+		assert.NoError(t, err)
+		req = mux.SetURLVars(req, map[string]string{
+			"username":   test.username,
+			"session_id": sessionID,
+		})
+		req.Header.Set("Fingerprint", test.fingerprint)
+		rr := httptest.NewRecorder()
+
+		// call handler func
+		userHandler.Join(rr, req)
+
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+
+		var frontendErr FrontendError
+		err = json.NewDecoder(rr.Body).Decode(&frontendErr)
+		assert.NoError(t, err)
+		assert.Equal(t, UserBannedError, frontendErr)
+	}
+	userCollection.(*mocks.UserCollection).AssertNotCalled(t, "AddUser", mock.Anything, mock.Anything)
+}
+
+// test join request on an invite only session
+func TestHandler_Join_InviteOnly(t *testing.T) {
+	sessionID := "session_id"
+	username := "username"
+	token := "invite_token"
+
+	settings := session.DefaultSettings()
+	settings.InviteOnly = true
+
+	// set up sessionCollection mock
+	var sessionCollection db.SessionCollection
+	sessionCollection = &mocks.SessionCollection{}
+
+	sessionCollection.(*mocks.SessionCollection).
+		On("GetSessionByID", context.Background(), sessionID).
+		Return(
+			&session.Session{ID: sessionID, SongList: make([]*song.Model, 0), Settings: settings},
+			nil,
+		)
+
+	sessionCollection.(*mocks.SessionCollection).
+		On("RedeemInvite", context.Background(), sessionID, token).
+		Return(&session.Invite{Token: token, SingleUse: true}, nil)
+
+	sessionCollection.(*mocks.SessionCollection).
+		On("SetLastUpdated", context.Background(), sessionID).
+		Return()
+
+	var userCollection db.UserCollection
+	userCollection = &mocks.UserCollection{}
+
+	userCollection.(*mocks.UserCollection).
+		On("AddUser", context.Background(), mock.Anything).
+		Return(nil)
+
+	userCollection.(*mocks.UserCollection).
+		On("ListUsers", context.Background(), sessionID).
+		Return(make([]*user.ListElement, 0), nil)
+
+	// create handler with mock collections
+	eventBus := events.NewEventBus()
+	eventBus.Start()
+	handler := &handler{
+		UserCollection:       userCollection,
+		SessionCollection:    sessionCollection,
+		spotifyAuthenticator: spotify.NewAuthenticator("http://123.de"),
+		eventBus:             eventBus,
+	}
+	userHandler := UserHandler(handler)
+
+	tests := []struct {
+		body         string
+		expectedCode int
+	}{
+		{body: "", expectedCode: http.StatusForbidden},
+		{body: fmt.Sprintf(`{"invite": "%v"}`, token), expectedCode: http.StatusOK},
+	}
+
+	for _, test := range tests {
+		req, err := http.NewRequest(
+			"POST",
+			fmt.Sprintf("/users/%v/join/%v", username, sessionID),
+			strings.NewReader(test.body),
+		)
+		assert.NoError(t, err)
+		req = mux.SetURLVars(req, map[string]string{
+			"username":   username,
+			"session_id": sessionID,
+		})
+		rr := httptest.NewRecorder()
+
+		// call handler func
+		userHandler.Join(rr, req)
+
+		assert.Equal(t, test.expectedCode, rr.Code)
+	}
+
+	sessionCollection.(*mocks.SessionCollection).AssertNumberOfCalls(t, "RedeemInvite", 1)
+	userCollection.(*mocks.UserCollection).AssertNumberOfCalls(t, "AddUser", 1)
+}
+
+// test join request on a full session with waiting list
+func TestHandler_Join_WaitingList(t *testing.T) {
+	sessionID := "session_id"
+	username := "username"
+
+	// set up sessionCollection mock
+	var sessionCollection db.SessionCollection
+	sessionCollection = &mocks.SessionCollection{}
+
+	sessionCollection.(*mocks.SessionCollection).
+		On("GetSessionByID", context.Background(), sessionID).
+		Return(
+			&session.Session{ID: sessionID, SongList: make([]*song.Model, 0), MaxUsers: 1, WaitingList: true},
+			nil,
+		)
+
+	sessionCollection.(*mocks.SessionCollection).
+		On("SetLastUpdated", context.Background(), sessionID).
+		Return()
+
+	// set up userCollection mock
+	var userCollection db.UserCollection
+	userCollection = &mocks.UserCollection{}
+
+	// session has no free slot for admitted users
+	userCollection.(*mocks.UserCollection).
+		On("AddUser", context.Background(), mock.MatchedBy(func(u *user.Model) bool {
+			return !u.Waiting
+		})).
+		Return(db.ErrSessionFull)
+
+	// waiting users can always be added
+	userCollection.(*mocks.UserCollection).
+		On("AddUser", context.Background(), mock.MatchedBy(func(u *user.Model) bool {
+			return u.Waiting
+		})).
+		Return(nil)
+
+	userCollection.(*mocks.UserCollection).
+		On("ListUsers", context.Background(), sessionID).
+		Return(make([]*user.ListElement, 0), nil)
+
+	// create handler with mock collections
+	eventBus := events.NewEventBus()
+	eventBus.Start()
+	handler := &handler{
+		UserCollection:       userCollection,
+		SessionCollection:    sessionCollection,
+		spotifyAuthenticator: spotify.NewAuthenticator("http://123.de"),
+		eventBus:             eventBus,
+	}
+	userHandler := UserHandler(handler)
+
+	// set up http request
+	req, err := http.NewRequest(
+		"POST",
+		fmt.Sprintf("/users/%v/join/%v", username, sessionID),
+		nil,
+	)
+	assert.NoError(t, err)
+	req = mux.SetURLVars(req, map[string]string{
+		"username":   username,
+		"session_id": sessionID,
+	})
+	rr := httptest.NewRecorder()
+
+	// call handler func
+	userHandler.Join(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	// decode response body
+	var response *struct {
+		UserInfo *user.Model `json:"user_info"`
+		AuthUrl  string      `json:"auth_url"`
+	}
+	err = json.NewDecoder(rr.Body).Decode(&response)
+	assert.NoError(t, err)
+
+	assert.True(t, response.UserInfo.Waiting)
+	assert.Equal(t, username, response.UserInfo.Username)
+}
+
 // test successful user list request
 func TestHandler_ListUsers(t *testing.T) {
 	username := "username"
@@ -313,6 +659,115 @@ func TestHandler_Vote(t *testing.T) {
 	assert.NoError(t, err)
 
 	assert.Equal(t, songList, response)
+	assert.Empty(t, rr.Header().Get(removalReasonHeader))
+}
+
+func TestHandler_Vote_Removal(t *testing.T) {
+	username := "username"
+	sessionID := "session_id"
+	songID := "song_id"
+	suggestingUser := "test_user"
+
+	songInfo := &song.Model{
+		ID:          songID,
+		SuggestedBy: suggestingUser,
+		Score:       -1,
+	}
+
+	settings := session.DefaultSettings()
+	settings.VotingRules.RemovalRatio = 0.5
+
+	users := []*user.ListElement{
+		{Username: username},
+		{Username: suggestingUser},
+		{Username: "waiting", Waiting: true},
+	}
+
+	var songCollection db.SongCollection
+	songCollection = &mocks.SongCollection{}
+
+	var userCollection db.UserCollection
+	userCollection = &mocks.UserCollection{}
+
+	var sessionCollection db.SessionCollection
+	sessionCollection = &mocks.SessionCollection{}
+
+	sessionCollection.(*mocks.SessionCollection).
+		On("SetLastUpdated", context.Background(), sessionID).
+		Return()
+	sessionCollection.(*mocks.SessionCollection).
+		On("GetSettings", context.Background(), sessionID).
+		Return(settings, nil)
+
+	songCollection.(*mocks.SongCollection).
+		On("GetSongByID", context.Background(), sessionID, songID).
+		Return(songInfo, nil)
+
+	// half of the two admitted users is enough to remove the song
+	songCollection.(*mocks.SongCollection).
+		On("VoteDown", context.Background(), sessionID, songID, username, session.RemovalThreshold{Downvoters: 1}).
+		Return(-1, session.RemovalReasonDownvotes, nil)
+	songCollection.(*mocks.SongCollection).
+		On("ListSongs", context.Background(), sessionID).
+		Return([]*song.Model{}, nil)
+
+	userCollection.(*mocks.UserCollection).
+		On("ListUsers", context.Background(), sessionID).
+		Return(users, nil)
+	userCollection.(*mocks.UserCollection).
+		On("IncrementScore", context.Background(), user.GenerateUserID(suggestingUser, sessionID), -1).
+		Return(nil)
+
+	eventBus := events.NewEventBus()
+	eventBus.Start()
+	// only the suggester is notified of the removal
+	suggesterGroup := events.UserGroupID(sessionID, suggestingUser)
+	sub := eventBus.Subscribe([]events.EventType{sse.UserNotification}, []events.GroupID{suggesterGroup})
+
+	handler := &handler{
+		SongCollection:    songCollection,
+		UserCollection:    userCollection,
+		SessionCollection: sessionCollection,
+		eventBus:          eventBus,
+		playlists:         sse.NewPlaylistTracker(eventBus),
+	}
+	userHandler := UserHandler(handler)
+
+	req, err := http.NewRequest(
+		"POST",
+		fmt.Sprintf("/users/%v/vote/%v/down", username, songID),
+		nil,
+	)
+	assert.NoError(t, err)
+
+	req = mux.SetURLVars(req, map[string]string{
+		"username":    username,
+		"song_id":     songID,
+		"vote_action": "down",
+	})
+	req.Header.Set("Session", sessionID)
+	rr := httptest.NewRecorder()
+
+	userHandler.Vote(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var response []*song.Model
+	err = json.NewDecoder(rr.Body).Decode(&response)
+	assert.NoError(t, err)
+
+	assert.Equal(t, string(session.RemovalReasonDownvotes), rr.Header().Get(removalReasonHeader))
+
+	event := <-sub.Channel
+	assert.Equal(t, suggesterGroup, event.GroupID)
+	assert.Equal(t, sse.UserNotificationPayload{
+		Kind: sse.NotificationSongRemoved,
+		Data: sse.SongRemovedPayload{
+			SongID:      songID,
+			SuggestedBy: suggestingUser,
+			Reason:      session.RemovalReasonDownvotes,
+		},
+	}, event.Data)
 }
 
 func TestHandler_SessionInfo(t *testing.T) {
@@ -336,10 +791,18 @@ func TestHandler_SessionInfo(t *testing.T) {
 		On("GetPlayer", context.TODO(), sessionID).
 		Return(player, nil)
 
+	var sessionCollection db.SessionCollection
+	sessionCollection = &mocks.SessionCollection{}
+
+	sessionCollection.(*mocks.SessionCollection).
+		On("GetSessionByID", context.TODO(), sessionID).
+		Return(&session.Session{ID: sessionID}, nil)
+
 	// create a handler with mock collection
 	handler := &handler{
-		UserCollection:   userCollection,
-		PlayerCollection: playerCollection,
+		UserCollection:    userCollection,
+		PlayerCollection:  playerCollection,
+		SessionCollection: sessionCollection,
 	}
 
 	// set up http request
@@ -372,3 +835,265 @@ func TestHandler_SessionInfo(t *testing.T) {
 
 	assert.Equal(t, admin.Username, response.AdminName)
 }
+
+// join codes resolve to their session, unknown codes are rejected
+func TestHandler_ResolveJoinCode(t *testing.T) {
+	sessionID := "session_id"
+	code := "ABCDEF"
+
+	var sessionCollection db.SessionCollection
+	sessionCollection = &mocks.SessionCollection{}
+
+	sessionCollection.(*mocks.SessionCollection).
+		On("ResolveJoinCode", context.Background(), code).
+		Return(sessionID, nil)
+
+	sessionCollection.(*mocks.SessionCollection).
+		On("ResolveJoinCode", context.Background(), "ZZZZZZ").
+		Return("", db.ErrNoSessionWithJoinCode)
+
+	handler := &handler{
+		SessionCollection: sessionCollection,
+	}
+	userHandler := UserHandler(handler)
+
+	tests := []struct {
+		code         string
+		expectedCode int
+	}{
+		{code: code, expectedCode: http.StatusOK},
+		{code: "ZZZZZZ", expectedCode: http.StatusBadRequest},
+	}
+
+	for _, test := range tests {
+		req, err := http.NewRequest("GET", fmt.Sprintf("/join/%v", test.code), nil)
+		assert.NoError(t, err)
+		req = mux.SetURLVars(req, map[string]string{
+			"code": test.code,
+		})
+		rr := httptest.NewRecorder()
+
+		// call handler func
+		userHandler.ResolveJoinCode(rr, req)
+
+		assert.Equal(t, test.expectedCode, rr.Code)
+	}
+}
+
+// the owner leaving hands the session on to a moderator
+func TestHandler_Leave_Owner(t *testing.T) {
+	sessionID := "session_id"
+	username := "owner"
+	ownerID := user.GenerateUserID(username, sessionID)
+	successor := &user.Model{
+		ID:        user.GenerateUserID("moderator", sessionID),
+		Username:  "moderator",
+		SessionID: sessionID,
+		Role:      user.RoleModerator,
+	}
+
+	var userCollection db.UserCollection
+	userCollection = &mocks.UserCollection{}
+
+	userCollection.(*mocks.UserCollection).
+		On("GetUserByID", context.Background(), ownerID).
+		Return(&user.Model{ID: ownerID, Username: username, SessionID: sessionID, IsAdmin: true}, nil)
+
+	userCollection.(*mocks.UserCollection).
+		On("GetSuccessor", context.Background(), sessionID, ownerID).
+		Return(successor, nil)
+
+	userCollection.(*mocks.UserCollection).
+		On("TransferOwnership", context.Background(), ownerID, successor.ID).
+		Return(nil)
+
+	userCollection.(*mocks.UserCollection).
+		On("DeleteUser", context.Background(), ownerID).
+		Return(nil)
+
+	userCollection.(*mocks.UserCollection).
+		On("AdmitWaitingUsers", context.Background(), sessionID).
+		Return(nil, nil)
+
+	userCollection.(*mocks.UserCollection).
+		On("ListUsers", context.Background(), sessionID).
+		Return(make([]*user.ListElement, 0), nil)
+
+	eventBus := events.NewEventBus()
+	eventBus.Start()
+	handler := &handler{
+		UserCollection: userCollection,
+		eventBus:       eventBus,
+	}
+	userHandler := UserHandler(handler)
+
+	req, err := http.NewRequest("POST", fmt.Sprintf("/users/%v/leave", username), nil)
+	assert.NoError(t, err)
+	req = mux.SetURLVars(req, map[string]string{
+		"username": username,
+	})
+	req.Header.Set("Session", sessionID)
+	rr := httptest.NewRecorder()
+
+	// call handler func
+	userHandler.Leave(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	userCollection.(*mocks.UserCollection).AssertNumberOfCalls(t, "TransferOwnership", 1)
+	userCollection.(*mocks.UserCollection).AssertNumberOfCalls(t, "DeleteUser", 1)
+}
+
+// history is returned with the most recent song first
+func TestHandler_History(t *testing.T) {
+	username := "username"
+	sessionID := "session_id"
+	now := time.Now()
+	history := []*player.HistoryEntry{
+		{Song: &song.Model{ID: "first"}, Started: now.Add(-10 * time.Minute)},
+		{Song: &song.Model{ID: "second"}, Started: now.Add(-5 * time.Minute), Skipped: true},
+	}
+
+	var playerCollection db.PlayerCollection
+	playerCollection = &mocks.PlayerCollection{}
+
+	var sessionCollection db.SessionCollection
+	sessionCollection = &mocks.SessionCollection{}
+
+	sessionCollection.(*mocks.SessionCollection).
+		On("SetLastUpdated", context.Background(), sessionID).
+		Return()
+
+	playerCollection.(*mocks.PlayerCollection).
+		On("GetHistory", context.Background(), sessionID).
+		Return(history, nil)
+
+	handler := &handler{
+		PlayerCollection:  playerCollection,
+		SessionCollection: sessionCollection,
+	}
+	userHandler := UserHandler(handler)
+
+	req, err := http.NewRequest(
+		"GET",
+		fmt.Sprintf("/users/%v/history", username),
+		nil,
+	)
+	assert.NoError(t, err)
+	req = mux.SetURLVars(req, map[string]string{
+		"username": username,
+	})
+	req.Header.Set("Session", sessionID)
+	rr := httptest.NewRecorder()
+
+	userHandler.History(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var response []*player.HistoryEntry
+	err = json.NewDecoder(rr.Body).Decode(&response)
+	assert.NoError(t, err)
+	assert.Len(t, response, 2)
+	assert.Equal(t, "second", response[0].Song.ID)
+	assert.True(t, response[0].Skipped)
+	assert.Equal(t, "first", response[1].Song.ID)
+}
+
+func TestHandler_ExportPlaylist_EmptyHistory(t *testing.T) {
+	username := "username"
+	sessionID := "session_id"
+
+	var playerCollection db.PlayerCollection
+	playerCollection = &mocks.PlayerCollection{}
+
+	var sessionCollection db.SessionCollection
+	sessionCollection = &mocks.SessionCollection{}
+
+	var userCollection db.UserCollection
+	userCollection = &mocks.UserCollection{}
+
+	sessionCollection.(*mocks.SessionCollection).
+		On("SetLastUpdated", context.Background(), sessionID).
+		Return()
+
+	userCollection.(*mocks.UserCollection).
+		On("GetUserByID", context.Background(), user.GenerateUserID(username, sessionID)).
+		Return(&user.Model{Username: username, SpotifyAuthorized: true}, nil)
+
+	playerCollection.(*mocks.PlayerCollection).
+		On("GetSetlist", context.Background(), sessionID).
+		Return([]*player.SetlistEntry{}, 0, nil)
+
+	handler := &handler{
+		PlayerCollection:  playerCollection,
+		SessionCollection: sessionCollection,
+		UserCollection:    userCollection,
+	}
+	userHandler := UserHandler(handler)
+
+	req, err := http.NewRequest(
+		"POST",
+		fmt.Sprintf("/users/%v/exportPlaylist", username),
+		http.NoBody,
+	)
+	assert.NoError(t, err)
+	req = mux.SetURLVars(req, map[string]string{
+		"username": username,
+	})
+	req.Header.Set("Session", sessionID)
+	rr := httptest.NewRecorder()
+
+	userHandler.ExportPlaylist(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	var response FrontendError
+	err = json.NewDecoder(rr.Body).Decode(&response)
+	assert.NoError(t, err)
+	assert.Equal(t, EmptyHistoryError, response)
+}
+
+func TestHandler_SuggestSongs_BadBatchSize(t *testing.T) {
+	username := "username"
+	sessionID := "session_id"
+
+	tests := []string{
+		`[]`,
+		fmt.Sprintf(`["%v"]`, strings.Repeat(`id", "`, maxBatchSuggestions)),
+		`{"song_id": "id"}`,
+	}
+
+	for _, body := range tests {
+		var sessionCollection db.SessionCollection
+		sessionCollection = &mocks.SessionCollection{}
+
+		sessionCollection.(*mocks.SessionCollection).
+			On("SetLastUpdated", context.Background(), sessionID).
+			Return()
+
+		handler := &handler{
+			SessionCollection: sessionCollection,
+		}
+		userHandler := UserHandler(handler)
+
+		req, err := http.NewRequest(
+			"POST",
+			fmt.Sprintf("/users/%v/suggest", username),
+			strings.NewReader(body),
+		)
+		assert.NoError(t, err)
+		req = mux.SetURLVars(req, map[string]string{
+			"username": username,
+		})
+		req.Header.Set("Session", sessionID)
+		rr := httptest.NewRecorder()
+
+		userHandler.SuggestSongs(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code, body)
+
+		var response FrontendError
+		err = json.NewDecoder(rr.Body).Decode(&response)
+		assert.NoError(t, err)
+		assert.Equal(t, RequestBodyMalformedError, response)
+	}
+}
@@ -0,0 +1,390 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/encore-fm/backend/config"
+	"github.com/encore-fm/backend/events"
+	"github.com/encore-fm/backend/session"
+	"github.com/encore-fm/backend/user"
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/websocket"
+)
+
+type WSHandler interface {
+	ServeWS(w http.ResponseWriter, r *http.Request)
+}
+
+var _ WSHandler = (*handler)(nil)
+
+// wsAuthTimeout is the time a client has to authenticate after opening the socket
+const wsAuthTimeout = 10 * time.Second
+
+// message types of the websocket protocol
+const (
+	wsMessageAuth      = "auth"
+	wsMessageCommand   = "command"
+	wsMessageEvent     = "event"
+	wsMessageReply     = "reply"
+	wsMessageHeartbeat = "heartbeat"
+)
+
+var (
+	ErrWSNotAuthenticated = errors.New("first websocket message has to authenticate the user")
+	ErrUnknownCommand     = errors.New("unknown websocket command")
+)
+
+// wsClientMessage is a message sent by a websocket client.
+// The first message has to be of type auth, all following messages are commands.
+type wsClientMessage struct {
+	Type   string `json:"type"`
+	Secret string `json:"secret,omitempty"`
+	// the id of the last received event, as a string or a number
+	LastEventID json.Number                `json:"last_event_id,omitempty"`
+	Playlist    string                     `json:"playlist,omitempty"`
+	ID          string                     `json:"id,omitempty"`
+	Command     string                     `json:"command,omitempty"`
+	Args        map[string]json.RawMessage `json:"args,omitempty"`
+}
+
+// wsEventMessage forwards an sse event to a websocket client,
+// the id is sent as a string like the id of the event stream
+type wsEventMessage struct {
+	Type  string           `json:"type"`
+	ID    uint64           `json:"id,string,omitempty"`
+	Event events.EventType `json:"event"`
+	Data  interface{}      `json:"data"`
+}
+
+// wsHeartbeatMessage keeps idle sockets open
+type wsHeartbeatMessage struct {
+	Type string `json:"type"`
+}
+
+// wsReplyMessage holds the response to the command with the same id,
+// status, header and body are the ones of the equivalent http request
+type wsReplyMessage struct {
+	Type   string            `json:"type"`
+	ID     string            `json:"id,omitempty"`
+	Status int               `json:"status"`
+	Header map[string]string `json:"header,omitempty"`
+	Body   json.RawMessage   `json:"body,omitempty"`
+}
+
+// wsCommandFunc performs a websocket command like the equivalent http request,
+// it returns the body and the headers of a successful response
+type wsCommandFunc func(
+	h *handler,
+	ctx context.Context,
+	sessionID, username string,
+	args map[string]string,
+) (interface{}, map[string]string, *requestError)
+
+type wsCommand struct {
+	action session.Action
+	args   []string
+	run    wsCommandFunc
+}
+
+var wsCommands = map[string]wsCommand{
+	"vote": {
+		action: session.ActionVote,
+		args:   []string{"song_id", "vote_action"},
+		run:    (*handler).wsVote,
+	},
+	"suggest": {
+		action: session.ActionSuggest,
+		args:   []string{"song_id"},
+		run:    (*handler).wsSuggest,
+	},
+	"play": {
+		action: session.ActionPlayPause,
+		run:    (*handler).wsPlay,
+	},
+	"pause": {
+		action: session.ActionPlayPause,
+		run:    (*handler).wsPause,
+	},
+	"seek": {
+		action: session.ActionSeek,
+		args:   []string{"position_ms"},
+		run:    (*handler).wsSeek,
+	},
+}
+
+// wsConn serializes the writes of the event and command loops
+type wsConn struct {
+	*websocket.Conn
+	mutex sync.Mutex
+}
+
+func (c *wsConn) send(message interface{}) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return websocket.JSON.Send(c.Conn, message)
+}
+
+// ServeWS handles websocket connections at the "/ws/{username}/{session_id}" URL.
+// The socket streams the same events as the sse endpoint and accepts commands.
+func (h *handler) ServeWS(w http.ResponseWriter, r *http.Request) {
+	// clients are authenticated with their secret, so cross origin sockets are fine
+	server := websocket.Server{Handler: h.serveWSConn}
+	server.ServeHTTP(w, r)
+}
+
+func (h *handler) serveWSConn(ws *websocket.Conn) {
+	defer ws.Close()
+	r := ws.Request()
+	ctx := r.Context()
+	msg := "[ws] serve websocket"
+
+	vars := mux.Vars(r)
+	sessionID := vars["session_id"]
+	username := vars["username"]
+	userID := user.GenerateUserID(username, sessionID)
+	conn := &wsConn{Conn: ws}
+
+	// the first message has to authenticate the user
+	var auth wsClientMessage
+	if err := ws.SetReadDeadline(time.Now().Add(wsAuthTimeout)); err != nil {
+		log.Errorf("%v: %v", msg, err)
+		return
+	}
+	if err := websocket.JSON.Receive(ws, &auth); err != nil {
+		log.Infof("%v: %v", msg, err)
+		return
+	}
+	if auth.Type != wsMessageAuth {
+		logError(log.WarnLevel, msg, ErrWSNotAuthenticated)
+		conn.replyWith(msg, "", http.StatusUnauthorized, RequestNotAuthorizedError)
+		return
+	}
+	if _, reqErr := authorizeUser(
+		context.Background(),
+		h.UserCollection,
+		username,
+		sessionID,
+		auth.Secret,
+		staticRole(user.RoleGuest),
+		false,
+	); reqErr != nil {
+		conn.replyError(msg, "", reqErr)
+		return
+	}
+	if err := ws.SetReadDeadline(time.Time{}); err != nil {
+		log.Errorf("%v: %v", msg, err)
+		return
+	}
+
+	if err := h.connect(ctx, msg, userID, sessionID); err != nil {
+		status, level, frontendErr := connectError(err)
+		logError(level, msg, err)
+		conn.replyWith(msg, "", status, frontendErr)
+		return
+	}
+	// subscribe to changes
+	eventTypes := streamEventTypes(auth.Playlist == fullPlaylistMode)
+	groups := streamGroups(sessionID, username)
+	sub := h.eventBus.Subscribe(eventTypes, groups)
+	conn.replyWith(msg, "", http.StatusOK, nil)
+	var unsubscribe sync.Once
+	stop := func() {
+		unsubscribe.Do(func() {
+			h.eventBus.Unsubscribe(sub)
+			h.disconnect(msg, userID, sessionID)
+		})
+	}
+	defer stop()
+
+	// read commands until the client disconnects
+	go func() {
+		defer stop()
+		for {
+			var command wsClientMessage
+			if err := websocket.JSON.Receive(ws, &command); err != nil {
+				log.Infof("%v: connection closed: %v", msg, err)
+				return
+			}
+			h.handleWSCommand(context.Background(), conn, msg, username, sessionID, auth.Secret, command)
+		}
+	}()
+
+	// forward sends an event to the client, it returns false if the stream has to end
+	forward := func(event events.Event) bool {
+		err := conn.send(wsEventMessage{Type: wsMessageEvent, ID: event.ID, Event: event.Type, Data: event.Data})
+		if err != nil {
+			log.Infof("%v: %v", msg, err)
+			return false
+		}
+		return !endsStream(event, username)
+	}
+	heartbeat := func() error {
+		return conn.send(wsHeartbeatMessage{Type: wsMessageHeartbeat})
+	}
+
+	stopped := false
+	covered, catchUp := h.catchUp(ctx, msg, sessionID, auth.LastEventID.String(), eventTypes, groups)
+	for _, event := range catchUp {
+		if !forward(event) {
+			stopped = true
+			break
+		}
+	}
+
+	if !stopped {
+		followEvents(msg, config.Conf.Events, sub.Channel, covered, forward, heartbeat)
+	}
+
+	log.Infof("%v: %v", msg, r.URL.Path)
+}
+
+// handleWSCommand authorizes and performs a command and replies with the response of the equivalent http request
+func (h *handler) handleWSCommand(
+	ctx context.Context,
+	conn *wsConn,
+	msg, username, sessionID, secret string,
+	command wsClientMessage,
+) {
+	if command.Type != wsMessageCommand {
+		logError(log.InfoLevel, msg, fmt.Errorf("unexpected message type %q", command.Type))
+		conn.replyWith(msg, command.ID, http.StatusBadRequest, RequestBodyMalformedError)
+		return
+	}
+	cmd, ok := wsCommands[command.Command]
+	if !ok {
+		logError(log.InfoLevel, msg, fmt.Errorf("%w: %v", ErrUnknownCommand, command.Command))
+		conn.replyWith(msg, command.ID, http.StatusBadRequest, RequestBodyMalformedError)
+		return
+	}
+
+	// the role and the permissions may have changed since the socket was opened
+	requiredRole := actionRole(h.SessionCollection, cmd.action)
+	if _, reqErr := authorizeUser(ctx, h.UserCollection, username, sessionID, secret, requiredRole, false); reqErr != nil {
+		conn.replyError(msg, command.ID, reqErr)
+		return
+	}
+
+	args := make(map[string]string, len(cmd.args))
+	for _, name := range cmd.args {
+		arg, err := wsArg(command.Args[name])
+		if err != nil {
+			logError(log.InfoLevel, msg, fmt.Errorf("%v argument %v: %w", command.Command, name, err))
+			conn.replyWith(msg, command.ID, http.StatusBadRequest, RequestUrlMalformedError)
+			return
+		}
+		args[name] = arg
+	}
+
+	body, header, reqErr := cmd.run(h, ctx, sessionID, username, args)
+	if reqErr != nil {
+		conn.replyError(msg, command.ID, reqErr)
+		return
+	}
+	conn.reply(msg, wsReplyMessage{
+		Type:   wsMessageReply,
+		ID:     command.ID,
+		Status: http.StatusOK,
+		Header: header,
+		Body:   marshalBody(msg, body),
+	})
+}
+
+func (h *handler) wsVote(
+	ctx context.Context,
+	sessionID, username string,
+	args map[string]string,
+) (interface{}, map[string]string, *requestError) {
+	songList, removalReason, reqErr := h.vote(ctx, "[ws] vote", sessionID, username, args["song_id"], args["vote_action"])
+	if reqErr != nil || removalReason == "" {
+		return songList, nil, reqErr
+	}
+	return songList, map[string]string{removalReasonHeader: string(removalReason)}, nil
+}
+
+func (h *handler) wsSuggest(
+	ctx context.Context,
+	sessionID, username string,
+	args map[string]string,
+) (interface{}, map[string]string, *requestError) {
+	songInfo, reqErr := h.suggestSong(ctx, "[ws] suggest song", sessionID, username, args["song_id"])
+	return songInfo, nil, reqErr
+}
+
+func (h *handler) wsPlay(
+	ctx context.Context,
+	sessionID, username string,
+	_ map[string]string,
+) (interface{}, map[string]string, *requestError) {
+	h.setPaused(ctx, sessionID, username, false)
+	return nil, nil, nil
+}
+
+func (h *handler) wsPause(
+	ctx context.Context,
+	sessionID, username string,
+	_ map[string]string,
+) (interface{}, map[string]string, *requestError) {
+	h.setPaused(ctx, sessionID, username, true)
+	return nil, nil, nil
+}
+
+func (h *handler) wsSeek(
+	ctx context.Context,
+	sessionID, username string,
+	args map[string]string,
+) (interface{}, map[string]string, *requestError) {
+	return nil, nil, h.seek(ctx, "[ws] seek", sessionID, username, args["position_ms"])
+}
+
+// wsArg converts a command argument to the string of the equivalent url variable
+func wsArg(raw json.RawMessage) (string, error) {
+	if len(raw) == 0 {
+		return "", errors.New("missing")
+	}
+	var arg string
+	if err := json.Unmarshal(raw, &arg); err == nil {
+		return arg, nil
+	}
+	var number json.Number
+	if err := json.Unmarshal(raw, &number); err != nil {
+		return "", err
+	}
+	return number.String(), nil
+}
+
+// replyWith replies with the json encoding of payload
+func (c *wsConn) replyWith(msg, id string, status int, payload interface{}) {
+	c.reply(msg, wsReplyMessage{Type: wsMessageReply, ID: id, Status: status, Body: marshalBody(msg, payload)})
+}
+
+// replyError logs the error and replies with it
+func (c *wsConn) replyError(msg, id string, reqErr *requestError) {
+	logError(reqErr.level, msg, reqErr.err)
+	c.replyWith(msg, id, reqErr.status, reqErr.body)
+}
+
+// marshalBody returns the json encoding of payload, nil if there is no payload
+func marshalBody(msg string, payload interface{}) json.RawMessage {
+	if payload == nil {
+		return nil
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		log.Errorf("%v: %v", msg, err)
+		return nil
+	}
+	return body
+}
+
+func (c *wsConn) reply(msg string, reply wsReplyMessage) {
+	if err := c.send(reply); err != nil {
+		log.Infof("%v: %v", msg, err)
+	}
+}
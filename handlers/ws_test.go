@@ -0,0 +1,251 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/encore-fm/backend/config"
+	"github.com/encore-fm/backend/db"
+	"github.com/encore-fm/backend/db/mocks"
+	"github.com/encore-fm/backend/events"
+	"github.com/encore-fm/backend/playerctrl"
+	"github.com/encore-fm/backend/session"
+	"github.com/encore-fm/backend/sse"
+	"github.com/encore-fm/backend/user"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"golang.org/x/net/websocket"
+)
+
+// test that a socket is closed if the first message carries a wrong secret
+func TestHandler_ServeWS_WrongSecret(t *testing.T) {
+	sessionID := "session_id"
+	username := "username"
+
+	usr, err := user.New(username, sessionID)
+	assert.NoError(t, err)
+
+	// set up userCollection mock
+	var userCollection db.UserCollection
+	userCollection = &mocks.UserCollection{}
+	userCollection.(*mocks.UserCollection).
+		On("GetUserByID", context.Background(), usr.ID).
+		Return(usr, nil)
+
+	eventBus := events.NewEventBus()
+	eventBus.Start()
+	handler := &handler{
+		UserCollection: userCollection,
+		eventBus:       eventBus,
+	}
+
+	r := mux.NewRouter()
+	r.Handle("/ws/{username}/{session_id}", http.HandlerFunc(WSHandler(handler).ServeWS))
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws/" + username + "/" + sessionID
+	ws, err := websocket.Dial(url, "", server.URL)
+	assert.NoError(t, err)
+	defer ws.Close()
+
+	err = websocket.JSON.Send(ws, wsClientMessage{Type: wsMessageAuth, Secret: "wrong secret"})
+	assert.NoError(t, err)
+
+	var reply wsReplyMessage
+	err = websocket.JSON.Receive(ws, &reply)
+	assert.NoError(t, err)
+	assert.Equal(t, wsMessageReply, reply.Type)
+	assert.Equal(t, http.StatusUnauthorized, reply.Status)
+
+	var frontendErr FrontendError
+	assert.NoError(t, json.Unmarshal(reply.Body, &frontendErr))
+	assert.Equal(t, RequestNotAuthorizedError, frontendErr)
+
+	// the server closes the socket after a failed authentication
+	var next wsClientMessage
+	assert.Error(t, websocket.JSON.Receive(ws, &next))
+}
+
+func TestWSArg(t *testing.T) {
+	testCases := []struct {
+		raw      string
+		expected string
+		err      bool
+	}{
+		{raw: `"song"`, expected: "song"},
+		{raw: `1500`, expected: "1500"},
+		{raw: ``, err: true},
+		{raw: `{"a": 1}`, err: true},
+	}
+
+	for _, tc := range testCases {
+		arg, err := wsArg(json.RawMessage(tc.raw))
+		if tc.err {
+			assert.Error(t, err, tc.raw)
+			continue
+		}
+		assert.NoError(t, err, tc.raw)
+		assert.Equal(t, tc.expected, arg)
+	}
+}
+
+// test that event ids are sent as strings, so javascript clients read them without losing precision
+func TestWSEventMessage_ID(t *testing.T) {
+	id := uint64(1)<<53 + 1
+	raw, err := json.Marshal(wsEventMessage{Type: wsMessageEvent, ID: id, Event: "event"})
+	assert.NoError(t, err)
+	assert.Contains(t, string(raw), `"id":"9007199254740993"`)
+
+	for _, lastEventID := range []string{`"9007199254740993"`, `9007199254740993`} {
+		var auth wsClientMessage
+		assert.NoError(t, json.Unmarshal([]byte(`{"type": "auth", "last_event_id": `+lastEventID+`}`), &auth))
+		assert.Equal(t, "9007199254740993", auth.LastEventID.String())
+	}
+}
+
+// test that commands are authorized with the session's permissions and performed without an http request
+func TestHandler_HandleWSCommand(t *testing.T) {
+	sessionID := "session_id"
+	username := "username"
+
+	usr, err := user.New(username, sessionID)
+	assert.NoError(t, err)
+
+	userCollection := &mocks.UserCollection{}
+	userCollection.On("GetUserByID", context.Background(), usr.ID).Return(usr, nil)
+
+	settings := session.DefaultSettings()
+	settings.Permissions = session.Permissions{session.ActionPlayPause: user.RoleGuest}
+	sessionCollection := &mocks.SessionCollection{}
+	sessionCollection.On("GetSettings", context.Background(), sessionID).Return(settings, nil)
+	sessionCollection.On("SetLastUpdated", context.Background(), sessionID).Return()
+
+	eventBus := events.NewEventBus()
+	eventBus.Start()
+	defer eventBus.Stop()
+	sub := eventBus.Subscribe([]events.EventType{playerctrl.PlayPauseEvent}, []events.GroupID{events.GroupID(sessionID)})
+
+	handler := &handler{
+		UserCollection:    userCollection,
+		SessionCollection: sessionCollection,
+		eventBus:          eventBus,
+	}
+
+	server := httptest.NewServer(websocket.Handler(func(ws *websocket.Conn) {
+		conn := &wsConn{Conn: ws}
+		for {
+			var command wsClientMessage
+			if err := websocket.JSON.Receive(ws, &command); err != nil {
+				return
+			}
+			handler.handleWSCommand(context.Background(), conn, "[test]", username, sessionID, usr.Secret, command)
+		}
+	}))
+	defer server.Close()
+
+	ws, err := websocket.Dial("ws"+strings.TrimPrefix(server.URL, "http"), "", server.URL)
+	assert.NoError(t, err)
+	defer ws.Close()
+
+	command := func(id, name string, args map[string]json.RawMessage) wsReplyMessage {
+		err := websocket.JSON.Send(ws, wsClientMessage{Type: wsMessageCommand, ID: id, Command: name, Args: args})
+		assert.NoError(t, err)
+		var reply wsReplyMessage
+		assert.NoError(t, websocket.JSON.Receive(ws, &reply))
+		assert.Equal(t, id, reply.ID)
+		return reply
+	}
+
+	// seeking is left to the owner
+	reply := command("1", "seek", map[string]json.RawMessage{"position_ms": json.RawMessage(`1000`)})
+	assert.Equal(t, http.StatusUnauthorized, reply.Status)
+	var frontendErr FrontendError
+	assert.NoError(t, json.Unmarshal(reply.Body, &frontendErr))
+	assert.Equal(t, ActionNotAllowedError, frontendErr)
+
+	reply = command("2", "pause", nil)
+	assert.Equal(t, http.StatusOK, reply.Status)
+	event := <-sub.Channel
+	assert.Equal(t, playerctrl.PlayPausePayload{Paused: true}, event.Data)
+}
+
+// test that a socket catching up leaves out the notifications of other users of the session
+func TestHandler_ServeWS_CatchUp(t *testing.T) {
+	sessionID := "session_id"
+	username := "bob"
+
+	// connecting reads the stream limits, zero means no limit
+	conf := config.Conf
+	config.Conf = &config.Config{Events: &config.EventsConfig{}}
+	defer func() { config.Conf = conf }()
+
+	usr, err := user.New(username, sessionID)
+	assert.NoError(t, err)
+
+	userCollection := &mocks.UserCollection{}
+	userCollection.On("GetUserByID", context.Background(), usr.ID).Return(usr, nil)
+	userCollection.On("AddSSEConnection", mock.Anything, usr.ID).Return(1, nil)
+	userCollection.On("RemoveSSEConnection", mock.Anything, usr.ID).Return(0, nil)
+
+	eventBus := events.NewEventBus()
+	eventBus.Record(sse.ClientEventTypes()...)
+	eventBus.Start()
+	defer eventBus.Stop()
+	handler := &handler{
+		UserCollection: userCollection,
+		eventBus:       eventBus,
+	}
+
+	groupID := events.GroupID(sessionID)
+	published := publishRecorded(t, eventBus,
+		events.Event{Type: sse.UserListChange, GroupID: groupID, Data: "before"},
+		events.Event{Type: sse.UserNotification, GroupID: events.UserGroupID(sessionID, "alice"), Data: "private"},
+		events.Event{Type: sse.UserListChange, GroupID: groupID, Data: "public"},
+	)
+
+	// the config is restored once the socket has been served
+	served := make(chan struct{})
+	r := mux.NewRouter()
+	r.HandleFunc("/ws/{username}/{session_id}", func(w http.ResponseWriter, r *http.Request) {
+		defer close(served)
+		WSHandler(handler).ServeWS(w, r)
+	})
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws/" + username + "/" + sessionID
+	ws, err := websocket.Dial(url, "", server.URL)
+	assert.NoError(t, err)
+
+	lastEventID := json.Number(strconv.FormatUint(published[0].ID, 10))
+	err = websocket.JSON.Send(ws, wsClientMessage{Type: wsMessageAuth, Secret: usr.Secret, LastEventID: lastEventID})
+	assert.NoError(t, err)
+
+	var reply wsReplyMessage
+	err = websocket.JSON.Receive(ws, &reply)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, reply.Status)
+
+	var event wsEventMessage
+	err = websocket.JSON.Receive(ws, &event)
+	assert.NoError(t, err)
+	assert.Equal(t, wsMessageEvent, event.Type)
+	assert.Equal(t, published[2].ID, event.ID)
+	assert.Equal(t, sse.UserListChange, event.Event)
+	assert.Equal(t, "public", event.Data)
+
+	assert.NoError(t, ws.Close())
+	select {
+	case <-served:
+	case <-time.After(time.Second):
+		t.Fatal("socket has not been closed")
+	}
+}
@@ -2,14 +2,17 @@ package main
 
 import (
 	"context"
+	"time"
 
 	"github.com/encore-fm/backend/config"
 	"github.com/encore-fm/backend/db"
 	"github.com/encore-fm/backend/events"
 	"github.com/encore-fm/backend/garbagecoll"
 	"github.com/encore-fm/backend/playerctrl"
+	"github.com/encore-fm/backend/recommend"
 	"github.com/encore-fm/backend/server"
 	"github.com/encore-fm/backend/spotifycl"
+	"github.com/encore-fm/backend/sse"
 	_ "github.com/heroku/x/hmetrics/onload"
 	log "github.com/sirupsen/logrus"
 	"github.com/zmb3/spotify"
@@ -24,6 +27,9 @@ func spotifyAuthSetup() spotify.Authenticator {
 		spotify.ScopeUserReadPrivate,
 		spotify.ScopeUserReadPlaybackState,
 		spotify.ScopeUserTopRead,
+		// required to export the session history as a playlist
+		spotify.ScopePlaylistModifyPrivate,
+		spotify.ScopePlaylistModifyPublic,
 	)
 	spotifyAuth.SetAuthInfo(
 		config.Conf.Spotify.ClientID,
@@ -37,7 +43,9 @@ func main() {
 
 	// init event bus
 	eventBus := events.NewEventBus()
+	eventBus.Record(sse.ClientEventTypes()...)
 	eventBus.Start()
+	playlists := sse.NewPlaylistTracker(eventBus)
 
 	// connect to database
 	dbConn, err := db.New()
@@ -60,8 +68,26 @@ func main() {
 		log.Fatalf("[startup] resetting sse connections: %v", err)
 	}
 
+	// create the track metadata cache, backed by the database if a collection is configured
+	var trackStore spotifycl.TrackStore
+	if config.Conf.Database.TrackCollectionName != "" {
+		trackStore = db.NewTrackCollection(dbConn.Client)
+	}
+	trackCache := spotifycl.NewTrackCache(
+		config.Conf.Spotify.TrackCacheSize,
+		time.Duration(config.Conf.Spotify.TrackCacheTTLInS)*time.Second,
+		trackStore,
+		config.Conf.Spotify.TrackCacheBypass,
+	)
+
 	// create spotify client
-	spotifyClient, err := spotifycl.New(config.Conf.Spotify.ClientID, config.Conf.Spotify.ClientSecret)
+	spotifyClient, err := spotifycl.New(
+		config.Conf.Spotify.ClientID,
+		config.Conf.Spotify.ClientSecret,
+		config.Conf.Spotify.SearchCacheSize,
+		time.Duration(config.Conf.Spotify.SearchCacheTTLInS)*time.Second,
+		trackCache,
+	)
 	if err != nil {
 		log.Fatalf("[startup] creating spotify client: %v", err)
 	}
@@ -74,11 +100,13 @@ func main() {
 	// create controller
 	playerCtrl := playerctrl.NewController(
 		eventBus,
+		playlists,
 		sessDB,
 		songDB,
 		userDB,
 		playerDB,
 		spotifyAuth,
+		recommend.NewSpotifySource(spotifyClient),
 	)
 	if err := playerCtrl.Start(); err != nil {
 		log.Fatalf("[startup] starting player controller: %v", err)
@@ -90,6 +118,6 @@ func main() {
 	log.Info("[startup] successfully started session garbage collector")
 
 	// start server
-	svr := server.New(eventBus, userDB, sessDB, songDB, playerDB, spotifyAuth, spotifyClient)
+	svr := server.New(eventBus, playlists, userDB, sessDB, songDB, playerDB, spotifyAuth, spotifyClient)
 	svr.Start()
 }
@@ -0,0 +1,51 @@
+package player
+
+import (
+	"time"
+
+	"github.com/encore-fm/backend/song"
+)
+
+// HistoryLen is the number of played songs kept per session with all their details
+const HistoryLen = 500
+
+// SetlistLen is the number of played songs kept per session for the playlist export,
+// enough for the setlist of a party running for days
+const SetlistLen = 5000
+
+// HistoryEntry is a song that has been played in a session.
+// The song keeps its suggester and the score it had when it left the queue.
+type HistoryEntry struct {
+	Song    *song.Model `json:"song" bson:"song"`
+	Started time.Time   `json:"started" bson:"started"`
+	Skipped bool        `json:"skipped" bson:"skipped"`
+}
+
+// SetlistEntry is the lightweight record of a played song that is kept for the playlist export
+type SetlistEntry struct {
+	SongID  string    `json:"song_id" bson:"song_id"`
+	Started time.Time `json:"started" bson:"started"`
+}
+
+// NewHistoryEntry archives the current song of a player
+func NewHistoryEntry(p *Player, skipped bool) *HistoryEntry {
+	return &HistoryEntry{
+		Song:    p.CurrentSong,
+		Started: p.SongStart,
+		Skipped: skipped,
+	}
+}
+
+// PlayedWithin returns the most recent entry of songID that started after since, or nil
+func PlayedWithin(history []*HistoryEntry, songID string, since time.Time) *HistoryEntry {
+	var latest *HistoryEntry
+	for _, entry := range history {
+		if entry.Song == nil || entry.Song.ID != songID || entry.Started.Before(since) {
+			continue
+		}
+		if latest == nil || entry.Started.After(latest.Started) {
+			latest = entry
+		}
+	}
+	return latest
+}
@@ -0,0 +1,31 @@
+package player
+
+import (
+	"testing"
+	"time"
+
+	"github.com/encore-fm/backend/song"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlayedWithin(t *testing.T) {
+	now := time.Now()
+	old := &HistoryEntry{Song: &song.Model{ID: "song_id"}, Started: now.Add(-2 * time.Hour)}
+	recent := &HistoryEntry{Song: &song.Model{ID: "song_id"}, Started: now.Add(-10 * time.Minute)}
+	other := &HistoryEntry{Song: &song.Model{ID: "other"}, Started: now.Add(-time.Minute)}
+	history := []*HistoryEntry{old, recent, other}
+
+	assert.Equal(t, recent, PlayedWithin(history, "song_id", now.Add(-time.Hour)))
+	assert.Nil(t, PlayedWithin(history, "song_id", now.Add(-5*time.Minute)))
+	assert.Nil(t, PlayedWithin(history, "unknown", now.Add(-time.Hour)))
+}
+
+func TestNewHistoryEntry(t *testing.T) {
+	start := time.Now()
+	p := &Player{CurrentSong: &song.Model{ID: "song_id", SuggestedBy: "anton", Score: 3}, SongStart: start}
+
+	entry := NewHistoryEntry(p, true)
+	assert.Equal(t, "song_id", entry.Song.ID)
+	assert.Equal(t, start, entry.Started)
+	assert.True(t, entry.Skipped)
+}
@@ -1,6 +1,7 @@
 package player
 
 import (
+	"math"
 	"time"
 
 	"github.com/encore-fm/backend/song"
@@ -13,6 +14,8 @@ type Player struct {
 	PauseStart    time.Time     `json:"pause_start" bson:"pause_start"`
 	PauseDuration time.Duration `json:"pause_duration" bson:"pause_duration"`
 	Paused        bool          `json:"paused" bson:"paused"`
+	// usernames of the users that voted to skip the current song, reset with every new song
+	SkipVotes []string `json:"skip_votes" bson:"skip_votes,omitempty"`
 }
 
 func New() *Player {
@@ -31,3 +34,13 @@ func (p *Player) Progress() time.Duration {
 	}
 	return p.PauseStart.Sub(p.SongStart) - p.PauseDuration
 }
+
+// SkipThreshold returns the number of skip votes needed to skip the current song
+// if the given fraction of listeners has to agree. At least one vote is always required.
+func SkipThreshold(fraction float64, listeners int) int {
+	threshold := int(math.Ceil(fraction * float64(listeners)))
+	if threshold < 1 {
+		return 1
+	}
+	return threshold
+}
@@ -34,3 +34,11 @@ func TestPlayer_Progress_Paused(t *testing.T) {
 	fmt.Println(player.Progress())
 	assert.WithinDuration(t, now.Add(player.Progress()), now.Add(1*time.Minute), 200*time.Millisecond)
 }
+
+func TestSkipThreshold(t *testing.T) {
+	assert.Equal(t, 1, SkipThreshold(0.5, 0))
+	assert.Equal(t, 1, SkipThreshold(0.5, 1))
+	assert.Equal(t, 2, SkipThreshold(0.5, 3))
+	assert.Equal(t, 2, SkipThreshold(0.5, 4))
+	assert.Equal(t, 4, SkipThreshold(1, 4))
+}
@@ -0,0 +1,112 @@
+package playerctrl
+
+import (
+	"context"
+	"testing"
+
+	"github.com/encore-fm/backend/db/mocks"
+	"github.com/encore-fm/backend/events"
+	"github.com/encore-fm/backend/player"
+	"github.com/encore-fm/backend/recommend"
+	"github.com/encore-fm/backend/session"
+	"github.com/encore-fm/backend/song"
+	"github.com/encore-fm/backend/sse"
+	"github.com/stretchr/testify/assert"
+	"github.com/zmb3/spotify"
+)
+
+// fakeSource recommends the first of its songs that is not excluded
+type fakeSource struct {
+	songs []*song.Model
+	seeds []*song.Model
+}
+
+func (f *fakeSource) Recommend(seeds []*song.Model, exclude map[string]bool, accept recommend.Filter) (*song.Model, error) {
+	f.seeds = seeds
+	for _, s := range f.songs {
+		if !exclude[s.ID] && accept(s) {
+			return s, nil
+		}
+	}
+	return nil, recommend.ErrNoRecommendation
+}
+
+func newAutopilotController(autopilot bool, history []*player.HistoryEntry, source recommend.Source) *Controller {
+	settings := session.DefaultSettings()
+	settings.Autopilot = autopilot
+	return newAutopilotControllerWithSettings(settings, history, source)
+}
+
+func newAutopilotControllerWithSettings(
+	settings *session.Settings,
+	history []*player.HistoryEntry,
+	source recommend.Source,
+) *Controller {
+	sessionID := "session_id"
+
+	sessionCollection := &mocks.SessionCollection{}
+	sessionCollection.On("GetSettings", context.Background(), sessionID).Return(settings, nil)
+
+	playerCollection := &mocks.PlayerCollection{}
+	playerCollection.On("GetHistory", context.Background(), sessionID).Return(history, nil)
+
+	eventBus := events.NewEventBus()
+	return NewController(
+		eventBus,
+		sse.NewPlaylistTracker(eventBus),
+		sessionCollection,
+		&mocks.SongCollection{},
+		&mocks.UserCollection{},
+		playerCollection,
+		spotify.Authenticator{},
+		source,
+	)
+}
+
+func TestController_RecommendSong(t *testing.T) {
+	history := []*player.HistoryEntry{
+		{Song: &song.Model{ID: "played"}},
+	}
+	source := &fakeSource{songs: []*song.Model{{ID: "played"}, {ID: "new"}}}
+	ctrl := newAutopilotController(true, history, source)
+
+	recommendation := ctrl.recommendSong(context.Background(), "session_id")
+
+	assert.NotNil(t, recommendation)
+	assert.Equal(t, "new", recommendation.ID)
+	assert.Equal(t, recommend.SystemUser, recommendation.SuggestedBy)
+	assert.Equal(t, []*song.Model{history[0].Song}, source.seeds)
+}
+
+func TestController_RecommendSong_AutopilotOff(t *testing.T) {
+	source := &fakeSource{songs: []*song.Model{{ID: "new"}}}
+	ctrl := newAutopilotController(false, nil, source)
+
+	assert.Nil(t, ctrl.recommendSong(context.Background(), "session_id"))
+}
+
+func TestController_RecommendSong_NothingLeft(t *testing.T) {
+	history := []*player.HistoryEntry{
+		{Song: &song.Model{ID: "played"}},
+	}
+	source := &fakeSource{songs: []*song.Model{{ID: "played"}}}
+	ctrl := newAutopilotController(true, history, source)
+
+	assert.Nil(t, ctrl.recommendSong(context.Background(), "session_id"))
+}
+
+func TestController_RecommendSong_ContentRules(t *testing.T) {
+	history := []*player.HistoryEntry{
+		{Song: &song.Model{ID: "played"}},
+	}
+	settings := session.DefaultSettings()
+	settings.Autopilot = true
+	settings.ExplicitFilter = true
+	source := &fakeSource{songs: []*song.Model{{ID: "explicit", Explicit: true}, {ID: "clean"}}}
+	ctrl := newAutopilotControllerWithSettings(settings, history, source)
+
+	recommendation := ctrl.recommendSong(context.Background(), "session_id")
+
+	assert.NotNil(t, recommendation)
+	assert.Equal(t, "clean", recommendation.ID)
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/encore-fm/backend/db"
 	"github.com/encore-fm/backend/events"
 	"github.com/encore-fm/backend/player"
+	"github.com/encore-fm/backend/recommend"
 	"github.com/encore-fm/backend/song"
 	"github.com/encore-fm/backend/sse"
 	log "github.com/sirupsen/logrus"
@@ -27,19 +28,30 @@ type Controller struct {
 	authenticator spotify.Authenticator
 
 	eventBus events.EventBus
+	// publishes the song lists of the sessions
+	playlists *sse.PlaylistTracker
 
 	// maps sessions to timers
 	// timer fires when current song ended and new song must be fetched from db
 	timers map[string]*time.Timer
+
+	// maps sessions to the start of the song that has last been skipped by vote
+	// prevents further votes from skipping again before the skip has been handled
+	skippedByVote map[string]time.Time
+
+	// picks songs for sessions with autopilot when their queue runs empty, may be nil
+	recommendations recommend.Source
 }
 
 func NewController(
 	eventBus events.EventBus,
+	playlists *sse.PlaylistTracker,
 	sessionCollection db.SessionCollection,
 	songCollection db.SongCollection,
 	userCollection db.UserCollection,
 	playerCollection db.PlayerCollection,
 	authenticator spotify.Authenticator,
+	recommendations recommend.Source,
 ) *Controller {
 	controller := &Controller{
 		sessionCollection: sessionCollection,
@@ -48,7 +60,10 @@ func NewController(
 		playerCollection:  playerCollection,
 		authenticator:     authenticator,
 		eventBus:          eventBus,
+		playlists:         playlists,
 		timers:            make(map[string]*time.Timer),
+		skippedByVote:     make(map[string]time.Time),
+		recommendations:   recommendations,
 	}
 	return controller
 }
@@ -95,10 +110,12 @@ func (ctrl *Controller) eventLoop() {
 	songAdded := ctrl.eventBus.Subscribe([]events.EventType{SongAdded}, []events.GroupID{events.GroupIDAny})
 	playPause := ctrl.eventBus.Subscribe([]events.EventType{PlayPauseEvent}, []events.GroupID{events.GroupIDAny})
 	skip := ctrl.eventBus.Subscribe([]events.EventType{SkipEvent}, []events.GroupID{events.GroupIDAny})
+	voteSkip := ctrl.eventBus.Subscribe([]events.EventType{VoteSkipEvent}, []events.GroupID{events.GroupIDAny})
 	seek := ctrl.eventBus.Subscribe([]events.EventType{SeekEvent}, []events.GroupID{events.GroupIDAny})
 	setSynchronized := ctrl.eventBus.Subscribe([]events.EventType{SetSynchronizedEvent}, []events.GroupID{events.GroupIDAny})
 	sseConnection := ctrl.eventBus.Subscribe([]events.EventType{SSEConnectionEvent}, []events.GroupID{events.GroupIDAny})
 	reset := ctrl.eventBus.Subscribe([]events.EventType{ResetEvent}, []events.GroupID{events.GroupIDAny})
+	sessionDeleted := ctrl.eventBus.Subscribe([]events.EventType{SessionDeletedEvent}, []events.GroupID{events.GroupIDAny})
 
 	for {
 		select {
@@ -111,6 +128,9 @@ func (ctrl *Controller) eventLoop() {
 		case ev := <-skip.Channel:
 			ctrl.handleSkip(ev)
 
+		case ev := <-voteSkip.Channel:
+			ctrl.handleVoteSkip(ev)
+
 		case ev := <-seek.Channel:
 			ctrl.handleSeek(ev)
 
@@ -122,6 +142,9 @@ func (ctrl *Controller) eventLoop() {
 
 		case ev := <-reset.Channel:
 			ctrl.handleReset(ev)
+
+		case ev := <-sessionDeleted.Channel:
+			ctrl.handleSessionDeleted(ev)
 		}
 	}
 }
@@ -148,6 +171,12 @@ func (ctrl *Controller) stopTimer(sessionID string) {
 // gets next song from db and deletes it
 // sends event
 func (ctrl *Controller) getNextSong(sessionID string) {
+	ctrl.playNextSong(sessionID, false)
+}
+
+// playNextSong moves the current song to the history and plays the next song of the queue,
+// skipped tells whether the current song was cut short
+func (ctrl *Controller) playNextSong(sessionID string, skipped bool) {
 	msg := "[playerctrl] get next song from db"
 	ctx := context.Background()
 	songList, err := ctrl.songCollection.ListSongs(ctx, sessionID)
@@ -162,28 +191,36 @@ func (ctrl *Controller) getNextSong(sessionID string) {
 		)
 		return
 	}
+
+	ctrl.archiveCurrentSong(ctx, sessionID, skipped)
+
+	var nextSong *song.Model
 	if len(songList) == 0 {
-		// if songList is empty
-		// reset player, log error and wait for songAdded
-		err = ctrl.playerCollection.SetPlayer(ctx, sessionID, player.New())
-		if err != nil {
-			log.Errorf("%v: %v", msg, err)
+		// if songList is empty, the autopilot may pick a song
+		nextSong = ctrl.recommendSong(ctx, sessionID)
+		if nextSong == nil {
+			// reset player, log error and wait for songAdded
+			err = ctrl.playerCollection.SetPlayer(ctx, sessionID, player.New())
+			if err != nil {
+				log.Errorf("%v: %v", msg, err)
+			}
+			// explicitly publish a skip event when playlist is empty, or else last song (in player) cannot get skipped
+			ctrl.notifyClientsBySessionID(sessionID, ctrl.playerSkipAction())
+			log.Warnf("%v: %v", msg, "songlist empty")
+			return
 		}
-		// explicitly publish a skip event when playlist is empty, or else last song (in player) cannot get skipped
-		ctrl.notifyClientsBySessionID(sessionID, ctrl.playerSkipAction())
-		log.Warnf("%v: %v", msg, "songlist empty")
-		return
-	}
+	} else {
+		// the song list is ordered by the queue ordering strategy of the session
+		nextSong = songList[0]
 
-	nextSong := songList[0]
+		// remove nextSong from db
+		if err := ctrl.songCollection.RemoveSong(ctx, sessionID, nextSong.ID); err != nil {
+			log.Errorf("%v: %v", msg, err)
+		}
 
-	// remove nextSong from db
-	if err := ctrl.songCollection.RemoveSong(ctx, sessionID, nextSong.ID); err != nil {
-		log.Errorf("%v: %v", msg, err)
+		ctrl.playlists.Publish(sessionID, songList[1:])
 	}
 
-	ctrl.eventBus.Publish(sse.PlaylistChange, events.GroupID(sessionID), songList[1:])
-
 	// fetch next song after song has ended
 	ctrl.setTimer(
 		sessionID,
@@ -215,6 +252,62 @@ func (ctrl *Controller) getNextSong(sessionID string) {
 	)
 }
 
+// recommendSong returns a song picked by the recommendation source if the autopilot of the session is on,
+// nil otherwise. Recommendations are based on the play history and never repeat a song of the history.
+// Like suggestions, they have to pass the content rules of the session.
+func (ctrl *Controller) recommendSong(ctx context.Context, sessionID string) *song.Model {
+	msg := "[playerctrl] recommend song"
+	if ctrl.recommendations == nil {
+		return nil
+	}
+
+	settings, err := ctrl.sessionCollection.GetSettings(ctx, sessionID)
+	if err != nil {
+		log.Errorf("%v: %v", msg, err)
+		return nil
+	}
+	if !settings.Autopilot {
+		return nil
+	}
+
+	history, err := ctrl.playerCollection.GetHistory(ctx, sessionID)
+	if err != nil {
+		log.Errorf("%v: %v", msg, err)
+		return nil
+	}
+
+	// the queue is empty, so there are no queued songs to check the artist limit against
+	accept := func(candidate *song.Model) bool {
+		return len(settings.CheckContent(candidate, nil)) == 0
+	}
+	recommendation, err := ctrl.recommendations.Recommend(recommend.Seeds(history), recommend.Played(history), accept)
+	if err != nil {
+		log.Warnf("%v: session [%v]: %v", msg, sessionID, err)
+		return nil
+	}
+	recommendation.SuggestedBy = recommend.SystemUser
+	log.Infof("%v: session [%v] song [%v]", msg, sessionID, recommendation.ID)
+	return recommendation
+}
+
+// adds the song in the player to the history of the session
+func (ctrl *Controller) archiveCurrentSong(ctx context.Context, sessionID string, skipped bool) {
+	msg := "[playerctrl] archive current song"
+	playr, err := ctrl.playerCollection.GetPlayer(ctx, sessionID)
+	if err != nil {
+		log.Errorf("%v: %v", msg, err)
+		return
+	}
+	if playr == nil || playr.CurrentSong == nil {
+		return
+	}
+	// a song that already ended can not be skipped anymore
+	skipped = skipped && !playr.IsEmpty()
+	if err := ctrl.playerCollection.AddHistoryEntry(ctx, sessionID, player.NewHistoryEntry(playr, skipped)); err != nil {
+		log.Errorf("%v: %v", msg, err)
+	}
+}
+
 // sends out a player state change event with relevant data about the current player state
 func (ctrl *Controller) notifyPlayerStateChange(sessionID string) {
 	msg := "[playerctrl] notify player state change"
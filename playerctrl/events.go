@@ -21,6 +21,16 @@ const SkipEvent events.EventType = "player_event:skip"
 
 type SkipPayload struct{}
 
+// define vote skip event, published whenever a user voted to skip the current song
+const VoteSkipEvent events.EventType = "player_event:vote_skip"
+
+type VoteSkipPayload struct {
+	SongID string `json:"song_id"`
+	// start of the song, tells apart several plays of the same song
+	SongStart time.Time `json:"song_start"`
+	Votes     int       `json:"votes"`
+}
+
 // define seek event
 const SeekEvent events.EventType = "player_event:seek"
 
@@ -44,6 +54,11 @@ type SSEConnectionPayload struct {
 	ConnectionEstablished bool
 }
 
+// define session deleted event, published after a session has been deleted
+const SessionDeletedEvent events.EventType = "player_event:session_deleted"
+
+type SessionDeletedPayload struct{}
+
 // define reset event
 const ResetEvent events.EventType = "player_event:reset_session"
 
@@ -99,7 +99,7 @@ func (ctrl *Controller) handleSkip(ev events.Event) {
 		return
 	}
 
-	ctrl.getNextSong(sessionID)
+	ctrl.playNextSong(sessionID, true)
 
 	// send out a player state change event
 	ctrl.notifyPlayerStateChange(sessionID)
@@ -107,6 +107,45 @@ func (ctrl *Controller) handleSkip(ev events.Event) {
 	log.Infof("%v: type={%v} id={%v}", msg, ev.Type, ev.GroupID)
 }
 
+// pushes the skip vote tally and skips the current song once enough synchronized users voted for it
+func (ctrl *Controller) handleVoteSkip(ev events.Event) {
+	msg := "[playerctrl] handle vote skip"
+	ctx := context.Background()
+	sessionID := string(ev.GroupID)
+	payload, ok := ev.Data.(VoteSkipPayload)
+	if !ok {
+		log.Errorf("%v: %v", msg, ErrEventPayloadMalformed)
+		return
+	}
+
+	settings, err := ctrl.sessionCollection.GetSettings(ctx, sessionID)
+	if err != nil {
+		log.Errorf("%v: %v", msg, err)
+		return
+	}
+	clients, err := ctrl.userCollection.GetSyncedSpotifyClients(ctx, sessionID)
+	if err != nil {
+		log.Errorf("%v: %v", msg, err)
+		return
+	}
+	required := player.SkipThreshold(settings.VotingRules.GetSkipFraction(), len(clients))
+
+	ctrl.eventBus.Publish(
+		sse.SkipVoteChange,
+		events.GroupID(sessionID),
+		sse.SkipVoteChangePayload{SongID: payload.SongID, Votes: payload.Votes, Required: required},
+	)
+
+	if payload.Votes < required || ctrl.skippedByVote[sessionID].Equal(payload.SongStart) {
+		return
+	}
+	ctrl.skippedByVote[sessionID] = payload.SongStart
+
+	ctrl.eventBus.Publish(SkipEvent, events.GroupID(sessionID), SkipPayload{})
+
+	log.Infof("%v: skipping song [%v] in session [%v] with %v/%v votes", msg, payload.SongID, sessionID, payload.Votes, required)
+}
+
 func (ctrl *Controller) handleSeek(ev events.Event) {
 	ctx := context.Background()
 	msg := "[playerctrl] handle seek"
@@ -336,3 +375,16 @@ func (ctrl *Controller) handleReset(ev events.Event) {
 	log.Infof("%v: id={%v}", msg, payload.SessionID)
 	ctrl.setTimer(payload.SessionID, 0, func() { ctrl.getNextSong(payload.SessionID) })
 }
+
+// forgets the state kept for a deleted session
+func (ctrl *Controller) handleSessionDeleted(ev events.Event) {
+	msg := "[playerctrl] handle session deleted"
+	sessionID := string(ev.GroupID)
+
+	delete(ctrl.skippedByVote, sessionID)
+	// every way of deleting a session publishes this event, so the session's events are cleaned up here
+	ctrl.playlists.Remove([]string{sessionID})
+	ctrl.eventBus.RemoveGroups([]events.GroupID{ev.GroupID})
+
+	log.Infof("%v: id={%v}", msg, sessionID)
+}
@@ -0,0 +1,85 @@
+package playerctrl
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/encore-fm/backend/db/mocks"
+	"github.com/encore-fm/backend/events"
+	"github.com/encore-fm/backend/session"
+	"github.com/encore-fm/backend/song"
+	"github.com/encore-fm/backend/sse"
+	"github.com/encore-fm/backend/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/zmb3/spotify"
+)
+
+// test that every play of a song can be skipped by vote once
+func TestController_HandleVoteSkip(t *testing.T) {
+	sessionID := "session_id"
+	groupID := events.GroupID(sessionID)
+
+	sessionCollection := &mocks.SessionCollection{}
+	sessionCollection.On("GetSettings", context.Background(), sessionID).Return(session.DefaultSettings(), nil)
+
+	userCollection := &mocks.UserCollection{}
+	userCollection.On("GetSyncedSpotifyClients", context.Background(), sessionID).
+		Return([]*user.SpotifyClient{{ID: "a"}, {ID: "b"}}, nil)
+
+	eventBus := events.NewEventBus()
+	eventBus.Record(sse.ClientEventTypes()...)
+	eventBus.Start()
+	defer eventBus.Stop()
+	playlists := sse.NewPlaylistTracker(eventBus)
+	ctrl := NewController(
+		eventBus,
+		playlists,
+		sessionCollection,
+		&mocks.SongCollection{},
+		userCollection,
+		&mocks.PlayerCollection{},
+		spotify.Authenticator{},
+		nil,
+	)
+
+	sub := eventBus.Subscribe([]events.EventType{SkipEvent}, []events.GroupID{groupID})
+	skipped := func() bool {
+		select {
+		case <-sub.Channel:
+			return true
+		case <-time.After(100 * time.Millisecond):
+			return false
+		}
+	}
+	voteSkip := func(start time.Time, votes int) {
+		ctrl.handleVoteSkip(events.Event{
+			Type:    VoteSkipEvent,
+			GroupID: groupID,
+			Data:    VoteSkipPayload{SongID: "song_id", SongStart: start, Votes: votes},
+		})
+	}
+
+	start := time.Now()
+	voteSkip(start, 1)
+	assert.True(t, skipped())
+	// further votes do not skip again
+	voteSkip(start, 2)
+	assert.False(t, skipped())
+
+	// the same song queued again can be skipped again
+	voteSkip(start.Add(time.Minute), 1)
+	assert.True(t, skipped())
+
+	playlists.Publish(sessionID, []*song.Model{{ID: "song_id"}})
+	stream := eventBus.Subscribe([]events.EventType{sse.PlayerStateChange}, []events.GroupID{groupID})
+
+	ctrl.handleSessionDeleted(events.Event{Type: SessionDeletedEvent, GroupID: groupID, Data: SessionDeletedPayload{}})
+	assert.NotContains(t, ctrl.skippedByVote, sessionID)
+
+	// the events of the session are forgotten and its streams end
+	_, open := <-stream.Channel
+	assert.False(t, open)
+	assert.Equal(t, uint64(0), eventBus.LastID(groupID))
+	assert.Empty(t, playlists.Snapshot(sessionID, nil).Songs)
+}
@@ -0,0 +1,63 @@
+package queue
+
+import (
+	"sort"
+	"time"
+
+	"github.com/encore-fm/backend/song"
+)
+
+// Arrange orders a song list for playback: pinned songs come first in order of their priority,
+// the remaining songs are ordered by the strategy of the ordering
+func Arrange(ordering Ordering, songs []*song.Model, now time.Time) []*song.Model {
+	pinned := make([]*song.Model, 0)
+	unpinned := make([]*song.Model, 0, len(songs))
+	for _, s := range songs {
+		if s.IsPinned() {
+			pinned = append(pinned, s)
+		} else {
+			unpinned = append(unpinned, s)
+		}
+	}
+	sort.SliceStable(pinned, func(i, j int) bool { return pinned[i].Priority < pinned[j].Priority })
+	return append(pinned, ordering.Strategy().Order(unpinned, now)...)
+}
+
+// Pinned returns the ids of the pinned songs of an arranged song list
+func Pinned(arranged []*song.Model) []string {
+	ids := make([]string, 0)
+	for _, s := range arranged {
+		if s.IsPinned() {
+			ids = append(ids, s.ID)
+		}
+	}
+	return ids
+}
+
+// Priorities maps the ids of a list of pinned songs to their new priority,
+// the first song is played next
+func Priorities(pinned []string) map[string]int {
+	priorities := make(map[string]int, len(pinned))
+	for i, id := range pinned {
+		priorities[id] = i + 1
+	}
+	return priorities
+}
+
+// Move returns the pinned song ids with songID moved to position,
+// positions after the last pinned song append the song to the pinned songs
+func Move(pinned []string, songID string, position int) []string {
+	result := make([]string, 0, len(pinned)+1)
+	for _, id := range pinned {
+		if id != songID {
+			result = append(result, id)
+		}
+	}
+	if position > len(result) {
+		position = len(result)
+	}
+	result = append(result, "")
+	copy(result[position+1:], result[position:])
+	result[position] = songID
+	return result
+}
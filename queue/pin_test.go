@@ -0,0 +1,43 @@
+package queue
+
+import (
+	"testing"
+	"time"
+
+	"github.com/encore-fm/backend/song"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestArrange(t *testing.T) {
+	songs := testSongs()
+	// pin carl's song first and bob's first song second
+	songs[4].Priority = 1
+	songs[3].Priority = 2
+
+	arranged := Arrange(OrderScore, songs, start)
+	assert.Equal(t, []string{"c1", "b1", "a1", "a2", "a3", "b2"}, ids(arranged))
+	assert.Equal(t, []string{"c1", "b1"}, Pinned(arranged))
+}
+
+func TestMove(t *testing.T) {
+	pinned := []string{"a", "b", "c"}
+
+	assert.Equal(t, []string{"c", "a", "b"}, Move(pinned, "c", 0))
+	assert.Equal(t, []string{"b", "a", "c"}, Move(pinned, "a", 1))
+	// moving past the pinned songs appends the song
+	assert.Equal(t, []string{"a", "b", "c", "d"}, Move(pinned, "d", 10))
+	// the input is not modified
+	assert.Equal(t, []string{"a", "b", "c"}, pinned)
+}
+
+func TestPriorities(t *testing.T) {
+	assert.Equal(t, map[string]int{"c": 1, "a": 2}, Priorities([]string{"c", "a"}))
+}
+
+func TestArrange_NoPinned(t *testing.T) {
+	songs := []*song.Model{
+		newSong("a1", "alice", 1, 0),
+		newSong("b1", "bob", 2, time.Minute),
+	}
+	assert.Equal(t, []string{"b1", "a1"}, ids(Arrange(OrderScore, songs, start)))
+}
@@ -0,0 +1,171 @@
+package queue
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/encore-fm/backend/song"
+)
+
+// Ordering names a strategy that decides in which order the songs of a session are played
+type Ordering string
+
+const (
+	// highest score first, ties are broken by the time a song was added
+	OrderScore Ordering = "score"
+	// songs are played in the order they were suggested
+	OrderFIFO Ordering = "fifo"
+	// suggesters take turns, each turn plays the best song of the suggester
+	OrderRoundRobin Ordering = "round_robin"
+	// like OrderScore, but songs gain priority the longer their suggester has been waiting
+	OrderWeightedFair Ordering = "weighted_fair"
+)
+
+// WaitWeight is the number of score points a song gains for every minute its suggester has been waiting
+const WaitWeight = 1.0
+
+var ErrBadOrdering = errors.New(`queue ordering must be in {"score", "fifo", "round_robin", "weighted_fair"}`)
+
+// Strategy orders the song list of a session, the first song is played next
+type Strategy interface {
+	Order(songs []*song.Model, now time.Time) []*song.Model
+}
+
+var strategies = map[Ordering]Strategy{
+	OrderScore:        scoreStrategy{},
+	OrderFIFO:         fifoStrategy{},
+	OrderRoundRobin:   roundRobinStrategy{},
+	OrderWeightedFair: weightedFairStrategy{},
+}
+
+// Valid returns true if there is a strategy for the ordering,
+// the empty ordering is valid and falls back to OrderScore
+func (o Ordering) Valid() bool {
+	if o == "" {
+		return true
+	}
+	_, ok := strategies[o]
+	return ok
+}
+
+// Strategy returns the strategy for the ordering, falling back to OrderScore
+func (o Ordering) Strategy() Strategy {
+	if strategy, ok := strategies[o]; ok {
+		return strategy
+	}
+	return strategies[OrderScore]
+}
+
+// byScore is the ordering songs had before strategies were introduced
+func byScore(a, b *song.Model) bool {
+	if a.Score != b.Score {
+		return a.Score > b.Score
+	}
+	return a.TimeAdded.Before(b.TimeAdded)
+}
+
+// sorted returns a sorted copy of songs, the input slice is left untouched
+func sorted(songs []*song.Model, less func(a, b *song.Model) bool) []*song.Model {
+	result := make([]*song.Model, len(songs))
+	copy(result, songs)
+	sort.SliceStable(result, func(i, j int) bool { return less(result[i], result[j]) })
+	return result
+}
+
+// groupBySuggester splits a song list into one list per suggester, each ordered by score.
+// The suggesters are returned in the order of their oldest pending suggestion.
+func groupBySuggester(songs []*song.Model) ([]string, map[string][]*song.Model) {
+	groups := make(map[string][]*song.Model)
+	suggesters := make([]string, 0)
+	for _, s := range sorted(songs, func(a, b *song.Model) bool { return a.TimeAdded.Before(b.TimeAdded) }) {
+		if _, ok := groups[s.SuggestedBy]; !ok {
+			suggesters = append(suggesters, s.SuggestedBy)
+		}
+		groups[s.SuggestedBy] = append(groups[s.SuggestedBy], s)
+	}
+	for suggester, group := range groups {
+		groups[suggester] = sorted(group, byScore)
+	}
+	return suggesters, groups
+}
+
+type scoreStrategy struct{}
+
+func (scoreStrategy) Order(songs []*song.Model, _ time.Time) []*song.Model {
+	return sorted(songs, byScore)
+}
+
+type fifoStrategy struct{}
+
+func (fifoStrategy) Order(songs []*song.Model, _ time.Time) []*song.Model {
+	return sorted(songs, func(a, b *song.Model) bool { return a.TimeAdded.Before(b.TimeAdded) })
+}
+
+type roundRobinStrategy struct{}
+
+func (roundRobinStrategy) Order(songs []*song.Model, _ time.Time) []*song.Model {
+	suggesters, groups := groupBySuggester(songs)
+	result := make([]*song.Model, 0, len(songs))
+	for len(result) < len(songs) {
+		for _, suggester := range suggesters {
+			if group := groups[suggester]; len(group) > 0 {
+				result = append(result, group[0])
+				groups[suggester] = group[1:]
+			}
+		}
+	}
+	return result
+}
+
+type weightedFairStrategy struct{}
+
+// Order simulates playing the queue: at every step the best song of each suggester competes
+// with its score plus a bonus for the time since the suggester was last served.
+// The clock advances by the duration of every song that is picked.
+func (weightedFairStrategy) Order(songs []*song.Model, now time.Time) []*song.Model {
+	suggesters, groups := groupBySuggester(songs)
+
+	lastServed := make(map[string]time.Time, len(suggesters))
+	for _, suggester := range suggesters {
+		oldest := groups[suggester][0].TimeAdded
+		for _, s := range groups[suggester] {
+			if s.TimeAdded.Before(oldest) {
+				oldest = s.TimeAdded
+			}
+		}
+		lastServed[suggester] = oldest
+	}
+
+	clock := now
+	result := make([]*song.Model, 0, len(songs))
+	for len(result) < len(songs) {
+		var next string
+		var nextPriority float64
+		found := false
+		for _, suggester := range suggesters {
+			group := groups[suggester]
+			if len(group) == 0 {
+				continue
+			}
+			wait := clock.Sub(lastServed[suggester]).Minutes()
+			if wait < 0 {
+				wait = 0
+			}
+			priority := float64(group[0].Score) + WaitWeight*wait
+			// ties go to the suggester that has been waiting longer
+			if !found ||
+				priority > nextPriority ||
+				priority == nextPriority && lastServed[suggester].Before(lastServed[next]) {
+				next, nextPriority, found = suggester, priority, true
+			}
+		}
+
+		picked := groups[next][0]
+		groups[next] = groups[next][1:]
+		result = append(result, picked)
+		lastServed[next] = clock
+		clock = clock.Add(time.Duration(picked.Duration) * time.Millisecond)
+	}
+	return result
+}
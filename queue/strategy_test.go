@@ -0,0 +1,90 @@
+package queue
+
+import (
+	"testing"
+	"time"
+
+	"github.com/encore-fm/backend/song"
+	"github.com/stretchr/testify/assert"
+)
+
+var start = time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+
+func newSong(id, suggestedBy string, score int, addedAfter time.Duration) *song.Model {
+	return &song.Model{
+		ID:          id,
+		SuggestedBy: suggestedBy,
+		Score:       score,
+		TimeAdded:   start.Add(addedAfter),
+		Duration:    int((3 * time.Minute).Milliseconds()),
+	}
+}
+
+func ids(songs []*song.Model) []string {
+	result := make([]string, 0, len(songs))
+	for _, s := range songs {
+		result = append(result, s.ID)
+	}
+	return result
+}
+
+// alice's clique upvotes all of her songs, bob and carl suggested later
+func testSongs() []*song.Model {
+	return []*song.Model{
+		newSong("a1", "alice", 5, 0),
+		newSong("a2", "alice", 4, time.Minute),
+		newSong("a3", "alice", 3, 2*time.Minute),
+		newSong("b1", "bob", 1, 3*time.Minute),
+		newSong("c1", "carl", 1, 4*time.Minute),
+		newSong("b2", "bob", 2, 5*time.Minute),
+	}
+}
+
+func TestOrdering_Valid(t *testing.T) {
+	assert.True(t, Ordering("").Valid())
+	assert.True(t, OrderRoundRobin.Valid())
+	assert.False(t, Ordering("random").Valid())
+}
+
+func TestOrdering_Strategy_Fallback(t *testing.T) {
+	assert.Equal(t, OrderScore.Strategy(), Ordering("").Strategy())
+}
+
+func TestScoreStrategy_Order(t *testing.T) {
+	songs := testSongs()
+	ordered := OrderScore.Strategy().Order(songs, start)
+	assert.Equal(t, []string{"a1", "a2", "a3", "b2", "b1", "c1"}, ids(ordered))
+	// the input is not modified
+	assert.Equal(t, "a1", songs[0].ID)
+	assert.Equal(t, "b2", songs[5].ID)
+}
+
+func TestFIFOStrategy_Order(t *testing.T) {
+	ordered := OrderFIFO.Strategy().Order(testSongs(), start)
+	assert.Equal(t, []string{"a1", "a2", "a3", "b1", "c1", "b2"}, ids(ordered))
+}
+
+func TestRoundRobinStrategy_Order(t *testing.T) {
+	ordered := OrderRoundRobin.Strategy().Order(testSongs(), start)
+	assert.Equal(t, []string{"a1", "b2", "c1", "a2", "b1", "a3"}, ids(ordered))
+}
+
+func TestWeightedFairStrategy_Order(t *testing.T) {
+	// alice's songs still score best, but bob and carl catch up while they wait
+	now := start.Add(5 * time.Minute)
+	ordered := OrderWeightedFair.Strategy().Order(testSongs(), now)
+	assert.Equal(t, []string{"a1", "b2", "a2", "c1", "b1", "a3"}, ids(ordered))
+
+	// without waiting time the strategy behaves like OrderScore for a single suggester
+	single := []*song.Model{
+		newSong("a1", "alice", 1, 0),
+		newSong("a2", "alice", 3, time.Minute),
+	}
+	assert.Equal(t, []string{"a2", "a1"}, ids(OrderWeightedFair.Strategy().Order(single, start)))
+}
+
+func TestStrategies_Empty(t *testing.T) {
+	for ordering, strategy := range strategies {
+		assert.Empty(t, strategy.Order(nil, start), ordering)
+	}
+}
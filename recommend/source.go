@@ -0,0 +1,77 @@
+package recommend
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/encore-fm/backend/player"
+	"github.com/encore-fm/backend/song"
+)
+
+// SystemUser is the suggester of songs added by the autopilot.
+// It contains a character that is not allowed in usernames, so it can not collide with a real user.
+const SystemUser = "~autopilot"
+
+const (
+	// MaxSeeds is the maximum number of seed tracks the spotify api accepts
+	MaxSeeds = 5
+	// number of seeds taken from the most recently played songs, the rest are the best rated songs
+	recentSeeds = 3
+)
+
+var (
+	ErrNoSeeds          = errors.New("no songs to base a recommendation on")
+	ErrNoRecommendation = errors.New("no recommendation found")
+)
+
+// Source recommends the next song of a session based on seed songs.
+// Songs whose id is in exclude must not be recommended, neither must songs that accept returns false for.
+type Source interface {
+	Recommend(seeds []*song.Model, exclude map[string]bool, accept Filter) (*song.Model, error)
+}
+
+// Filter returns true if a candidate may be recommended. Candidates only carry the id, name, artists,
+// duration and explicit flag of the track, they are checked before the rest is looked up.
+type Filter func(candidate *song.Model) bool
+
+// Seeds picks the seed songs for a recommendation from the play history of a session:
+// the most recently played songs and the best rated songs, without duplicates
+func Seeds(history []*player.HistoryEntry) []*song.Model {
+	seeds := make([]*song.Model, 0, MaxSeeds)
+	picked := make(map[string]bool)
+	add := func(s *song.Model) {
+		if s == nil || picked[s.ID] || len(seeds) >= MaxSeeds {
+			return
+		}
+		picked[s.ID] = true
+		seeds = append(seeds, s)
+	}
+
+	// history is ordered oldest first
+	for i := len(history) - 1; i >= 0 && len(seeds) < recentSeeds; i-- {
+		add(history[i].Song)
+	}
+
+	byScore := make([]*song.Model, 0, len(history))
+	for _, entry := range history {
+		if entry.Song != nil {
+			byScore = append(byScore, entry.Song)
+		}
+	}
+	sort.SliceStable(byScore, func(i, j int) bool { return byScore[i].Score > byScore[j].Score })
+	for _, s := range byScore {
+		add(s)
+	}
+	return seeds
+}
+
+// Played returns the ids of all songs in the history
+func Played(history []*player.HistoryEntry) map[string]bool {
+	played := make(map[string]bool, len(history))
+	for _, entry := range history {
+		if entry.Song != nil {
+			played[entry.Song.ID] = true
+		}
+	}
+	return played
+}
@@ -0,0 +1,45 @@
+package recommend
+
+import (
+	"testing"
+
+	"github.com/encore-fm/backend/player"
+	"github.com/encore-fm/backend/song"
+	"github.com/stretchr/testify/assert"
+)
+
+func entry(id string, score int) *player.HistoryEntry {
+	return &player.HistoryEntry{Song: &song.Model{ID: id, Score: score}}
+}
+
+func ids(songs []*song.Model) []string {
+	result := make([]string, 0, len(songs))
+	for _, s := range songs {
+		result = append(result, s.ID)
+	}
+	return result
+}
+
+func TestSeeds(t *testing.T) {
+	history := []*player.HistoryEntry{
+		entry("best", 10),
+		entry("second_best", 8),
+		entry("bad", -2),
+		entry("old", 0),
+		entry("recent", 1),
+		entry("latest", 2),
+		entry("recent", 1),
+	}
+
+	// the three most recent songs first, then the best rated ones
+	assert.Equal(t, []string{"recent", "latest", "old", "best", "second_best"}, ids(Seeds(history)))
+}
+
+func TestSeeds_Empty(t *testing.T) {
+	assert.Empty(t, Seeds(nil))
+}
+
+func TestPlayed(t *testing.T) {
+	history := []*player.HistoryEntry{entry("a", 0), entry("b", 0)}
+	assert.Equal(t, map[string]bool{"a": true, "b": true}, Played(history))
+}
@@ -0,0 +1,70 @@
+package recommend
+
+import (
+	"github.com/encore-fm/backend/song"
+	"github.com/encore-fm/backend/spotifycl"
+	"github.com/zmb3/spotify"
+)
+
+// number of recommendations requested at once, some of them may have been played already
+const recommendationLimit = 20
+
+type spotifySource struct {
+	client *spotifycl.SpotifyClient
+}
+
+var _ Source = (*spotifySource)(nil)
+
+// NewSpotifySource creates a Source that uses the spotify recommendations api
+func NewSpotifySource(client *spotifycl.SpotifyClient) Source {
+	return &spotifySource{client: client}
+}
+
+func (s *spotifySource) Recommend(seeds []*song.Model, exclude map[string]bool, accept Filter) (*song.Model, error) {
+	if len(seeds) == 0 {
+		return nil, ErrNoSeeds
+	}
+
+	ids := make([]spotify.ID, 0, len(seeds))
+	for _, seed := range seeds {
+		ids = append(ids, spotify.ID(seed.ID))
+	}
+
+	limit := recommendationLimit
+	recommendations, err := s.client.Client.GetRecommendations(
+		spotify.Seeds{Tracks: ids},
+		nil,
+		&spotify.Options{Limit: &limit},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, track := range recommendations.Tracks {
+		if exclude[string(track.ID)] || !accept(candidate(track)) {
+			continue
+		}
+		// recommendations only contain simple tracks, song.New needs the album information
+		fullTrack, err := s.client.Client.GetTrack(track.ID)
+		if err != nil {
+			return nil, err
+		}
+		return song.New(SystemUser, 0, fullTrack), nil
+	}
+	return nil, ErrNoRecommendation
+}
+
+// candidate returns the information of a simple track that is known before looking up the full track
+func candidate(track spotify.SimpleTrack) *song.Model {
+	artists := make([]string, 0, len(track.Artists))
+	for _, artist := range track.Artists {
+		artists = append(artists, artist.Name)
+	}
+	return &song.Model{
+		ID:       string(track.ID),
+		Name:     track.Name,
+		Artists:  artists,
+		Duration: track.Duration,
+		Explicit: track.Explicit,
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/encore-fm/backend/events"
 	"github.com/encore-fm/backend/handlers"
 	"github.com/encore-fm/backend/spotifycl"
+	"github.com/encore-fm/backend/sse"
 	"github.com/zmb3/spotify"
 )
 
@@ -15,6 +16,7 @@ type Model struct {
 	SessionCollection db.SessionCollection
 	SongCollection    db.SongCollection
 	SSEHandler        handlers.SSEHandler
+	WSHandler         handlers.WSHandler
 	AdminHandler      handlers.AdminHandler
 	UserHandler       handlers.UserHandler
 	ServerHandler     handlers.ServerHandler
@@ -26,6 +28,7 @@ type Model struct {
 
 func New(
 	eventBus events.EventBus,
+	playlists *sse.PlaylistTracker,
 	userHandle db.UserCollection,
 	sessHandle db.SessionCollection,
 	songHandle db.SongCollection,
@@ -36,6 +39,7 @@ func New(
 
 	handler := handlers.New(
 		eventBus,
+		playlists,
 		userHandle,
 		sessHandle,
 		songHandle,
@@ -50,6 +54,7 @@ func New(
 		SessionCollection: sessHandle,
 		SongCollection:    songHandle,
 		SSEHandler:        handlers.SSEHandler(handler),
+		WSHandler:         handlers.WSHandler(handler),
 		AdminHandler:      handlers.AdminHandler(handler),
 		UserHandler:       handlers.UserHandler(handler),
 		ServerHandler:     handlers.ServerHandler(handler),
@@ -4,6 +4,7 @@ import (
 	"net/http"
 
 	"github.com/encore-fm/backend/handlers"
+	"github.com/encore-fm/backend/session"
 	"github.com/gorilla/mux"
 )
 
@@ -14,7 +15,12 @@ func (s *Model) setupServerRoutes(r *mux.Router) {
 	)
 }
 
-func (s *Model) setupUserRoutes(r *mux.Router, auth handlers.AuthFunc) {
+func (s *Model) setupUserRoutes(
+	r *mux.Router,
+	auth handlers.AuthFunc,
+	waitingAuth handlers.AuthFunc,
+	authorize handlers.AuthorizeFunc,
+) {
 	r.Handle(
 		"/users/{username}/join/{session_id}",
 		http.HandlerFunc(s.UserHandler.Join),
@@ -22,17 +28,17 @@ func (s *Model) setupUserRoutes(r *mux.Router, auth handlers.AuthFunc) {
 
 	r.Handle(
 		"/users/{username}/leave",
-		auth(http.HandlerFunc(s.UserHandler.Leave)),
+		waitingAuth(http.HandlerFunc(s.UserHandler.Leave)),
 	).Methods(http.MethodDelete)
 
 	r.Handle(
 		"/users/{username}/info",
-		auth(http.HandlerFunc(s.UserHandler.UserInfo)),
+		waitingAuth(http.HandlerFunc(s.UserHandler.UserInfo)),
 	).Methods(http.MethodGet)
 
 	r.Handle(
 		"/users/{username}/ping",
-		auth(http.HandlerFunc(s.UserHandler.UserPing)),
+		waitingAuth(http.HandlerFunc(s.UserHandler.UserPing)),
 	)
 
 	r.Handle(
@@ -42,7 +48,17 @@ func (s *Model) setupUserRoutes(r *mux.Router, auth handlers.AuthFunc) {
 
 	r.Handle(
 		"/users/{username}/suggest/{song_id}",
-		auth(http.HandlerFunc(s.UserHandler.SuggestSong)),
+		authorize(session.ActionSuggest)(http.HandlerFunc(s.UserHandler.SuggestSong)),
+	).Methods(http.MethodPost)
+
+	r.Handle(
+		"/users/{username}/suggest",
+		authorize(session.ActionSuggest)(http.HandlerFunc(s.UserHandler.SuggestSongs)),
+	).Methods(http.MethodPost)
+
+	r.Handle(
+		"/users/{username}/import",
+		authorize(session.ActionImport)(http.HandlerFunc(s.UserHandler.ImportSongs)),
 	).Methods(http.MethodPost)
 
 	r.Handle(
@@ -50,9 +66,14 @@ func (s *Model) setupUserRoutes(r *mux.Router, auth handlers.AuthFunc) {
 		auth(http.HandlerFunc(s.UserHandler.ListSongs)),
 	).Methods(http.MethodGet)
 
+	r.Handle(
+		"/users/{username}/history",
+		auth(http.HandlerFunc(s.UserHandler.History)),
+	).Methods(http.MethodGet)
+
 	r.Handle(
 		"/users/{username}/vote/{song_id}/{vote_action}",
-		auth(http.HandlerFunc(s.UserHandler.Vote)),
+		authorize(session.ActionVote)(http.HandlerFunc(s.UserHandler.Vote)),
 	).Methods(http.MethodPost)
 
 	r.Handle(
@@ -70,6 +91,21 @@ func (s *Model) setupUserRoutes(r *mux.Router, auth handlers.AuthFunc) {
 		http.HandlerFunc(s.UserHandler.SessionInfo),
 	).Methods(http.MethodGet)
 
+	r.Handle(
+		"/join/{code}",
+		http.HandlerFunc(s.UserHandler.ResolveJoinCode),
+	).Methods(http.MethodGet)
+
+	r.Handle(
+		"/users/{username}/search",
+		auth(http.HandlerFunc(s.UserHandler.Search)),
+	).Methods(http.MethodGet)
+
+	r.Handle(
+		"/users/{username}/exportPlaylist",
+		auth(http.HandlerFunc(s.UserHandler.ExportPlaylist)),
+	).Methods(http.MethodPost)
+
 	r.Handle(
 		"/users/{username}/favouriteSongs",
 		auth(http.HandlerFunc(s.UserHandler.ListFavouriteSongs)),
@@ -81,7 +117,7 @@ func (s *Model) setupUserRoutes(r *mux.Router, auth handlers.AuthFunc) {
 	).Methods(http.MethodPost)
 }
 
-func (s *Model) setupAdminRoutes(r *mux.Router, auth handlers.AuthFunc) {
+func (s *Model) setupAdminRoutes(r *mux.Router, auth handlers.AuthFunc, authorize handlers.AuthorizeFunc) {
 	r.Handle(
 		"/admin/{username}/createSession",
 		http.HandlerFunc(s.AdminHandler.CreateSession),
@@ -94,8 +130,63 @@ func (s *Model) setupAdminRoutes(r *mux.Router, auth handlers.AuthFunc) {
 
 	r.Handle(
 		"/users/{username}/removeSong/{song_id}",
-		auth(http.HandlerFunc(s.AdminHandler.RemoveSong)),
+		authorize(session.ActionRemoveSong)(http.HandlerFunc(s.AdminHandler.RemoveSong)),
+	).Methods(http.MethodDelete)
+
+	r.Handle(
+		"/admin/{username}/queue/{song_id}/pin",
+		auth(http.HandlerFunc(s.AdminHandler.PinSong)),
+	).Methods(http.MethodPost)
+
+	r.Handle(
+		"/admin/{username}/queue/{song_id}/pin",
+		auth(http.HandlerFunc(s.AdminHandler.UnpinSong)),
 	).Methods(http.MethodDelete)
+
+	r.Handle(
+		"/admin/{username}/queue/{song_id}/position/{position}",
+		auth(http.HandlerFunc(s.AdminHandler.MoveSong)),
+	).Methods(http.MethodPut)
+
+	r.Handle(
+		"/admin/{username}/settings",
+		auth(http.HandlerFunc(s.AdminHandler.GetSettings)),
+	).Methods(http.MethodGet)
+
+	r.Handle(
+		"/admin/{username}/settings",
+		auth(http.HandlerFunc(s.AdminHandler.UpdateSettings)),
+	).Methods(http.MethodPut)
+
+	r.Handle(
+		"/admin/{username}/password",
+		auth(http.HandlerFunc(s.AdminHandler.SetPassword)),
+	).Methods(http.MethodPut)
+
+	r.Handle(
+		"/admin/{username}/invites",
+		auth(http.HandlerFunc(s.AdminHandler.CreateInvite)),
+	).Methods(http.MethodPost)
+
+	r.Handle(
+		"/admin/{username}/users/{target}",
+		auth(http.HandlerFunc(s.AdminHandler.KickUser)),
+	).Methods(http.MethodDelete)
+
+	r.Handle(
+		"/admin/{username}/promote/{target}",
+		auth(http.HandlerFunc(s.AdminHandler.Promote)),
+	).Methods(http.MethodPost)
+
+	r.Handle(
+		"/admin/{username}/demote/{target}",
+		auth(http.HandlerFunc(s.AdminHandler.Demote)),
+	).Methods(http.MethodPost)
+
+	r.Handle(
+		"/admin/{username}/transferOwnership/{target}",
+		auth(http.HandlerFunc(s.AdminHandler.TransferOwnership)),
+	).Methods(http.MethodPost)
 }
 
 func (s *Model) setupSpotifyRoutes(r *mux.Router) {
@@ -110,27 +201,37 @@ func (s *Model) setupEventRoutes(r *mux.Router) {
 		"/events/{username}/{session_id}",
 		http.HandlerFunc(s.SSEHandler.ServeHTTP),
 	)
+
+	r.Handle(
+		"/ws/{username}/{session_id}",
+		http.HandlerFunc(s.WSHandler.ServeWS),
+	)
 }
 
-func (s *Model) setupPlayerRoutes(r *mux.Router, auth handlers.AuthFunc) {
+func (s *Model) setupPlayerRoutes(r *mux.Router, auth handlers.AuthFunc, authorize handlers.AuthorizeFunc) {
 	r.Handle(
 		"/users/{username}/player/play",
-		auth(http.HandlerFunc(s.PlayerHandler.Play)),
+		authorize(session.ActionPlayPause)(http.HandlerFunc(s.PlayerHandler.Play)),
 	).Methods(http.MethodPost)
 
 	r.Handle(
 		"/users/{username}/player/pause",
-		auth(http.HandlerFunc(s.PlayerHandler.Pause)),
+		authorize(session.ActionPlayPause)(http.HandlerFunc(s.PlayerHandler.Pause)),
 	).Methods(http.MethodPost)
 
 	r.Handle(
 		"/users/{username}/player/skip",
-		auth(http.HandlerFunc(s.PlayerHandler.Skip)),
+		authorize(session.ActionSkip)(http.HandlerFunc(s.PlayerHandler.Skip)),
+	).Methods(http.MethodPost)
+
+	r.Handle(
+		"/users/{username}/player/voteSkip",
+		authorize(session.ActionVoteSkip)(http.HandlerFunc(s.PlayerHandler.VoteSkip)),
 	).Methods(http.MethodPost)
 
 	r.Handle(
 		"/users/{username}/player/seek/{position_ms}",
-		auth(http.HandlerFunc(s.PlayerHandler.Seek)),
+		authorize(session.ActionSeek)(http.HandlerFunc(s.PlayerHandler.Seek)),
 	).Methods(http.MethodPost)
 
 	r.Handle(
@@ -154,4 +255,9 @@ func (s *Model) setupDebugRoutes(r *mux.Router) {
 		"/debug/reset_player_controller/{session_id}",
 		http.HandlerFunc(s.DebugHandler.ResetControllerState),
 	)
+
+	r.Handle(
+		"/debug/track_cache",
+		http.HandlerFunc(s.DebugHandler.TrackCacheStats),
+	).Methods(http.MethodGet)
 }
@@ -20,10 +20,11 @@ func (s *Model) Start() {
 	// setup routes
 	s.setupServerRoutes(r)
 	s.setupSpotifyRoutes(r)
-	s.setupUserRoutes(r, handlers.UserAuth(s.UserCollection))
-	s.setupAdminRoutes(r, handlers.AdminAuth(s.UserCollection))
+	authorize := handlers.Authorize(s.UserCollection, s.SessionCollection)
+	s.setupUserRoutes(r, handlers.UserAuth(s.UserCollection), handlers.WaitingUserAuth(s.UserCollection), authorize)
+	s.setupAdminRoutes(r, handlers.AdminAuth(s.UserCollection), authorize)
 	s.setupEventRoutes(r)
-	s.setupPlayerRoutes(r, handlers.UserAuth(s.UserCollection))
+	s.setupPlayerRoutes(r, handlers.UserAuth(s.UserCollection), authorize)
 
 	if config.Conf.Server.Debug {
 		s.setupDebugRoutes(r)
@@ -44,10 +45,12 @@ func (s *Model) listenAndServe(r *mux.Router) {
 	addr := fmt.Sprintf(":%v", s.Port)
 	allowedOrigins := muxh.AllowedOrigins([]string{config.Conf.Server.FrontendBaseUrl})
 	allowedHeaders := muxh.AllowedHeaders([]string{
-		"X-Requested-With", "Content-Type", "Authorization", "Session",
+		"X-Requested-With", "Content-Type", "Authorization", "Session", "Fingerprint",
 	})
 	allowedMethods := muxh.AllowedMethods([]string{"GET", "POST", "PUT", "DELETE", "HEAD", "OPTIONS"})
-	err := http.ListenAndServe(addr, muxh.CORS(allowedOrigins, allowedHeaders, allowedMethods)(r))
+	// response headers the frontend reads besides the cors safelisted ones
+	exposedHeaders := muxh.ExposedHeaders([]string{"Removal-Reason", "Retry-After"})
+	err := http.ListenAndServe(addr, muxh.CORS(allowedOrigins, allowedHeaders, allowedMethods, exposedHeaders)(r))
 	if err != nil {
 		log.Errorf("server error: %v", err)
 	}
@@ -0,0 +1,109 @@
+package session
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/encore-fm/backend/util"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	InviteTokenBytes = 16
+	// bcrypt ignores everything after 72 bytes
+	MaxPasswordLen = 72
+)
+
+var (
+	ErrPasswordTooLong = fmt.Errorf("password can not have more than %v characters", MaxPasswordLen)
+	ErrBadInviteExpiry = errors.New("invite expiry can not be negative")
+)
+
+// Invite grants access to a session without knowing its password.
+// Single use invites are deleted after they have been redeemed once.
+type Invite struct {
+	Token     string    `json:"token" bson:"token"`
+	SingleUse bool      `json:"single_use" bson:"single_use"`
+	Created   time.Time `json:"created" bson:"created"`
+	// zero value means the invite never expires
+	ExpiresAt time.Time `json:"expires_at" bson:"expires_at"`
+}
+
+// NewInvite creates an invite that is valid for the given duration, 0 means forever
+func NewInvite(singleUse bool, validFor time.Duration) (*Invite, error) {
+	if validFor < 0 {
+		return nil, ErrBadInviteExpiry
+	}
+
+	token, err := util.GenerateSecret(InviteTokenBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	invite := &Invite{
+		Token:     token,
+		SingleUse: singleUse,
+		Created:   now,
+	}
+	if validFor > 0 {
+		invite.ExpiresAt = now.Add(validFor)
+	}
+	return invite, nil
+}
+
+// HashPassword returns the bcrypt hash of a session password
+func HashPassword(password string) (string, error) {
+	if len(password) > MaxPasswordLen {
+		return "", ErrPasswordTooLong
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("hash password: %v", err)
+	}
+	return string(hash), nil
+}
+
+// IsPasswordProtected returns true if a password is required to join the session
+func (s *Session) IsPasswordProtected() bool {
+	return s.PasswordHash != ""
+}
+
+// CheckPassword returns true if the password matches the session's password
+func (s *Session) CheckPassword(password string) bool {
+	if !s.IsPasswordProtected() {
+		return true
+	}
+	return bcrypt.CompareHashAndPassword([]byte(s.PasswordHash), []byte(password)) == nil
+}
+
+// Ban keeps a user from joining the session again.
+// The fingerprint is sent by the client and is optional.
+type Ban struct {
+	Username    string    `json:"username" bson:"username"`
+	Fingerprint string    `json:"fingerprint" bson:"fingerprint"`
+	Created     time.Time `json:"created" bson:"created"`
+}
+
+// NewBan creates a ban for the given username and client fingerprint
+func NewBan(username, fingerprint string) *Ban {
+	return &Ban{
+		Username:    username,
+		Fingerprint: fingerprint,
+		Created:     time.Now(),
+	}
+}
+
+// IsBanned returns true if the username or the client fingerprint have been banned from the session
+func (s *Session) IsBanned(username, fingerprint string) bool {
+	for _, ban := range s.Bans {
+		if ban.Username == username {
+			return true
+		}
+		if fingerprint != "" && ban.Fingerprint == fingerprint {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,61 @@
+package session
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSession_CheckPassword(t *testing.T) {
+	hash, err := HashPassword("hunter2")
+	assert.NoError(t, err)
+
+	sess := &Session{PasswordHash: hash}
+	assert.True(t, sess.IsPasswordProtected())
+	assert.True(t, sess.CheckPassword("hunter2"))
+	assert.False(t, sess.CheckPassword("hunter3"))
+	assert.False(t, sess.CheckPassword(""))
+}
+
+func TestSession_CheckPassword_NoPassword(t *testing.T) {
+	sess := &Session{}
+	assert.False(t, sess.IsPasswordProtected())
+	assert.True(t, sess.CheckPassword("anything"))
+}
+
+func TestHashPassword_TooLong(t *testing.T) {
+	_, err := HashPassword(strings.Repeat("a", MaxPasswordLen+1))
+	assert.Equal(t, ErrPasswordTooLong, err)
+}
+
+func TestNewInvite(t *testing.T) {
+	invite, err := NewInvite(true, time.Hour)
+	assert.NoError(t, err)
+	assert.True(t, invite.SingleUse)
+	assert.Equal(t, 2*InviteTokenBytes, len(invite.Token))
+	assert.WithinDuration(t, time.Now().Add(time.Hour), invite.ExpiresAt, time.Second)
+
+	invite, err = NewInvite(false, 0)
+	assert.NoError(t, err)
+	assert.True(t, invite.ExpiresAt.IsZero())
+
+	_, err = NewInvite(false, -time.Second)
+	assert.Equal(t, ErrBadInviteExpiry, err)
+}
+
+func TestSession_IsBanned(t *testing.T) {
+	sess := &Session{
+		Bans: []*Ban{
+			NewBan("troll", ""),
+			NewBan("spammer", "fingerprint"),
+		},
+	}
+
+	assert.True(t, sess.IsBanned("troll", ""))
+	assert.True(t, sess.IsBanned("troll", "other"))
+	assert.True(t, sess.IsBanned("new_name", "fingerprint"))
+	assert.False(t, sess.IsBanned("guest", ""))
+	assert.False(t, sess.IsBanned("guest", "other"))
+}
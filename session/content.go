@@ -0,0 +1,144 @@
+package session
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/encore-fm/backend/song"
+)
+
+// MaxBlockedEntries is the maximum number of blocked artists and of blocked tracks of a session
+const MaxBlockedEntries = 100
+
+var (
+	ErrNegativeDuration    = errors.New("song durations can not be negative")
+	ErrBadDurationRange    = errors.New("minimum song duration can not exceed the maximum song duration")
+	ErrNegativeArtistCap   = errors.New("max songs per artist can not be negative")
+	ErrTooManyBlockedItems = fmt.Errorf("at most %v artists and %v tracks can be blocked", MaxBlockedEntries, MaxBlockedEntries)
+)
+
+// ContentRules restrict which songs can be suggested in a session
+type ContentRules struct {
+	// songs shorter than this are rejected, 0 means no limit
+	MinDurationS int `json:"min_duration_s" bson:"min_duration_s"`
+	// songs longer than this are rejected, 0 means no limit
+	MaxDurationS int `json:"max_duration_s" bson:"max_duration_s"`
+	// artist names, compared case insensitively
+	BlockedArtists []string `json:"blocked_artists" bson:"blocked_artists"`
+	// spotify track ids
+	BlockedTracks []string `json:"blocked_tracks" bson:"blocked_tracks"`
+	// maximum number of songs of the same artist in the queue, 0 means no limit
+	MaxPerArtist int `json:"max_per_artist" bson:"max_per_artist"`
+}
+
+// ContentRule names a rule a song can violate
+type ContentRule string
+
+const (
+	RuleExplicit      ContentRule = "explicit"
+	RuleMinDuration   ContentRule = "min_duration"
+	RuleMaxDuration   ContentRule = "max_duration"
+	RuleBlockedArtist ContentRule = "blocked_artist"
+	RuleBlockedTrack  ContentRule = "blocked_track"
+	RuleMaxPerArtist  ContentRule = "max_per_artist"
+)
+
+// Violation describes why a song breaks a content rule
+type Violation struct {
+	Rule        ContentRule `json:"rule"`
+	Description string      `json:"description"`
+}
+
+// Validate returns an error if any of the rules is out of range
+func (r ContentRules) Validate() error {
+	if r.MinDurationS < 0 || r.MaxDurationS < 0 {
+		return ErrNegativeDuration
+	}
+	if r.MaxDurationS > 0 && r.MinDurationS > r.MaxDurationS {
+		return ErrBadDurationRange
+	}
+	if r.MaxPerArtist < 0 {
+		return ErrNegativeArtistCap
+	}
+	if len(r.BlockedArtists) > MaxBlockedEntries || len(r.BlockedTracks) > MaxBlockedEntries {
+		return ErrTooManyBlockedItems
+	}
+	return nil
+}
+
+// CheckContent returns the content rules the candidate violates, including the explicit filter.
+// queued is the song list of the session, it is only needed if MaxPerArtist is set.
+func (s *Settings) CheckContent(candidate *song.Model, queued []*song.Model) []Violation {
+	rules := s.ContentRules
+	violations := make([]Violation, 0)
+
+	if s.ExplicitFilter && candidate.Explicit {
+		violations = append(violations, Violation{
+			Rule:        RuleExplicit,
+			Description: "songs with explicit lyrics are not allowed",
+		})
+	}
+
+	duration := time.Duration(candidate.Duration) * time.Millisecond
+	if min := time.Duration(rules.MinDurationS) * time.Second; min > 0 && duration < min {
+		violations = append(violations, Violation{
+			Rule:        RuleMinDuration,
+			Description: fmt.Sprintf("songs must be at least %v long", min),
+		})
+	}
+	if max := time.Duration(rules.MaxDurationS) * time.Second; max > 0 && duration > max {
+		violations = append(violations, Violation{
+			Rule:        RuleMaxDuration,
+			Description: fmt.Sprintf("songs can not be longer than %v", max),
+		})
+	}
+
+	for _, trackID := range rules.BlockedTracks {
+		if trackID == candidate.ID {
+			violations = append(violations, Violation{
+				Rule:        RuleBlockedTrack,
+				Description: "the song has been blocked",
+			})
+			break
+		}
+	}
+
+	for _, artist := range candidate.Artists {
+		if containsFold(rules.BlockedArtists, artist) {
+			violations = append(violations, Violation{
+				Rule:        RuleBlockedArtist,
+				Description: fmt.Sprintf("songs by %v have been blocked", artist),
+			})
+		}
+	}
+
+	if rules.MaxPerArtist > 0 {
+		for _, artist := range candidate.Artists {
+			count := 0
+			for _, s := range queued {
+				if containsFold(s.Artists, artist) {
+					count++
+				}
+			}
+			if count >= rules.MaxPerArtist {
+				violations = append(violations, Violation{
+					Rule:        RuleMaxPerArtist,
+					Description: fmt.Sprintf("the queue already contains %v songs by %v", count, artist),
+				})
+			}
+		}
+	}
+
+	return violations
+}
+
+func containsFold(list []string, s string) bool {
+	for _, entry := range list {
+		if strings.EqualFold(entry, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,75 @@
+package session
+
+import (
+	"testing"
+	"time"
+
+	"github.com/encore-fm/backend/song"
+	"github.com/stretchr/testify/assert"
+)
+
+func rules(violations []Violation) []ContentRule {
+	result := make([]ContentRule, 0, len(violations))
+	for _, violation := range violations {
+		result = append(result, violation.Rule)
+	}
+	return result
+}
+
+func TestContentRules_Validate(t *testing.T) {
+	assert.NoError(t, ContentRules{MinDurationS: 60, MaxDurationS: 600}.Validate())
+	assert.NoError(t, ContentRules{MinDurationS: 60}.Validate())
+	assert.Equal(t, ErrNegativeDuration, ContentRules{MaxDurationS: -1}.Validate())
+	assert.Equal(t, ErrBadDurationRange, ContentRules{MinDurationS: 600, MaxDurationS: 60}.Validate())
+	assert.Equal(t, ErrNegativeArtistCap, ContentRules{MaxPerArtist: -1}.Validate())
+	assert.Equal(t, ErrTooManyBlockedItems, ContentRules{BlockedTracks: make([]string, MaxBlockedEntries+1)}.Validate())
+
+	settings := DefaultSettings()
+	settings.ContentRules.MaxPerArtist = -1
+	assert.Equal(t, ErrNegativeArtistCap, settings.Validate())
+}
+
+func TestSettings_CheckContent(t *testing.T) {
+	candidate := &song.Model{
+		ID:       "song_id",
+		Artists:  []string{"Daft Punk", "Pharrell Williams"},
+		Duration: int((4 * time.Minute).Milliseconds()),
+		Explicit: true,
+	}
+
+	settings := DefaultSettings()
+	assert.Empty(t, settings.CheckContent(candidate, nil))
+
+	settings.ExplicitFilter = true
+	settings.ContentRules = ContentRules{
+		MinDurationS:   300,
+		BlockedArtists: []string{"daft punk"},
+		BlockedTracks:  []string{"song_id"},
+	}
+	assert.Equal(
+		t,
+		[]ContentRule{RuleExplicit, RuleMinDuration, RuleBlockedTrack, RuleBlockedArtist},
+		rules(settings.CheckContent(candidate, nil)),
+	)
+
+	settings = DefaultSettings()
+	settings.ContentRules.MaxDurationS = 180
+	violations := settings.CheckContent(candidate, nil)
+	assert.Equal(t, []ContentRule{RuleMaxDuration}, rules(violations))
+	assert.Equal(t, "songs can not be longer than 3m0s", violations[0].Description)
+}
+
+func TestSettings_CheckContent_MaxPerArtist(t *testing.T) {
+	candidate := &song.Model{ID: "candidate", Artists: []string{"Daft Punk"}}
+	queued := []*song.Model{
+		{ID: "a", Artists: []string{"daft punk"}},
+		{ID: "b", Artists: []string{"Justice"}},
+	}
+
+	settings := DefaultSettings()
+	settings.ContentRules.MaxPerArtist = 2
+	assert.Empty(t, settings.CheckContent(candidate, queued))
+
+	queued = append(queued, &song.Model{ID: "c", Artists: []string{"Daft Punk", "Julian Casablancas"}})
+	assert.Equal(t, []ContentRule{RuleMaxPerArtist}, rules(settings.CheckContent(candidate, queued)))
+}
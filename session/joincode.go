@@ -0,0 +1,32 @@
+package session
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+const JoinCodeLen = 6
+
+// upper case letters without I and O, which are easily confused with 1 and 0
+const JoinCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ"
+
+// NewJoinCode returns a random code that is short enough to be typed in by hand
+func NewJoinCode() (string, error) {
+	alphabetLen := big.NewInt(int64(len(JoinCodeAlphabet)))
+	code := make([]byte, JoinCodeLen)
+	for i := range code {
+		n, err := rand.Int(rand.Reader, alphabetLen)
+		if err != nil {
+			return "", fmt.Errorf("generate join code: %v", err)
+		}
+		code[i] = JoinCodeAlphabet[n.Int64()]
+	}
+	return string(code), nil
+}
+
+// NormalizeJoinCode makes user input comparable to generated codes
+func NormalizeJoinCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
@@ -0,0 +1,21 @@
+package session
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewJoinCode(t *testing.T) {
+	code, err := NewJoinCode()
+	assert.NoError(t, err)
+	assert.Len(t, code, JoinCodeLen)
+	for _, c := range code {
+		assert.True(t, strings.ContainsRune(JoinCodeAlphabet, c))
+	}
+}
+
+func TestNormalizeJoinCode(t *testing.T) {
+	assert.Equal(t, "ABCDEF", NormalizeJoinCode(" abcDef\n"))
+}
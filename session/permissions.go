@@ -0,0 +1,73 @@
+package session
+
+import (
+	"errors"
+
+	"github.com/encore-fm/backend/user"
+)
+
+// Action is something a user can do in a session that requires a permission
+type Action string
+
+const (
+	ActionSuggest    Action = "suggest"
+	ActionVote       Action = "vote"
+	ActionSkip       Action = "skip"
+	ActionVoteSkip   Action = "vote_skip"
+	ActionSeek       Action = "seek"
+	ActionPlayPause  Action = "play_pause"
+	ActionRemoveSong Action = "remove_song"
+	ActionImport     Action = "import"
+)
+
+var (
+	ErrUnknownAction = errors.New("permissions contain an unknown action")
+	ErrBadRole       = errors.New(`role must be in {"owner", "moderator", "guest"}`)
+)
+
+// Permissions maps each action to the least role that is allowed to perform it
+type Permissions map[Action]user.Role
+
+func DefaultPermissions() Permissions {
+	return Permissions{
+		ActionSuggest:    user.RoleGuest,
+		ActionVote:       user.RoleGuest,
+		ActionSkip:       user.RoleOwner,
+		ActionVoteSkip:   user.RoleGuest,
+		ActionSeek:       user.RoleOwner,
+		ActionPlayPause:  user.RoleOwner,
+		ActionRemoveSong: user.RoleModerator,
+		ActionImport:     user.RoleModerator,
+	}
+}
+
+// RequiredRole returns the least role that is allowed to perform the action
+// actions missing from the table fall back to the default permissions
+func (p Permissions) RequiredRole(action Action) user.Role {
+	if role, ok := p[action]; ok {
+		return role
+	}
+	if role, ok := DefaultPermissions()[action]; ok {
+		return role
+	}
+	return user.RoleOwner
+}
+
+// Allows returns true if the role is allowed to perform the action
+func (p Permissions) Allows(role user.Role, action Action) bool {
+	return role.Includes(p.RequiredRole(action))
+}
+
+// Validate returns an error if the table contains unknown actions or roles
+func (p Permissions) Validate() error {
+	defaults := DefaultPermissions()
+	for action, role := range p {
+		if _, ok := defaults[action]; !ok {
+			return ErrUnknownAction
+		}
+		if !role.Valid() {
+			return ErrBadRole
+		}
+	}
+	return nil
+}
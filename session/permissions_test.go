@@ -0,0 +1,28 @@
+package session
+
+import (
+	"testing"
+
+	"github.com/encore-fm/backend/user"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPermissions_Allows(t *testing.T) {
+	permissions := Permissions{
+		ActionSkip: user.RoleGuest,
+	}
+
+	assert.True(t, permissions.Allows(user.RoleGuest, ActionSkip))
+	// missing actions fall back to the defaults
+	assert.False(t, permissions.Allows(user.RoleGuest, ActionRemoveSong))
+	assert.True(t, permissions.Allows(user.RoleModerator, ActionRemoveSong))
+	assert.True(t, permissions.Allows(user.RoleOwner, ActionSeek))
+	// unknown actions are reserved for the owner
+	assert.False(t, permissions.Allows(user.RoleModerator, "unknown"))
+}
+
+func TestPermissions_Validate(t *testing.T) {
+	assert.NoError(t, DefaultPermissions().Validate())
+	assert.Equal(t, ErrUnknownAction, Permissions{"dance": user.RoleGuest}.Validate())
+	assert.Equal(t, ErrBadRole, Permissions{ActionVote: "dj"}.Validate())
+}
@@ -11,13 +11,34 @@ import (
 const IDBytes = 16
 
 // Session stores session information
-// todo: maybe save session options later
 type Session struct {
 	ID          string         `json:"id" bson:"_id"`
 	SongList    []*song.Model  `json:"song_list" bson:"song_list"`
 	Player      *player.Player `json:"player" bson:"player"`
 	Created     time.Time      `json:"created" bson:"created"`
 	LastUpdated time.Time      `json:"last_updated" bson:"last_updated"`
+	Settings    *Settings      `json:"settings" bson:"settings"`
+	// short code that can be used instead of the session id to join
+	JoinCode string `json:"join_code" bson:"join_code"`
+
+	// maximum number of users allowed in the session, 0 falls back to the global limit
+	MaxUsers int `json:"max_users" bson:"max_users"`
+	// number of admitted (not waiting) users in the session
+	UserCount int `json:"user_count" bson:"user_count"`
+	// if set, users joining a full session are put on a waiting list instead of being rejected
+	WaitingList bool `json:"waiting_list" bson:"waiting_list"`
+
+	// bcrypt hash of the session password, empty if the session is not password protected
+	PasswordHash string    `json:"-" bson:"password_hash"`
+	Invites      []*Invite `json:"-" bson:"invites"`
+	Bans         []*Ban    `json:"-" bson:"bans"`
+
+	// the last player.HistoryLen songs that were played, oldest first
+	History []*player.HistoryEntry `json:"-" bson:"history"`
+	// the last player.SetlistLen songs that were played, oldest first
+	Setlist []*player.SetlistEntry `json:"-" bson:"setlist"`
+	// number of songs added to the setlist, including those that have been dropped from it
+	Played int `json:"-" bson:"played"`
 }
 
 func New() (*Session, error) {
@@ -29,8 +50,13 @@ func New() (*Session, error) {
 	return &Session{
 		ID:          sessionID,
 		SongList:    make([]*song.Model, 0),
+		Invites:     make([]*Invite, 0),
+		Bans:        make([]*Ban, 0),
+		History:     make([]*player.HistoryEntry, 0),
+		Setlist:     make([]*player.SetlistEntry, 0),
 		Player:      player.New(),
 		Created:     timestamp,
 		LastUpdated: timestamp,
+		Settings:    DefaultSettings(),
 	}, nil
 }
@@ -0,0 +1,223 @@
+package session
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/encore-fm/backend/queue"
+)
+
+type Visibility string
+
+const (
+	Public  Visibility = "public"
+	Private Visibility = "private"
+)
+
+const (
+	MaxNameLen        = 50
+	MaxDescriptionLen = 300
+)
+
+var (
+	ErrNameTooLong           = fmt.Errorf("session name can not have more than %v characters", MaxNameLen)
+	ErrDescriptionTooLong    = fmt.Errorf("session description can not have more than %v characters", MaxDescriptionLen)
+	ErrBadVisibility         = errors.New(`visibility must be in {"public", "private"}`)
+	ErrNegativeSuggestionCap = errors.New("max pending suggestions can not be negative")
+	ErrNegativeMinInterval   = errors.New("minimum suggestion interval can not be negative")
+	ErrNegativeScorePerSlot  = errors.New("score per suggestion slot can not be negative")
+	ErrNegativeRepeatWindow  = errors.New("repeat window can not be negative")
+	ErrBadSkipFraction       = errors.New("skip fraction must be between 0 and 1")
+	ErrBadRemovalScore       = errors.New("removal score can not be positive")
+	ErrBadRemovalRatio       = errors.New("removal ratio must be between 0 and 1")
+)
+
+const DefaultSkipFraction = 0.5
+
+type VotingRules struct {
+	AllowDownvotes bool `json:"allow_downvotes" bson:"allow_downvotes"`
+	// fraction of synchronized users that have to vote to skip the current song, 0 uses the default
+	SkipFraction float64 `json:"skip_fraction" bson:"skip_fraction"`
+	// songs whose score drops to this value are removed from the queue, 0 disables the rule
+	RemovalScore int `json:"removal_score" bson:"removal_score"`
+	// fraction of session members that have to downvote a song to remove it, 0 disables the rule
+	RemovalRatio float64 `json:"removal_ratio" bson:"removal_ratio"`
+}
+
+// GetSkipFraction returns the skip fraction, falling back to the default if it is not set
+func (v VotingRules) GetSkipFraction() float64 {
+	if v.SkipFraction == 0 {
+		return DefaultSkipFraction
+	}
+	return v.SkipFraction
+}
+
+// RemovalReason tells why a song was removed from the queue automatically
+type RemovalReason string
+
+const (
+	RemovalReasonScore     RemovalReason = "score"
+	RemovalReasonDownvotes RemovalReason = "downvote_ratio"
+)
+
+// RemovalThreshold is the removal rule of a session resolved for its current size
+type RemovalThreshold struct {
+	// songs with a score less than or equal to Score are removed, only used if negative
+	Score int
+	// songs with at least this many downvoters are removed, 0 disables the rule
+	Downvoters int
+}
+
+// Enabled returns true if at least one of the removal rules is active
+func (t RemovalThreshold) Enabled() bool {
+	return t.Score < 0 || t.Downvoters > 0
+}
+
+// Reason returns why a song with the given score and number of downvoters is removed, empty if it is kept
+func (t RemovalThreshold) Reason(score, downvoters int) RemovalReason {
+	if t.Score < 0 && score <= t.Score {
+		return RemovalReasonScore
+	}
+	if t.Downvoters > 0 && downvoters >= t.Downvoters {
+		return RemovalReasonDownvotes
+	}
+	return ""
+}
+
+// RemovalThreshold resolves the removal rules for a session with the given number of members
+func (v VotingRules) RemovalThreshold(members int) RemovalThreshold {
+	threshold := RemovalThreshold{Score: v.RemovalScore}
+	if v.RemovalRatio > 0 {
+		downvoters := int(math.Ceil(v.RemovalRatio * float64(members)))
+		if downvoters < 1 {
+			downvoters = 1
+		}
+		threshold.Downvoters = downvoters
+	}
+	return threshold
+}
+
+type SuggestionLimits struct {
+	// maximum number of songs a user can have in the queue at once, 0 means no limit
+	MaxPending int `json:"max_pending" bson:"max_pending"`
+	// minimum number of seconds between two suggestions of the same user, 0 means no limit
+	MinIntervalS int `json:"min_interval_s" bson:"min_interval_s"`
+	// users earn one additional pending suggestion per ScorePerSlot points of score, 0 disables the allowance
+	ScorePerSlot int `json:"score_per_slot" bson:"score_per_slot"`
+	// number of seconds after a song started playing before it can be suggested again, 0 means no limit
+	RepeatWindowS int `json:"repeat_window_s" bson:"repeat_window_s"`
+}
+
+// MinInterval returns the minimum time between two suggestions of the same user
+func (l SuggestionLimits) MinInterval() time.Duration {
+	return time.Duration(l.MinIntervalS) * time.Second
+}
+
+// RepeatWindow returns the time after a song started playing before it can be suggested again
+func (l SuggestionLimits) RepeatWindow() time.Duration {
+	return time.Duration(l.RepeatWindowS) * time.Second
+}
+
+// PendingAllowance returns how many songs a user with the given score can have in the queue at once,
+// 0 means no limit
+func (l SuggestionLimits) PendingAllowance(score int) int {
+	if l.MaxPending == 0 {
+		return 0
+	}
+	if l.ScorePerSlot == 0 || score <= 0 {
+		return l.MaxPending
+	}
+	return l.MaxPending + score/l.ScorePerSlot
+}
+
+// NextSuggestion returns the earliest time a user whose last suggestion was at last can suggest again
+func (l SuggestionLimits) NextSuggestion(last time.Time) time.Time {
+	return last.Add(l.MinInterval())
+}
+
+// Settings contains the rules of a session, editable by the admin
+type Settings struct {
+	Name             string           `json:"name" bson:"name"`
+	Description      string           `json:"description" bson:"description"`
+	VotingRules      VotingRules      `json:"voting_rules" bson:"voting_rules"`
+	SuggestionLimits SuggestionLimits `json:"suggestion_limits" bson:"suggestion_limits"`
+	// if set, songs with explicit lyrics can not be suggested
+	ExplicitFilter bool         `json:"explicit_filter" bson:"explicit_filter"`
+	ContentRules   ContentRules `json:"content_rules" bson:"content_rules"`
+	Visibility     Visibility   `json:"visibility" bson:"visibility"`
+	// if set, users can only join with an invite issued by the admin
+	InviteOnly  bool        `json:"invite_only" bson:"invite_only"`
+	Permissions Permissions `json:"permissions" bson:"permissions"`
+	// order in which the songs of the queue are played, empty falls back to ordering by score
+	QueueOrdering queue.Ordering `json:"queue_ordering" bson:"queue_ordering"`
+	// if set, recommended songs are played when the queue runs empty
+	Autopilot bool `json:"autopilot" bson:"autopilot"`
+}
+
+func DefaultSettings() *Settings {
+	return &Settings{
+		VotingRules: VotingRules{
+			AllowDownvotes: true,
+			SkipFraction:   DefaultSkipFraction,
+		},
+		SuggestionLimits: SuggestionLimits{
+			MaxPending: 0,
+		},
+		ExplicitFilter: false,
+		Visibility:     Private,
+		InviteOnly:     false,
+		Permissions:    DefaultPermissions(),
+		QueueOrdering:  queue.OrderScore,
+		Autopilot:      false,
+		ContentRules: ContentRules{
+			BlockedArtists: make([]string, 0),
+			BlockedTracks:  make([]string, 0),
+		},
+	}
+}
+
+// Validate returns an error if any of the settings is out of range
+func (s *Settings) Validate() error {
+	if len(s.Name) > MaxNameLen {
+		return ErrNameTooLong
+	}
+	if len(s.Description) > MaxDescriptionLen {
+		return ErrDescriptionTooLong
+	}
+	if s.Visibility != Public && s.Visibility != Private {
+		return ErrBadVisibility
+	}
+	if s.SuggestionLimits.MaxPending < 0 {
+		return ErrNegativeSuggestionCap
+	}
+	if s.SuggestionLimits.MinIntervalS < 0 {
+		return ErrNegativeMinInterval
+	}
+	if s.SuggestionLimits.ScorePerSlot < 0 {
+		return ErrNegativeScorePerSlot
+	}
+	if s.SuggestionLimits.RepeatWindowS < 0 {
+		return ErrNegativeRepeatWindow
+	}
+	if s.VotingRules.SkipFraction < 0 || s.VotingRules.SkipFraction > 1 {
+		return ErrBadSkipFraction
+	}
+	if s.VotingRules.RemovalScore > 0 {
+		return ErrBadRemovalScore
+	}
+	if s.VotingRules.RemovalRatio < 0 || s.VotingRules.RemovalRatio > 1 {
+		return ErrBadRemovalRatio
+	}
+	if err := s.ContentRules.Validate(); err != nil {
+		return err
+	}
+	if !s.QueueOrdering.Valid() {
+		return queue.ErrBadOrdering
+	}
+	if err := s.Permissions.Validate(); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package session
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/encore-fm/backend/queue"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaultSettings_Valid(t *testing.T) {
+	assert.NoError(t, DefaultSettings().Validate())
+}
+
+func TestSettings_Validate(t *testing.T) {
+	nameTooLong := DefaultSettings()
+	nameTooLong.Name = strings.Repeat("a", MaxNameLen+1)
+	assert.Equal(t, ErrNameTooLong, nameTooLong.Validate())
+
+	descriptionTooLong := DefaultSettings()
+	descriptionTooLong.Description = strings.Repeat("a", MaxDescriptionLen+1)
+	assert.Equal(t, ErrDescriptionTooLong, descriptionTooLong.Validate())
+
+	badVisibility := DefaultSettings()
+	badVisibility.Visibility = "secret"
+	assert.Equal(t, ErrBadVisibility, badVisibility.Validate())
+
+	negativeLimit := DefaultSettings()
+	negativeLimit.SuggestionLimits.MaxPending = -1
+	assert.Equal(t, ErrNegativeSuggestionCap, negativeLimit.Validate())
+
+	negativeInterval := DefaultSettings()
+	negativeInterval.SuggestionLimits.MinIntervalS = -1
+	assert.Equal(t, ErrNegativeMinInterval, negativeInterval.Validate())
+
+	negativeScorePerSlot := DefaultSettings()
+	negativeScorePerSlot.SuggestionLimits.ScorePerSlot = -1
+	assert.Equal(t, ErrNegativeScorePerSlot, negativeScorePerSlot.Validate())
+
+	negativeRepeatWindow := DefaultSettings()
+	negativeRepeatWindow.SuggestionLimits.RepeatWindowS = -1
+	assert.Equal(t, ErrNegativeRepeatWindow, negativeRepeatWindow.Validate())
+
+	badSkipFraction := DefaultSettings()
+	badSkipFraction.VotingRules.SkipFraction = 1.5
+	assert.Equal(t, ErrBadSkipFraction, badSkipFraction.Validate())
+
+	badOrdering := DefaultSettings()
+	badOrdering.QueueOrdering = "random"
+	assert.Equal(t, queue.ErrBadOrdering, badOrdering.Validate())
+
+	badRemovalScore := DefaultSettings()
+	badRemovalScore.VotingRules.RemovalScore = 3
+	assert.Equal(t, ErrBadRemovalScore, badRemovalScore.Validate())
+
+	badRemovalRatio := DefaultSettings()
+	badRemovalRatio.VotingRules.RemovalRatio = -0.1
+	assert.Equal(t, ErrBadRemovalRatio, badRemovalRatio.Validate())
+}
+
+func TestVotingRules_RemovalThreshold(t *testing.T) {
+	disabled := VotingRules{}
+	assert.False(t, disabled.RemovalThreshold(10).Enabled())
+
+	byScore := VotingRules{RemovalScore: -5}
+	assert.Equal(t, RemovalThreshold{Score: -5}, byScore.RemovalThreshold(10))
+	assert.True(t, byScore.RemovalThreshold(10).Enabled())
+
+	byRatio := VotingRules{RemovalRatio: 0.5}
+	assert.Equal(t, RemovalThreshold{Downvoters: 3}, byRatio.RemovalThreshold(5))
+	assert.Equal(t, RemovalThreshold{Downvoters: 1}, byRatio.RemovalThreshold(0))
+}
+
+func TestRemovalThreshold_Reason(t *testing.T) {
+	disabled := RemovalThreshold{}
+	assert.Equal(t, RemovalReason(""), disabled.Reason(-100, 100))
+
+	both := RemovalThreshold{Score: -5, Downvoters: 3}
+	assert.Equal(t, RemovalReason(""), both.Reason(-4, 2))
+	assert.Equal(t, RemovalReasonScore, both.Reason(-5, 5))
+	assert.Equal(t, RemovalReasonDownvotes, both.Reason(-4, 3))
+}
+
+func TestSuggestionLimits_PendingAllowance(t *testing.T) {
+	unlimited := SuggestionLimits{ScorePerSlot: 5}
+	assert.Equal(t, 0, unlimited.PendingAllowance(100))
+
+	fixed := SuggestionLimits{MaxPending: 2}
+	assert.Equal(t, 2, fixed.PendingAllowance(100))
+
+	byScore := SuggestionLimits{MaxPending: 2, ScorePerSlot: 5}
+	assert.Equal(t, 2, byScore.PendingAllowance(-10))
+	assert.Equal(t, 2, byScore.PendingAllowance(4))
+	assert.Equal(t, 4, byScore.PendingAllowance(12))
+}
+
+func TestSuggestionLimits_NextSuggestion(t *testing.T) {
+	last := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	limits := SuggestionLimits{MinIntervalS: 30}
+	assert.Equal(t, last.Add(30*time.Second), limits.NextSuggestion(last))
+}
@@ -14,11 +14,19 @@ type Model struct {
 	CoverUrl    string    `json:"cover_url" bson:"cover_url"`
 	AlbumName   string    `json:"album_name" bson:"album_name"`
 	PreviewUrl  string    `json:"preview_url" bson:"preview_url"`
+	Explicit    bool      `json:"explicit" bson:"explicit"`
 	SuggestedBy string    `json:"suggested_by" bson:"suggested_by"`
 	Score       int       `json:"score" bson:"score"`
 	TimeAdded   time.Time `json:"time_added" bson:"time_added"`
 	Upvoters    []string  `json:"upvoters" bson:"upvoters"`
 	Downvoters  []string  `json:"downvoters" bson:"downvoters"`
+	// pinned songs are played before all other songs, lowest priority first. 0 means not pinned
+	Priority int `json:"priority" bson:"priority"`
+}
+
+// IsPinned returns true if the song has been pinned by the admin
+func (m *Model) IsPinned() bool {
+	return m.Priority > 0
 }
 
 func New(
@@ -39,6 +47,7 @@ func New(
 		CoverUrl:    albumUrl,
 		AlbumName:   info.Album.Name,
 		PreviewUrl:  info.PreviewURL,
+		Explicit:    info.Explicit,
 		SuggestedBy: suggestingUser,
 		Score:       score,
 		TimeAdded:   time.Now(),
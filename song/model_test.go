@@ -20,6 +20,7 @@ func TestNew1(t *testing.T) {
 		CoverUrl:    "cover_url",
 		AlbumName:   "album_name",
 		PreviewUrl:  "preview_url",
+		Explicit:    true,
 		SuggestedBy: username,
 		Score:       songScore,
 		TimeAdded:   time.Time{},
@@ -37,6 +38,7 @@ func TestNew1(t *testing.T) {
 			ID:         spotify.ID(expected.ID),
 			Name:       expected.Name,
 			PreviewURL: expected.PreviewUrl,
+			Explicit:   expected.Explicit,
 		},
 		Album: spotify.SimpleAlbum{
 			Name: expected.AlbumName,
@@ -83,6 +85,7 @@ func TestNew2(t *testing.T) {
 			ID:         spotify.ID(expected.ID),
 			Name:       expected.Name,
 			PreviewURL: expected.PreviewUrl,
+			Explicit:   expected.Explicit,
 		},
 		Album: spotify.SimpleAlbum{
 			Name:   expected.AlbumName,
@@ -4,6 +4,7 @@ import (
 	"context"
 	"time"
 
+	"github.com/encore-fm/backend/cache"
 	log "github.com/sirupsen/logrus"
 	"github.com/zmb3/spotify"
 	"golang.org/x/oauth2"
@@ -17,9 +18,19 @@ type SpotifyClient struct {
 	config *clientcredentials.Config
 	ticker *time.Ticker
 	quit   chan struct{}
+
+	// caches search results by query
+	searchCache *cache.LRU
+	// caches the metadata of single tracks
+	trackCache *TrackCache
 }
 
-func New(clientID, clientSecret string) (*SpotifyClient, error) {
+func New(
+	clientID, clientSecret string,
+	searchCacheSize int,
+	searchCacheTTL time.Duration,
+	trackCache *TrackCache,
+) (*SpotifyClient, error) {
 	config := &clientcredentials.Config{
 		ClientID:     clientID,
 		ClientSecret: clientSecret,
@@ -36,6 +47,9 @@ func New(clientID, clientSecret string) (*SpotifyClient, error) {
 		config: config,
 		ticker: time.NewTicker(RefreshWaitTime),
 		quit:   make(chan struct{}),
+
+		searchCache: cache.New(searchCacheSize, searchCacheTTL),
+		trackCache:  trackCache,
 	}, nil
 }
 
@@ -0,0 +1,105 @@
+package spotifycl
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+
+	"github.com/zmb3/spotify"
+)
+
+type CollectionType string
+
+const (
+	CollectionPlaylist CollectionType = "playlist"
+	CollectionAlbum    CollectionType = "album"
+)
+
+// number of tracks requested per page, the maximum accepted for albums
+const collectionPageSize = 50
+
+var ErrBadCollectionURI = errors.New("uri must point to a spotify playlist or album")
+
+// ParseCollectionURI parses a playlist or album uri like "spotify:playlist:<id>"
+// or a link like "https://open.spotify.com/album/<id>"
+func ParseCollectionURI(uri string) (CollectionType, spotify.ID, error) {
+	var parts []string
+	if strings.HasPrefix(uri, "spotify:") {
+		parts = strings.Split(strings.TrimPrefix(uri, "spotify:"), ":")
+	} else {
+		link, err := url.Parse(uri)
+		if err != nil || link.Host != "open.spotify.com" {
+			return "", "", ErrBadCollectionURI
+		}
+		parts = strings.Split(strings.Trim(link.Path, "/"), "/")
+	}
+
+	if len(parts) != 2 || parts[1] == "" {
+		return "", "", ErrBadCollectionURI
+	}
+	collectionType := CollectionType(parts[0])
+	if collectionType != CollectionPlaylist && collectionType != CollectionAlbum {
+		return "", "", ErrBadCollectionURI
+	}
+	return collectionType, spotify.ID(parts[1]), nil
+}
+
+// VisitCollectionTracks pages through the tracks of a playlist or album and calls visit for every track
+// until visit returns false or all tracks have been visited. Local playlist tracks are left out.
+func (c *SpotifyClient) VisitCollectionTracks(uri string, visit func(track *spotify.FullTrack) bool) error {
+	collectionType, id, err := ParseCollectionURI(uri)
+	if err != nil {
+		return err
+	}
+	if collectionType == CollectionAlbum {
+		return c.visitAlbumTracks(id, visit)
+	}
+	return c.visitPlaylistTracks(id, visit)
+}
+
+func (c *SpotifyClient) visitPlaylistTracks(id spotify.ID, visit func(track *spotify.FullTrack) bool) error {
+	limit := collectionPageSize
+	for offset := 0; ; offset += limit {
+		page, err := c.Client.GetPlaylistTracksOpt(id, &spotify.Options{Limit: &limit, Offset: &offset}, "")
+		if err != nil {
+			return err
+		}
+		for i := range page.Tracks {
+			track := page.Tracks[i]
+			if track.IsLocal || track.Track.ID == "" {
+				continue
+			}
+			if !visit(&track.Track) {
+				return nil
+			}
+		}
+		if page.Next == "" {
+			return nil
+		}
+	}
+}
+
+func (c *SpotifyClient) visitAlbumTracks(id spotify.ID, visit func(track *spotify.FullTrack) bool) error {
+	// album tracks do not contain the album itself, which is needed for the cover
+	album, err := c.Client.GetAlbum(id)
+	if err != nil {
+		return err
+	}
+
+	limit := collectionPageSize
+	for offset := 0; ; offset += limit {
+		page, err := c.Client.GetAlbumTracksOpt(id, &spotify.Options{Limit: &limit, Offset: &offset})
+		if err != nil {
+			return err
+		}
+		for _, track := range page.Tracks {
+			fullTrack := &spotify.FullTrack{SimpleTrack: track, Album: album.SimpleAlbum}
+			if !visit(fullTrack) {
+				return nil
+			}
+		}
+		if page.Next == "" {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package spotifycl
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zmb3/spotify"
+)
+
+func TestParseCollectionURI(t *testing.T) {
+	collectionType, id, err := ParseCollectionURI("spotify:playlist:37i9dQZF1DXcBWIGoYBM5M")
+	assert.NoError(t, err)
+	assert.Equal(t, CollectionPlaylist, collectionType)
+	assert.Equal(t, spotify.ID("37i9dQZF1DXcBWIGoYBM5M"), id)
+
+	collectionType, id, err = ParseCollectionURI("https://open.spotify.com/album/7vEJAtP3KgKSpOHVgwm3Eh?si=abc")
+	assert.NoError(t, err)
+	assert.Equal(t, CollectionAlbum, collectionType)
+	assert.Equal(t, spotify.ID("7vEJAtP3KgKSpOHVgwm3Eh"), id)
+}
+
+func TestParseCollectionURI_Invalid(t *testing.T) {
+	invalid := []string{
+		"",
+		"spotify:track:7unF2ARDGldwWxZWCmlwDM",
+		"spotify:playlist:",
+		"https://example.com/playlist/37i9dQZF1DXcBWIGoYBM5M",
+		"https://open.spotify.com/artist/0kbYTNQb4Pb1rPbbaF0pT4",
+	}
+	for _, uri := range invalid {
+		_, _, err := ParseCollectionURI(uri)
+		assert.Equal(t, ErrBadCollectionURI, err, uri)
+	}
+}
@@ -0,0 +1,44 @@
+package spotifycl
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/zmb3/spotify"
+)
+
+// maximum number of tracks returned by a search
+const SearchLimit = 20
+
+var ErrEmptyQuery = errors.New("search query must not be empty")
+
+// SearchTracks searches spotify for tracks matching the query.
+// Results are cached, so the returned tracks must not be modified.
+func (c *SpotifyClient) SearchTracks(query string) ([]*spotify.FullTrack, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, ErrEmptyQuery
+	}
+
+	// spotify search is case insensitive
+	key := strings.ToLower(query)
+	if tracks, ok := c.searchCache.Get(key); ok {
+		return tracks.([]*spotify.FullTrack), nil
+	}
+
+	limit := SearchLimit
+	result, err := c.Client.SearchOpt(query, spotify.SearchTypeTrack, &spotify.Options{Limit: &limit})
+	if err != nil {
+		return nil, fmt.Errorf("[spotifycl] search tracks: %w", err)
+	}
+
+	tracks := make([]*spotify.FullTrack, 0, SearchLimit)
+	if result.Tracks != nil {
+		for i := range result.Tracks.Tracks {
+			tracks = append(tracks, &result.Tracks.Tracks[i])
+		}
+	}
+	c.searchCache.Add(key, tracks)
+	return tracks, nil
+}
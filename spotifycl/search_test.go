@@ -0,0 +1,29 @@
+package spotifycl
+
+import (
+	"testing"
+	"time"
+
+	"github.com/encore-fm/backend/cache"
+	"github.com/stretchr/testify/assert"
+	"github.com/zmb3/spotify"
+)
+
+func TestSpotifyClient_SearchTracks_Cached(t *testing.T) {
+	cached := []*spotify.FullTrack{
+		{SimpleTrack: spotify.SimpleTrack{ID: "song_id"}},
+	}
+	client := &SpotifyClient{searchCache: cache.New(1, time.Minute)}
+	client.searchCache.Add("daft punk", cached)
+
+	// queries differing in case and surrounding space share a cache entry, spotify is not asked
+	tracks, err := client.SearchTracks("  Daft Punk ")
+	assert.NoError(t, err)
+	assert.Equal(t, cached, tracks)
+}
+
+func TestSpotifyClient_SearchTracks_EmptyQuery(t *testing.T) {
+	client := &SpotifyClient{searchCache: cache.New(1, time.Minute)}
+	_, err := client.SearchTracks(" ")
+	assert.Equal(t, ErrEmptyQuery, err)
+}
@@ -0,0 +1,167 @@
+package spotifycl
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/encore-fm/backend/cache"
+	"github.com/encore-fm/backend/song"
+	log "github.com/sirupsen/logrus"
+	"github.com/zmb3/spotify"
+)
+
+// TrackInfo holds the metadata of a track that is needed to suggest it
+type TrackInfo struct {
+	ID         string    `json:"id" bson:"_id"`
+	Name       string    `json:"name" bson:"name"`
+	Artists    []string  `json:"artists" bson:"artists"`
+	Duration   int       `json:"duration_ms" bson:"duration_ms"`
+	CoverUrl   string    `json:"cover_url" bson:"cover_url"`
+	AlbumName  string    `json:"album_name" bson:"album_name"`
+	PreviewUrl string    `json:"preview_url" bson:"preview_url"`
+	Explicit   bool      `json:"explicit" bson:"explicit"`
+	CachedAt   time.Time `json:"cached_at" bson:"cached_at"`
+}
+
+func NewTrackInfo(track *spotify.FullTrack) *TrackInfo {
+	s := song.New("", 0, track)
+	return &TrackInfo{
+		ID:         s.ID,
+		Name:       s.Name,
+		Artists:    s.Artists,
+		Duration:   s.Duration,
+		CoverUrl:   s.CoverUrl,
+		AlbumName:  s.AlbumName,
+		PreviewUrl: s.PreviewUrl,
+		Explicit:   s.Explicit,
+		CachedAt:   time.Now(),
+	}
+}
+
+// Song creates a song suggested by suggestingUser from the track
+func (t *TrackInfo) Song(suggestingUser string, score int) *song.Model {
+	return &song.Model{
+		ID:          t.ID,
+		Name:        t.Name,
+		Artists:     t.Artists,
+		Duration:    t.Duration,
+		CoverUrl:    t.CoverUrl,
+		AlbumName:   t.AlbumName,
+		PreviewUrl:  t.PreviewUrl,
+		Explicit:    t.Explicit,
+		SuggestedBy: suggestingUser,
+		Score:       score,
+		TimeAdded:   time.Now(),
+		Upvoters:    make([]string, 0),
+		Downvoters:  make([]string, 0),
+	}
+}
+
+// TrackStore persists track metadata, so it survives restarts and is shared between server instances
+type TrackStore interface {
+	// GetTrack returns nil and no error if the track is not stored
+	GetTrack(ctx context.Context, trackID string) (*TrackInfo, error)
+	SetTrack(ctx context.Context, track *TrackInfo) error
+}
+
+// TrackCache caches track metadata in memory and, if a store is given, in the store.
+// Tracks are shared across all sessions.
+type TrackCache struct {
+	lru   *cache.LRU
+	ttl   time.Duration
+	store TrackStore
+
+	// if set, every lookup misses, fetched tracks are still cached
+	bypass bool
+
+	hits   uint64
+	misses uint64
+}
+
+// NewTrackCache creates a cache holding size tracks in memory for ttl each, store may be nil
+func NewTrackCache(size int, ttl time.Duration, store TrackStore, bypass bool) *TrackCache {
+	return &TrackCache{
+		lru:    cache.New(size, ttl),
+		ttl:    ttl,
+		store:  store,
+		bypass: bypass,
+	}
+}
+
+// Get returns the cached metadata of a track, ok is false on a miss
+func (c *TrackCache) Get(ctx context.Context, trackID string) (track *TrackInfo, ok bool) {
+	track = c.get(ctx, trackID)
+	if track == nil {
+		atomic.AddUint64(&c.misses, 1)
+		return nil, false
+	}
+	atomic.AddUint64(&c.hits, 1)
+	return track, true
+}
+
+func (c *TrackCache) get(ctx context.Context, trackID string) *TrackInfo {
+	if c.bypass {
+		return nil
+	}
+	if track, ok := c.lru.Get(trackID); ok {
+		return track.(*TrackInfo)
+	}
+	if c.store == nil {
+		return nil
+	}
+
+	track, err := c.store.GetTrack(ctx, trackID)
+	if err != nil {
+		log.Errorf("[spotifycl] get track from store: %v", err)
+		return nil
+	}
+	if track == nil || time.Since(track.CachedAt) >= c.ttl {
+		return nil
+	}
+	c.lru.Add(trackID, track)
+	return track
+}
+
+// Add caches the metadata of a track
+func (c *TrackCache) Add(ctx context.Context, track *TrackInfo) {
+	c.lru.Add(track.ID, track)
+	if c.store == nil {
+		return
+	}
+	if err := c.store.SetTrack(ctx, track); err != nil {
+		log.Errorf("[spotifycl] add track to store: %v", err)
+	}
+}
+
+// Stats returns the number of hits and misses since the cache was created
+func (c *TrackCache) Stats() (hits, misses uint64) {
+	return atomic.LoadUint64(&c.hits), atomic.LoadUint64(&c.misses)
+}
+
+// GetTrackInfo returns the metadata of a track, spotify is only asked if the track is not cached
+func (c *SpotifyClient) GetTrackInfo(ctx context.Context, trackID string) (*TrackInfo, error) {
+	if track, ok := c.trackCache.Get(ctx, trackID); ok {
+		return track, nil
+	}
+
+	fullTrack, err := c.Client.GetTrack(spotify.ID(trackID))
+	if err != nil {
+		return nil, err
+	}
+	track := NewTrackInfo(fullTrack)
+	c.trackCache.Add(ctx, track)
+	return track, nil
+}
+
+// CacheTracks adds tracks that were fetched from spotify otherwise, e.g. with the client of a user
+func (c *SpotifyClient) CacheTracks(ctx context.Context, tracks []spotify.FullTrack) {
+	for i := range tracks {
+		c.trackCache.Add(ctx, NewTrackInfo(&tracks[i]))
+	}
+}
+
+// TrackCacheStats returns the number of hits and misses of the track cache
+func (c *SpotifyClient) TrackCacheStats() (hits, misses uint64) {
+	return c.trackCache.Stats()
+}
@@ -0,0 +1,79 @@
+package spotifycl
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeStore map[string]*TrackInfo
+
+func (s fakeStore) GetTrack(_ context.Context, trackID string) (*TrackInfo, error) {
+	return s[trackID], nil
+}
+
+func (s fakeStore) SetTrack(_ context.Context, track *TrackInfo) error {
+	s[track.ID] = track
+	return nil
+}
+
+func TestTrackCache(t *testing.T) {
+	ctx := context.Background()
+	store := fakeStore{
+		"stored": {ID: "stored", CachedAt: time.Now()},
+		"stale":  {ID: "stale", CachedAt: time.Now().Add(-2 * time.Hour)},
+	}
+	c := NewTrackCache(10, time.Hour, store, false)
+
+	c.Add(ctx, &TrackInfo{ID: "added", CachedAt: time.Now()})
+	assert.Contains(t, store, "added")
+
+	track, ok := c.Get(ctx, "added")
+	assert.True(t, ok)
+	assert.Equal(t, "added", track.ID)
+
+	// tracks of the store are used if they have not expired
+	_, ok = c.Get(ctx, "stored")
+	assert.True(t, ok)
+	_, ok = c.Get(ctx, "stale")
+	assert.False(t, ok)
+	_, ok = c.Get(ctx, "unknown")
+	assert.False(t, ok)
+
+	hits, misses := c.Stats()
+	assert.Equal(t, uint64(2), hits)
+	assert.Equal(t, uint64(2), misses)
+}
+
+func TestTrackCache_Bypass(t *testing.T) {
+	ctx := context.Background()
+	store := fakeStore{}
+	c := NewTrackCache(10, time.Hour, store, true)
+
+	c.Add(ctx, &TrackInfo{ID: "added", CachedAt: time.Now()})
+	assert.Contains(t, store, "added")
+
+	_, ok := c.Get(ctx, "added")
+	assert.False(t, ok)
+	hits, misses := c.Stats()
+	assert.Equal(t, uint64(0), hits)
+	assert.Equal(t, uint64(1), misses)
+}
+
+func TestSpotifyClient_GetTrackInfo_Cached(t *testing.T) {
+	ctx := context.Background()
+	client := &SpotifyClient{trackCache: NewTrackCache(10, time.Hour, nil, false)}
+	client.trackCache.Add(ctx, &TrackInfo{ID: "song_id", Name: "song_name", CachedAt: time.Now()})
+
+	// spotify is not asked
+	track, err := client.GetTrackInfo(ctx, "song_id")
+	assert.NoError(t, err)
+
+	s := track.Song("username", 1)
+	assert.Equal(t, "song_id", s.ID)
+	assert.Equal(t, "song_name", s.Name)
+	assert.Equal(t, "username", s.SuggestedBy)
+	assert.Equal(t, 1, s.Score)
+}
@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/encore-fm/backend/events"
+	"github.com/encore-fm/backend/session"
 	"github.com/encore-fm/backend/song"
 	"github.com/encore-fm/backend/user"
 )
@@ -13,8 +14,26 @@ const (
 	PlayerStateChange      events.EventType = "sse:player_state_change"
 	UserListChange         events.EventType = "sse:user_list_change"
 	UserSynchronizedChange events.EventType = "sse:user_synchronized_change"
+	SessionSettingsChange  events.EventType = "sse:session_settings_change"
+	UserKicked             events.EventType = "sse:user_kicked"
+	SkipVoteChange         events.EventType = "sse:skip_vote_change"
+	UserNotification       events.EventType = "sse:user_notification" // published to the group of a single user
 )
 
+// ClientEventTypes returns the event types sent to clients, they are recorded for replay after reconnecting
+func ClientEventTypes() []events.EventType {
+	return append([]events.EventType{
+		PlaylistChange,
+		PlayerStateChange,
+		UserListChange,
+		UserSynchronizedChange,
+		SessionSettingsChange,
+		UserKicked,
+		SkipVoteChange,
+		UserNotification,
+	}, PlaylistPatchTypes...)
+}
+
 type PlaylistChangePayload []*song.Model
 
 type PlayerStateChangePayload struct {
@@ -30,3 +49,44 @@ type UserSynchronizedChangePayload struct {
 	UserID       string `json:"user_id"`
 	Synchronized bool   `json:"synchronized"`
 }
+
+type SessionSettingsChangePayload *session.Settings
+
+type UserKickedPayload struct {
+	Username string `json:"username"`
+	Banned   bool   `json:"banned"`
+}
+
+type SkipVoteChangePayload struct {
+	SongID   string `json:"song_id"`
+	Votes    int    `json:"votes"`
+	Required int    `json:"required"`
+}
+
+type NotificationKind string
+
+const (
+	// a song suggested by the user has been removed from the queue
+	NotificationSongRemoved NotificationKind = "song_removed"
+	// the role of the user has changed
+	NotificationRoleChanged NotificationKind = "role_changed"
+)
+
+// UserNotificationPayload tells a single user about something that concerns only them,
+// the type of data depends on the kind
+type UserNotificationPayload struct {
+	Kind NotificationKind `json:"kind"`
+	Data interface{}      `json:"data"`
+}
+
+// SongRemovedPayload is the data of a song_removed notification
+type SongRemovedPayload struct {
+	SongID      string                `json:"song_id"`
+	SuggestedBy string                `json:"suggested_by"`
+	Reason      session.RemovalReason `json:"reason"`
+}
+
+// RoleChangedPayload is the data of a role_changed notification
+type RoleChangedPayload struct {
+	Role user.Role `json:"role"`
+}
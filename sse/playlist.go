@@ -0,0 +1,260 @@
+package sse
+
+import (
+	"sync"
+	"time"
+
+	"github.com/encore-fm/backend/events"
+	"github.com/encore-fm/backend/song"
+)
+
+// Playlist patches, every patch increments the playlist revision of the session by one.
+// Clients apply them in the order of their revisions and detect gaps by the revision
+// not following the one of the last applied patch.
+const (
+	PlaylistSnapshot    events.EventType = "sse:playlist_snapshot"
+	SongAdded           events.EventType = "sse:song_added"
+	PlaylistSongRemoved events.EventType = "sse:playlist_song_removed"
+	SongScoreChanged    events.EventType = "sse:song_score_changed"
+	QueueReordered      events.EventType = "sse:queue_reordered"
+)
+
+// PlaylistPatchTypes are the event types of the incremental playlist updates
+var PlaylistPatchTypes = []events.EventType{
+	PlaylistSnapshot,
+	SongAdded,
+	PlaylistSongRemoved,
+	SongScoreChanged,
+	QueueReordered,
+}
+
+// PlaylistSnapshotPayload replaces the playlist of the client
+type PlaylistSnapshotPayload struct {
+	Revision uint64        `json:"revision"`
+	Songs    []*song.Model `json:"songs"`
+}
+
+// IndexedSong is a song and its position in the playlist
+type IndexedSong struct {
+	Index int         `json:"index"`
+	Song  *song.Model `json:"song"`
+}
+
+// SongAddedPayload lists the added songs by increasing index,
+// they are inserted one after the other
+type SongAddedPayload struct {
+	Revision uint64         `json:"revision"`
+	Songs    []*IndexedSong `json:"songs"`
+}
+
+type PlaylistSongRemovedPayload struct {
+	Revision uint64   `json:"revision"`
+	SongIDs  []string `json:"song_ids"`
+}
+
+type ScoreChange struct {
+	SongID     string   `json:"song_id"`
+	Score      int      `json:"score"`
+	Upvoters   []string `json:"upvoters"`
+	Downvoters []string `json:"downvoters"`
+}
+
+type SongScoreChangedPayload struct {
+	Revision uint64         `json:"revision"`
+	Songs    []*ScoreChange `json:"songs"`
+}
+
+// QueueReorderedPayload holds the new order of all songs and the priorities of the pinned songs
+type QueueReorderedPayload struct {
+	Revision   uint64         `json:"revision"`
+	Order      []string       `json:"order"`
+	Priorities map[string]int `json:"priorities"`
+}
+
+// PlaylistTracker remembers the last published playlist of every session
+// and publishes the patches between it and a new playlist
+type PlaylistTracker struct {
+	eventBus  events.EventBus
+	mutex     sync.Mutex
+	playlists map[string]*trackedPlaylist
+}
+
+type trackedPlaylist struct {
+	revision uint64
+	songs    []*song.Model
+}
+
+func NewPlaylistTracker(eventBus events.EventBus) *PlaylistTracker {
+	return &PlaylistTracker{
+		eventBus:  eventBus,
+		playlists: make(map[string]*trackedPlaylist),
+	}
+}
+
+// Publish publishes the patches that turn the last playlist of the session into songList,
+// followed by the full playlist for clients that do not apply patches.
+// A snapshot is published if the last playlist is unknown.
+func (t *PlaylistTracker) Publish(sessionID string, songList []*song.Model) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	groupID := events.GroupID(sessionID)
+	// publishing while holding the lock keeps the events in the order of their revisions,
+	// the event bus delivers them to every subscriber in the order they were published
+	tracked, ok := t.playlists[sessionID]
+	if !ok {
+		tracked = &trackedPlaylist{revision: initialRevision() + 1, songs: songList}
+		t.playlists[sessionID] = tracked
+		t.eventBus.Publish(PlaylistSnapshot, groupID, PlaylistSnapshotPayload{Revision: tracked.revision, Songs: songList})
+	} else {
+		for _, patch := range playlistPatches(tracked.songs, songList, tracked.revision) {
+			t.eventBus.Publish(patch.Type, groupID, patch.Data)
+			tracked.revision++
+		}
+		tracked.songs = songList
+	}
+
+	t.eventBus.Publish(PlaylistChange, groupID, PlaylistChangePayload(songList))
+}
+
+// Snapshot returns the last playlist of the session and its revision.
+// songList is adopted if the last playlist is unknown.
+func (t *PlaylistTracker) Snapshot(sessionID string, songList []*song.Model) PlaylistSnapshotPayload {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	tracked, ok := t.playlists[sessionID]
+	if !ok {
+		tracked = &trackedPlaylist{revision: initialRevision(), songs: songList}
+		t.playlists[sessionID] = tracked
+	}
+	return PlaylistSnapshotPayload{Revision: tracked.revision, Songs: tracked.songs}
+}
+
+// Remove forgets the playlists of deleted sessions
+func (t *PlaylistTracker) Remove(sessionIDs []string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	for _, sessionID := range sessionIDs {
+		delete(t.playlists, sessionID)
+	}
+}
+
+// revisions continue to grow across server restarts, so clients never mistake a new playlist for the next patch.
+// They start like event ids and stay below 2^53, so javascript clients read them without losing precision.
+func initialRevision() uint64 {
+	return events.InitialID(time.Now())
+}
+
+// playlistPatches returns the patches turning previous into current, numbered from revision+1 on.
+// Removals come first, then additions, score changes and finally the new order if it still differs.
+func playlistPatches(previous, current []*song.Model, revision uint64) []events.Event {
+	patches := make([]events.Event, 0)
+	next := func() uint64 {
+		revision++
+		return revision
+	}
+
+	previousByID := make(map[string]*song.Model, len(previous))
+	for _, s := range previous {
+		previousByID[s.ID] = s
+	}
+	currentIDs := make(map[string]bool, len(current))
+	for _, s := range current {
+		currentIDs[s.ID] = true
+	}
+
+	// order of the client after applying removals and additions
+	order := make([]string, 0, len(current))
+	removed := make([]string, 0)
+	for _, s := range previous {
+		if currentIDs[s.ID] {
+			order = append(order, s.ID)
+		} else {
+			removed = append(removed, s.ID)
+		}
+	}
+	if len(removed) > 0 {
+		patches = append(patches, events.Event{
+			Type: PlaylistSongRemoved,
+			Data: PlaylistSongRemovedPayload{Revision: next(), SongIDs: removed},
+		})
+	}
+
+	added := make([]*IndexedSong, 0)
+	for i, s := range current {
+		if _, ok := previousByID[s.ID]; ok {
+			continue
+		}
+		added = append(added, &IndexedSong{Index: i, Song: s})
+		index := i
+		if index > len(order) {
+			index = len(order)
+		}
+		order = append(order[:index], append([]string{s.ID}, order[index:]...)...)
+	}
+	if len(added) > 0 {
+		patches = append(patches, events.Event{
+			Type: SongAdded,
+			Data: SongAddedPayload{Revision: next(), Songs: added},
+		})
+	}
+
+	changed := make([]*ScoreChange, 0)
+	reordered := false
+	for i, s := range current {
+		if order[i] != s.ID {
+			reordered = true
+		}
+		old, ok := previousByID[s.ID]
+		if !ok {
+			continue
+		}
+		if old.Priority != s.Priority {
+			reordered = true
+		}
+		if old.Score != s.Score || !equalStrings(old.Upvoters, s.Upvoters) || !equalStrings(old.Downvoters, s.Downvoters) {
+			changed = append(changed, &ScoreChange{
+				SongID:     s.ID,
+				Score:      s.Score,
+				Upvoters:   s.Upvoters,
+				Downvoters: s.Downvoters,
+			})
+		}
+	}
+	if len(changed) > 0 {
+		patches = append(patches, events.Event{
+			Type: SongScoreChanged,
+			Data: SongScoreChangedPayload{Revision: next(), Songs: changed},
+		})
+	}
+
+	if reordered {
+		priorities := make(map[string]int)
+		for i, s := range current {
+			order[i] = s.ID
+			if s.IsPinned() {
+				priorities[s.ID] = s.Priority
+			}
+		}
+		patches = append(patches, events.Event{
+			Type: QueueReordered,
+			Data: QueueReorderedPayload{Revision: next(), Order: order, Priorities: priorities},
+		})
+	}
+
+	return patches
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,248 @@
+package sse
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/encore-fm/backend/events"
+	"github.com/encore-fm/backend/song"
+	"github.com/stretchr/testify/assert"
+)
+
+func newSong(id string, score int) *song.Model {
+	return &song.Model{ID: id, Score: score, Upvoters: []string{}, Downvoters: []string{}}
+}
+
+// apply applies patches like a client would
+func apply(songs []*song.Model, patches []events.Event) []*song.Model {
+	result := append([]*song.Model{}, songs...)
+	for _, patch := range patches {
+		switch payload := patch.Data.(type) {
+		case PlaylistSongRemovedPayload:
+			for _, id := range payload.SongIDs {
+				for i, s := range result {
+					if s.ID == id {
+						result = append(result[:i], result[i+1:]...)
+						break
+					}
+				}
+			}
+		case SongAddedPayload:
+			for _, added := range payload.Songs {
+				index := added.Index
+				if index > len(result) {
+					index = len(result)
+				}
+				result = append(result[:index], append([]*song.Model{added.Song}, result[index:]...)...)
+			}
+		case SongScoreChangedPayload:
+			for _, change := range payload.Songs {
+				for i, s := range result {
+					if s.ID == change.SongID {
+						updated := *s
+						updated.Score = change.Score
+						updated.Upvoters = change.Upvoters
+						updated.Downvoters = change.Downvoters
+						result[i] = &updated
+					}
+				}
+			}
+		case QueueReorderedPayload:
+			byID := make(map[string]*song.Model)
+			for _, s := range result {
+				byID[s.ID] = s
+			}
+			result = result[:0]
+			for _, id := range payload.Order {
+				updated := *byID[id]
+				updated.Priority = payload.Priorities[id]
+				result = append(result, &updated)
+			}
+		}
+	}
+	return result
+}
+
+func ids(songs []*song.Model) []string {
+	result := make([]string, 0, len(songs))
+	for _, s := range songs {
+		result = append(result, s.ID)
+	}
+	return result
+}
+
+func TestPlaylistPatches(t *testing.T) {
+	upvoted := newSong("c", 2)
+	upvoted.Upvoters = []string{"user"}
+	pinned := newSong("b", 1)
+	pinned.Priority = 1
+
+	testCases := []struct {
+		name     string
+		previous []*song.Model
+		current  []*song.Model
+		types    []events.EventType
+	}{
+		{
+			name:     "unchanged",
+			previous: []*song.Model{newSong("a", 1), newSong("b", 0)},
+			current:  []*song.Model{newSong("a", 1), newSong("b", 0)},
+			types:    []events.EventType{},
+		},
+		{
+			name:     "added in place",
+			previous: []*song.Model{newSong("a", 1), newSong("b", 0)},
+			current:  []*song.Model{newSong("a", 1), newSong("c", 0), newSong("b", 0)},
+			types:    []events.EventType{SongAdded},
+		},
+		{
+			name:     "removed",
+			previous: []*song.Model{newSong("a", 1), newSong("b", 0)},
+			current:  []*song.Model{newSong("b", 0)},
+			types:    []events.EventType{PlaylistSongRemoved},
+		},
+		{
+			name:     "upvote moves song",
+			previous: []*song.Model{newSong("a", 1), newSong("b", 0), newSong("c", 1)},
+			current:  []*song.Model{upvoted, newSong("a", 1), newSong("b", 0)},
+			types:    []events.EventType{SongScoreChanged, QueueReordered},
+		},
+		{
+			name:     "pinned",
+			previous: []*song.Model{newSong("a", 1), newSong("b", 1)},
+			current:  []*song.Model{pinned, newSong("a", 1)},
+			types:    []events.EventType{QueueReordered},
+		},
+		{
+			name:     "everything",
+			previous: []*song.Model{newSong("a", 1), newSong("b", 1), newSong("c", 1)},
+			current:  []*song.Model{newSong("d", 0), upvoted, pinned},
+			types:    []events.EventType{PlaylistSongRemoved, SongAdded, SongScoreChanged, QueueReordered},
+		},
+	}
+
+	for _, tc := range testCases {
+		patches := playlistPatches(tc.previous, tc.current, 41)
+
+		types := make([]events.EventType, 0, len(patches))
+		for i, patch := range patches {
+			types = append(types, patch.Type)
+			// revisions are consecutive
+			switch payload := patch.Data.(type) {
+			case PlaylistSongRemovedPayload:
+				assert.Equal(t, uint64(42+i), payload.Revision, tc.name)
+			case SongAddedPayload:
+				assert.Equal(t, uint64(42+i), payload.Revision, tc.name)
+			case SongScoreChangedPayload:
+				assert.Equal(t, uint64(42+i), payload.Revision, tc.name)
+			case QueueReorderedPayload:
+				assert.Equal(t, uint64(42+i), payload.Revision, tc.name)
+			}
+		}
+		assert.Equal(t, tc.types, types, tc.name)
+		assert.Equal(t, tc.current, apply(tc.previous, patches), tc.name)
+	}
+}
+
+func TestPlaylistTracker_Publish(t *testing.T) {
+	sessionID := "session_id"
+	eventBus := events.NewEventBus()
+	eventBus.Start()
+	defer eventBus.Stop()
+
+	types := append([]events.EventType{PlaylistChange}, PlaylistPatchTypes...)
+	sub := eventBus.Subscribe(types, []events.GroupID{events.GroupID(sessionID)})
+	receive := func() events.Event {
+		select {
+		case ev := <-sub.Channel:
+			return ev
+		case <-time.After(time.Second):
+			t.Fatal("no event received")
+			return events.Event{}
+		}
+	}
+
+	tracker := NewPlaylistTracker(eventBus)
+	snapshot := tracker.Snapshot(sessionID, []*song.Model{newSong("a", 0)})
+	assert.Equal(t, []string{"a"}, ids(snapshot.Songs))
+	// revisions are read by javascript clients without losing precision
+	assert.Less(t, snapshot.Revision, uint64(1)<<53)
+
+	tracker.Publish(sessionID, []*song.Model{newSong("a", 0), newSong("b", 0)})
+	received := []events.Event{receive(), receive()}
+
+	var added *SongAddedPayload
+	var full PlaylistChangePayload
+	for _, ev := range received {
+		switch payload := ev.Data.(type) {
+		case SongAddedPayload:
+			added = &payload
+		case PlaylistChangePayload:
+			full = payload
+		}
+	}
+	assert.NotNil(t, added)
+	assert.Equal(t, snapshot.Revision+1, added.Revision)
+	assert.Equal(t, []string{"a", "b"}, ids(full))
+
+	// unknown playlists are published as a snapshot
+	tracker.Remove([]string{sessionID})
+	tracker.Publish(sessionID, []*song.Model{newSong("b", 0)})
+	received = []events.Event{receive(), receive()}
+	types = []events.EventType{received[0].Type, received[1].Type}
+	assert.Equal(t, []events.EventType{PlaylistSnapshot, PlaylistChange}, types)
+}
+
+// test that a subscriber receives the patches of consecutive changes in the order of their revisions
+func TestPlaylistTracker_PublishOrder(t *testing.T) {
+	sessionID := "session_id"
+	eventBus := events.NewEventBus()
+	eventBus.Start()
+	defer eventBus.Stop()
+
+	sub := eventBus.Subscribe(PlaylistPatchTypes, []events.GroupID{events.GroupID(sessionID)})
+	tracker := NewPlaylistTracker(eventBus)
+
+	// every change removes, adds and rescores a song
+	changes := 20
+	tracker.Publish(sessionID, []*song.Model{newSong("0", 0)})
+	for i := 1; i <= changes; i++ {
+		tracker.Publish(sessionID, []*song.Model{newSong(strconv.Itoa(i), 0), newSong(strconv.Itoa(i-1), i)})
+	}
+
+	received := 0
+	var last uint64
+	for {
+		select {
+		case ev := <-sub.Channel:
+			revision := patchRevision(t, ev)
+			if received > 0 {
+				assert.Equal(t, last+1, revision, ev.Type)
+			}
+			last = revision
+			received++
+			continue
+		case <-time.After(200 * time.Millisecond):
+		}
+		break
+	}
+	assert.Greater(t, received, 2*changes)
+}
+
+func patchRevision(t *testing.T, ev events.Event) uint64 {
+	switch payload := ev.Data.(type) {
+	case PlaylistSnapshotPayload:
+		return payload.Revision
+	case SongAddedPayload:
+		return payload.Revision
+	case PlaylistSongRemovedPayload:
+		return payload.Revision
+	case SongScoreChangedPayload:
+		return payload.Revision
+	case QueueReorderedPayload:
+		return payload.Revision
+	}
+	t.Fatalf("unexpected event %v", ev.Type)
+	return 0
+}
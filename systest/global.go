@@ -39,7 +39,8 @@ const (
 var (
 	testNow     = time.Now()
 	testSession = &session.Session{
-		ID: TestSessionID,
+		ID:        TestSessionID,
+		UserCount: 2,
 		SongList: []*song.Model{{
 			Name:        "SkiFoan",
 			ID:          SkiFoanID,
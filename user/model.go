@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"regexp"
+	"time"
 
 	"github.com/encore-fm/backend/util"
 	"golang.org/x/oauth2"
@@ -33,9 +34,14 @@ type Model struct {
 	Secret            string `json:"secret" bson:"secret"`
 	SessionID         string `json:"session_id" bson:"session_id"`
 	IsAdmin           bool   `json:"is_admin" bson:"is_admin"`
+	Role              Role   `json:"role" bson:"role"`
 	Score             int    `json:"score" bson:"score"`
 	SpotifyAuthorized bool   `json:"spotify_authorized" bson:"spotify_authorized"`
 
+	// waiting users joined a full session and have not been admitted yet
+	Waiting      bool      `json:"waiting" bson:"waiting"`
+	WaitingSince time.Time `json:"-" bson:"waiting_since"`
+
 	SpotifySynchronized bool `json:"-" bson:"spotify_synchronized"`
 	AutoSync            bool `json:"-" bson:"auto_sync"`
 
@@ -43,13 +49,21 @@ type Model struct {
 	AuthState string        `json:"-" bson:"auth_state"`
 
 	ActiveSSEConnections int `json:"-" bson:"active_sse_connections"`
+
+	// optional client fingerprint sent on join, used to enforce bans
+	Fingerprint string `json:"-" bson:"fingerprint"`
+
+	// time of the last song suggestion, used to enforce the suggestion interval
+	LastSuggestion time.Time `json:"-" bson:"last_suggestion"`
 }
 
 type ListElement struct {
 	Username            string `json:"username" bson:"username"`
 	IsAdmin             bool   `json:"is_admin" bson:"is_admin"`
+	Role                Role   `json:"role" bson:"role"`
 	Score               int    `json:"score" bson:"score"`
 	SpotifySynchronized bool   `json:"spotify_synchronized" bson:"spotify_synchronized"`
+	Waiting             bool   `json:"waiting" bson:"waiting"`
 }
 
 type SpotifyClient struct {
@@ -101,6 +115,7 @@ func New(username, sessionID string) (*Model, error) {
 		Secret:               secret,
 		SessionID:            sessionID,
 		IsAdmin:              false,
+		Role:                 RoleGuest,
 		Score:                1,
 		AuthState:            state,
 		SpotifyAuthorized:    false,
@@ -117,5 +132,6 @@ func NewAdmin(username, sessionID string) (*Model, error) {
 		return nil, err
 	}
 	admin.IsAdmin = true
+	admin.Role = RoleOwner
 	return admin, nil
 }
@@ -16,6 +16,7 @@ func TestNew(t *testing.T) {
 	assert.Equal(t, sessionID, result.SessionID)
 	assert.Equal(t, 1, result.Score)
 	assert.False(t, result.IsAdmin)
+	assert.Equal(t, RoleGuest, result.Role)
 	assert.False(t, result.SpotifyAuthorized)
 	assert.Equal(t, 128, len(result.Secret))
 }
@@ -31,6 +32,7 @@ func TestNewAdmin(t *testing.T) {
 
 	assert.Equal(t, 1, result.Score)
 	assert.True(t, result.IsAdmin)
+	assert.Equal(t, RoleOwner, result.Role)
 	assert.False(t, result.SpotifyAuthorized)
 	assert.Equal(t, 128, len(result.Secret))
 }
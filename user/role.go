@@ -0,0 +1,42 @@
+package user
+
+// Role determines what a user is allowed to do in a session.
+// The owner is the only user with the admin flag set.
+type Role string
+
+const (
+	// the owner created the session or had it transferred to them, there is exactly one per session
+	RoleOwner Role = "owner"
+	// moderators help the owner moderating the queue
+	RoleModerator Role = "moderator"
+	RoleGuest     Role = "guest"
+)
+
+var roleRank = map[Role]int{
+	RoleGuest:     0,
+	RoleModerator: 1,
+	RoleOwner:     2,
+}
+
+// Includes returns true if the role has at least the permissions of the other role
+func (r Role) Includes(other Role) bool {
+	return roleRank[r] >= roleRank[other]
+}
+
+// GetRole returns the user's role
+// users stored before roles existed only have the admin flag
+func (m *Model) GetRole() Role {
+	if m.Role != "" {
+		return m.Role
+	}
+	if m.IsAdmin {
+		return RoleOwner
+	}
+	return RoleGuest
+}
+
+// Valid returns true for the known roles
+func (r Role) Valid() bool {
+	_, ok := roleRank[r]
+	return ok
+}
@@ -0,0 +1,21 @@
+package user
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRole_Includes(t *testing.T) {
+	assert.True(t, RoleOwner.Includes(RoleModerator))
+	assert.True(t, RoleModerator.Includes(RoleModerator))
+	assert.True(t, RoleModerator.Includes(RoleGuest))
+	assert.False(t, RoleGuest.Includes(RoleModerator))
+	assert.False(t, RoleModerator.Includes(RoleOwner))
+}
+
+func TestModel_GetRole(t *testing.T) {
+	assert.Equal(t, RoleOwner, (&Model{IsAdmin: true}).GetRole())
+	assert.Equal(t, RoleGuest, (&Model{}).GetRole())
+	assert.Equal(t, RoleModerator, (&Model{Role: RoleModerator}).GetRole())
+}